@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lwlach/turvo-integration-backend/internal/config"
+)
+
+// runConfig implements the "config" subcommand:
+//
+//	config print [-config file] [-section.setting value...]
+//
+// prints the effective configuration (file, environment and flags combined) as YAML with
+// secrets redacted, followed by every configuration problem.
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: config print [-config file] [-section.setting value...]")
+		return 2
+	}
+
+	cfg, err := config.Load("config print", args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if printErr := cfg.Print(os.Stdout); printErr != nil {
+		fmt.Fprintf(os.Stderr, "failed to print configuration: %v\n", printErr)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 1
+	}
+	return 0
+}
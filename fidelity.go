@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lwlach/turvo-integration-backend/internal/mapping"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	loadservice "github.com/lwlach/turvo-integration-backend/internal/service/load"
+)
+
+// runFidelity implements the "fidelity" subcommand: it round-trips loads through the
+// Drumkit→Turvo→Drumkit mappings and prints which fields survive, change or are lost.
+//
+//	fidelity [-format table|markdown|json] [-profile file -tenant id] load.json...
+//	fidelity -golden internal/service/load/testdata/fidelity [-update]
+func runFidelity(args []string) int {
+	flags := flag.NewFlagSet("fidelity", flag.ContinueOnError)
+	format := flags.String("format", "table", "output format: table, markdown or json")
+	goldenDir := flags.String("golden", "", "compare the report of every *.json in this directory with its .golden file")
+	update := flags.Bool("update", false, "with -golden, rewrite the .golden files instead of comparing")
+	profilePath := flags.String("profile", "", "mapping profile file (default: built-in profile)")
+	tenantID := flags.String("tenant", "default", "tenant whose mapping profile is used")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	profiles := mapping.DefaultSet()
+	if *profilePath != "" {
+		var err error
+		profiles, err = mapping.LoadFile(*profilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid mapping profile %s:\n%v\n", *profilePath, err)
+			return 1
+		}
+	}
+
+	// The mappings do not call Turvo, so no client is needed
+	service := loadservice.NewService(nil, loadservice.Config{
+		Profile: profiles.ForTenant(*tenantID),
+	})
+
+	if *goldenDir != "" {
+		return checkGoldenFiles(service, *goldenDir, *update)
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: fidelity [-format table|markdown|json] load.json... | -golden dir [-update]")
+		return 2
+	}
+
+	for _, path := range flags.Args() {
+		if flags.NArg() > 1 {
+			fmt.Printf("== %s ==\n", path)
+		}
+		if err := writeFidelityReport(service, path, *format, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// checkGoldenFiles compares (or with update, rewrites) the table report of every load in dir
+func checkGoldenFiles(service *loadservice.Service, dir string, update bool) int {
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(inputs) == 0 {
+		fmt.Fprintf(os.Stderr, "no *.json loads found in %s\n", dir)
+		return 1
+	}
+
+	failed := false
+	for _, input := range inputs {
+		var report bytes.Buffer
+		if err := writeFidelityReport(service, input, "table", &report); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", input, err)
+			return 1
+		}
+
+		golden := strings.TrimSuffix(input, ".json") + ".golden"
+		if update {
+			if err := os.WriteFile(golden, report.Bytes(), 0o644); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", golden, err)
+				return 1
+			}
+			fmt.Printf("updated %s\n", golden)
+			continue
+		}
+
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v (run with -update to create it)\n", golden, err)
+			failed = true
+			continue
+		}
+		if !bytes.Equal(want, report.Bytes()) {
+			fmt.Fprintf(os.Stderr, "FAIL %s: mapping fidelity differs from %s\n--- want\n%s--- got\n%s", input, golden, want, report.Bytes())
+			failed = true
+			continue
+		}
+		fmt.Printf("ok   %s\n", input)
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// writeFidelityReport reads a load from path, round-trips it and writes the report in format
+func writeFidelityReport(service *loadservice.Service, path, format string, w io.Writer) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var load models.Load
+	if err := json.Unmarshal(data, &load); err != nil {
+		return fmt.Errorf("invalid load: %w", err)
+	}
+
+	report, err := service.RoundTrip(&load)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "table":
+		return report.WriteTable(w)
+	case "markdown":
+		return report.WriteMarkdown(w)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	default:
+		return fmt.Errorf("unknown format: %q", format)
+	}
+}
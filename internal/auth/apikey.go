@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIKeyHeader carries an API key
+const APIKeyHeader = "X-API-Key"
+
+// hashPrefix marks the hash algorithm of stored API keys
+const hashPrefix = "sha256:"
+
+// HashAPIKey returns the hash API keys are stored and looked up by ("sha256:<hex>")
+func HashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// KeyStore looks up API keys by hash
+type KeyStore interface {
+	LookupAPIKey(hash string) (*Principal, bool)
+}
+
+// APIKeyAuthenticator authenticates the X-API-Key header against one or more key stores
+type APIKeyAuthenticator struct {
+	stores []KeyStore
+}
+
+func NewAPIKeyAuthenticator(stores ...KeyStore) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{stores: stores}
+}
+
+// Authenticate implements Authenticator
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	apiKey := r.Header.Get(APIKeyHeader)
+	if apiKey == "" {
+		return nil, ErrNoCredentials
+	}
+
+	hash := HashAPIKey(apiKey)
+	for _, store := range a.stores {
+		if principal, found := store.LookupAPIKey(hash); found {
+			return principal, nil
+		}
+	}
+	return nil, errors.New("invalid API key")
+}
+
+// StaticKey is an API key configured in a key file. Only its hash is stored.
+type StaticKey struct {
+	ID     string   `yaml:"id"`
+	Hash   string   `yaml:"hash"`   // "sha256:<hex>" of the key
+	Tenant string   `yaml:"tenant"` // Tenant the key is bound to; empty for keys not bound to a tenant
+	Scopes []string `yaml:"scopes"`
+}
+
+// StaticKeys is a fixed set of API keys
+type StaticKeys struct {
+	keys map[string]StaticKey // By hash
+}
+
+// NewStaticKeys builds a key store from keys, rejecting duplicate hashes and unknown scopes
+func NewStaticKeys(keys []StaticKey) (*StaticKeys, error) {
+	store := &StaticKeys{keys: make(map[string]StaticKey, len(keys))}
+
+	var errs []error
+	for i, key := range keys {
+		if key.ID == "" {
+			errs = append(errs, fmt.Errorf("keys[%d].id is required", i))
+		}
+		if !strings.HasPrefix(key.Hash, hashPrefix) || len(key.Hash) != len(hashPrefix)+sha256.Size*2 {
+			errs = append(errs, fmt.Errorf("keys[%d].hash must be %q followed by the hex SHA-256 of the key", i, hashPrefix))
+		}
+		if len(key.Scopes) == 0 {
+			errs = append(errs, fmt.Errorf("keys[%d].scopes must not be empty", i))
+		}
+		for _, scope := range key.Scopes {
+			if !knownScope(scope) {
+				errs = append(errs, fmt.Errorf("keys[%d]: unknown scope %q", i, scope))
+			}
+		}
+		key.Hash = strings.ToLower(key.Hash)
+		if _, exists := store.keys[key.Hash]; exists {
+			errs = append(errs, fmt.Errorf("keys[%d]: duplicate key hash", i))
+		}
+		store.keys[key.Hash] = key
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return store, nil
+}
+
+// LoadStaticKeys reads a YAML or JSON key file:
+//
+//	keys:
+//	  - id: ops-dashboard
+//	    hash: sha256:<hex>
+//	    tenant: acme
+//	    scopes: [loads:read]
+func LoadStaticKeys(path string) (*StaticKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API key file: %w", err)
+	}
+
+	var file struct {
+		Keys []StaticKey `yaml:"keys"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid API key file %s: %w", path, err)
+	}
+
+	store, err := NewStaticKeys(file.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API key file %s:\n%w", path, err)
+	}
+	return store, nil
+}
+
+// LookupAPIKey implements KeyStore
+func (s *StaticKeys) LookupAPIKey(hash string) (*Principal, bool) {
+	key, found := s.keys[hash]
+	if !found {
+		return nil, false
+	}
+	return &Principal{
+		Method:   MethodAPIKey,
+		ID:       key.ID,
+		TenantID: key.Tenant,
+		Scopes:   key.Scopes,
+	}, true
+}
+
+// Len returns the number of keys
+func (s *StaticKeys) Len() int {
+	return len(s.keys)
+}
+
+func knownScope(scope string) bool {
+	switch scope {
+	case ScopeLoadsRead, ScopeLoadsWrite, ScopeTenantsAdmin, ScopeTenantsAny:
+		return true
+	}
+	return false
+}
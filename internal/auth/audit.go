@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Audit outcomes
+const (
+	AuditUnauthenticated = "unauthenticated" // Missing or invalid credentials
+	AuditForbidden       = "forbidden"       // Valid credentials without the required scope or tenant
+)
+
+// AuditEvent records a failed authentication or authorization attempt
+type AuditEvent struct {
+	Time       time.Time
+	Outcome    string
+	Method     string
+	Path       string
+	RemoteAddr string
+	RequestID  string
+	Principal  string // "<method>:<id>" if the caller was authenticated
+	TenantID   string
+	Reason     string
+}
+
+// LogAudit writes audit events to the application log
+func LogAudit(event AuditEvent) {
+	slog.Warn("audit",
+		"outcome", event.Outcome,
+		"method", event.Method,
+		"path", event.Path,
+		"remote_addr", event.RemoteAddr,
+		"request_id", event.RequestID,
+		"principal", event.Principal,
+		"tenant_id", event.TenantID,
+		"reason", event.Reason,
+	)
+}
+
+// Audit records a failed attempt with the auditor of the request (set by Middleware).
+// Other middleware, such as tenant selection, uses it to report denials.
+func Audit(r *http.Request, outcome string, principal *Principal, reason string) {
+	audit, ok := r.Context().Value(auditKey).(func(AuditEvent))
+	if !ok {
+		audit = LogAudit
+	}
+	audit(newAuditEvent(r, outcome, principal, reason))
+}
+
+func newAuditEvent(r *http.Request, outcome string, principal *Principal, reason string) AuditEvent {
+	event := AuditEvent{
+		Time:       time.Now().UTC(),
+		Outcome:    outcome,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RemoteAddr: r.RemoteAddr,
+		RequestID:  middleware.GetReqID(r.Context()),
+		Reason:     reason,
+	}
+	if principal != nil {
+		event.Principal = principal.Method + ":" + principal.ID
+		event.TenantID = principal.TenantID
+	}
+	return event
+}
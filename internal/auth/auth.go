@@ -0,0 +1,201 @@
+// Package auth authenticates requests to our REST API and checks per-route scopes.
+//
+// Credentials are checked by a chain of Authenticators (API keys, JWT bearer tokens); the
+// first one that recognises the request's credentials decides. The resulting Principal is
+// stored in the request context, and routes declare the scopes they need with Require.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/lwlach/turvo-integration-backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Scopes a route can require
+const (
+	ScopeLoadsRead    = "loads:read"    // List loads and statuses
+	ScopeLoadsWrite   = "loads:write"   // Create loads
+	ScopeTenantsAdmin = "tenants:admin" // Manage tenants
+	ScopeTenantsAny   = "tenants:any"   // Select any tenant with X-Tenant-ID (for credentials not bound to a tenant)
+)
+
+// TenantScopes are the scopes a tenant-bound credential may hold
+var TenantScopes = []string{ScopeLoadsRead, ScopeLoadsWrite}
+
+// Authentication methods
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+	MethodNone   = "none" // Authentication disabled
+)
+
+// ErrNoCredentials is returned by an Authenticator when the request carries no credentials it handles
+var ErrNoCredentials = errors.New("no credentials")
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Method   string
+	ID       string // API key ID or JWT subject
+	TenantID string // Tenant the credentials are bound to; empty if not bound to one
+	Scopes   []string
+}
+
+// HasScope reports whether the principal was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator checks one kind of credentials. It returns ErrNoCredentials if the request
+// does not carry that kind, and any other error if the credentials are invalid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type contextKey int
+
+const (
+	principalKey contextKey = iota
+	auditKey
+)
+
+// FromContext returns the authenticated principal of the request
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(*Principal)
+	return principal, ok
+}
+
+// WithPrincipal returns a context carrying the principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// Config configures the authentication middleware
+type Config struct {
+	// Authenticators are tried in order
+	Authenticators []Authenticator
+
+	// Disabled lets every request through as an anonymous principal with all tenant scopes
+	// and ScopeTenantsAny (but not ScopeTenantsAdmin). Only for local development.
+	Disabled bool
+
+	// Audit receives failed authentication and authorization attempts; defaults to LogAudit
+	Audit func(AuditEvent)
+}
+
+// Middleware authenticates every request and rejects those without valid credentials with 401
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	audit := cfg.Audit
+	if audit == nil {
+		audit = LogAudit
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), auditKey, audit)
+
+			if cfg.Disabled {
+				anonymous := &Principal{
+					Method: MethodNone,
+					ID:     "anonymous",
+					Scopes: append([]string{ScopeTenantsAny}, TenantScopes...),
+				}
+				next.ServeHTTP(w, r.WithContext(WithPrincipal(ctx, anonymous)))
+				return
+			}
+
+			_, span := tracing.Start(ctx, "auth.authenticate")
+			principal, err := authenticate(cfg.Authenticators, r)
+			if principal != nil {
+				span.SetAttributes(
+					attribute.String("auth.method", principal.Method),
+					attribute.String("auth.principal", principal.ID),
+					tracing.TenantID.String(principal.TenantID),
+				)
+			}
+			tracing.End(span, err)
+			if err != nil {
+				audit(newAuditEvent(r, AuditUnauthenticated, nil, err.Error()))
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(ctx, principal)))
+		})
+	}
+}
+
+// Require rejects requests whose principal lacks any of scopes with 403
+func Require(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := FromContext(r.Context())
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			var missing []string
+			for _, scope := range scopes {
+				if !principal.HasScope(scope) {
+					missing = append(missing, scope)
+				}
+			}
+			if len(missing) > 0 {
+				reason := "missing scope " + strings.Join(missing, ", ")
+				Audit(r, AuditForbidden, principal, reason)
+				http.Error(w, "forbidden: "+reason, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireUnbound rejects requests whose principal is bound to a tenant with 403, for routes
+// that act across tenants. Scopes alone do not suffice there: a tenant's credentials may
+// carry an admin scope.
+func RequireUnbound() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := FromContext(r.Context())
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if principal.TenantID != "" {
+				reason := "credentials bound to tenant " + principal.TenantID
+				Audit(r, AuditForbidden, principal, reason)
+				http.Error(w, "forbidden: "+reason, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authenticate asks each authenticator in turn; the first that finds its credentials decides
+func authenticate(authenticators []Authenticator, r *http.Request) (*Principal, error) {
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return principal, nil
+	}
+	return nil, errors.New("authentication required")
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequireUnbound(t *testing.T) {
+	keys, err := NewStaticKeys([]StaticKey{
+		{ID: "admin", Hash: HashAPIKey("admin-key"), Scopes: []string{ScopeTenantsAdmin}},
+		{ID: "acme-admin", Hash: HashAPIKey("acme-key"), Tenant: "acme", Scopes: []string{ScopeTenantsAdmin}},
+	})
+	if err != nil {
+		t.Fatalf("NewStaticKeys: %v", err)
+	}
+	var events []AuditEvent
+	handler := Middleware(Config{
+		Authenticators: []Authenticator{NewAPIKeyAuthenticator(keys), newTestJWTAuthenticator(t)},
+		Audit:          func(event AuditEvent) { events = append(events, event) },
+	})(Require(ScopeTenantsAdmin)(RequireUnbound()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))))
+
+	apiKeyRequest := func(key string) *http.Request {
+		request := httptest.NewRequest(http.MethodGet, "/admin/tenants", nil)
+		request.Header.Set(APIKeyHeader, key)
+		return request
+	}
+	adminToken := signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"scope": "tenants:admin"}))
+	tests := []struct {
+		name    string
+		request *http.Request
+		status  int
+	}{
+		{name: "API key not bound to a tenant", request: apiKeyRequest("admin-key"), status: http.StatusNoContent},
+		{name: "API key bound to a tenant", request: apiKeyRequest("acme-key"), status: http.StatusForbidden},
+		{name: "JWT with a tenant claim", request: bearerRequest(adminToken), status: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events = nil
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, test.request)
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if test.status == http.StatusForbidden {
+				if !strings.Contains(recorder.Body.String(), "bound to tenant acme") {
+					t.Errorf("body = %q, want the tenant named", recorder.Body)
+				}
+				if len(events) != 1 || events[0].Outcome != AuditForbidden || events[0].TenantID != "acme" {
+					t.Errorf("audit events = %+v, want one forbidden for tenant acme", events)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,414 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // Registers SHA-256 for crypto.Hash
+	_ "crypto/sha512" // Registers SHA-384 and SHA-512 for crypto.Hash
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often the JWKS file may be re-read when a token names an unknown key
+const jwksReloadInterval = 10 * time.Second
+
+// JWTConfig configures JWT bearer token authentication
+type JWTConfig struct {
+	JWKSPath    string        // Local JWKS file with the issuer's public keys
+	Issuer      string        // Required "iss"; not checked if empty
+	Audience    string        // Required "aud" entry; not checked if empty
+	TenantClaim string        // Claim holding the tenant ID; default "tenant_id"
+	Leeway      time.Duration // Allowed clock skew for exp/nbf; default 1m
+}
+
+// JWTAuthenticator authenticates "Authorization: Bearer <JWT>" tokens signed with RS256/384/512
+// or ES256/384/512 by a key in a local JWKS file. Scopes come from the "scope" claim
+// (space-separated) or "scp" (array). The file is re-read when a token names an unknown key,
+// so keys can be rotated without a restart.
+type JWTAuthenticator struct {
+	cfg JWTConfig
+
+	mutex      sync.RWMutex
+	keys       map[string]crypto.PublicKey // By kid
+	modTime    time.Time
+	lastReload time.Time
+}
+
+func NewJWTAuthenticator(cfg JWTConfig) (*JWTAuthenticator, error) {
+	if cfg.TenantClaim == "" {
+		cfg.TenantClaim = "tenant_id"
+	}
+	if cfg.Leeway == 0 {
+		cfg.Leeway = time.Minute
+	}
+
+	a := &JWTAuthenticator{cfg: cfg}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Authenticate implements Authenticator
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return nil, ErrNoCredentials
+	}
+	token := strings.TrimSpace(header[len(prefix):])
+
+	claims, err := a.verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bearer token: %w", err)
+	}
+
+	principal := &Principal{
+		Method: MethodJWT,
+		Scopes: claims.scopes(),
+	}
+	principal.ID, _ = claims["sub"].(string)
+	principal.TenantID, _ = claims[a.cfg.TenantClaim].(string)
+	if principal.ID == "" {
+		return nil, errors.New("invalid bearer token: sub is required")
+	}
+	return principal, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims map[string]interface{}
+
+// verify checks the token's signature and registered claims and returns its claims
+func (a *JWTAuthenticator) verify(token string) (jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+
+	hash, err := algorithmHash(header.Alg)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := a.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(header.Alg, key, hasher.Sum(nil), hash, signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %w", err)
+	}
+	if err := a.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkClaims validates exp, nbf, iss and aud
+func (a *JWTAuthenticator) checkClaims(claims jwtClaims) error {
+	now := time.Now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("exp is required")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(a.cfg.Leeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(a.cfg.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not valid yet")
+	}
+
+	if a.cfg.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != a.cfg.Issuer {
+			return fmt.Errorf("unexpected issuer %q", iss)
+		}
+	}
+
+	if a.cfg.Audience != "" && !claims.hasAudience(a.cfg.Audience) {
+		return errors.New("token is not for this audience")
+	}
+	return nil
+}
+
+func (c jwtClaims) hasAudience(audience string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, entry := range aud {
+			if entry == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c jwtClaims) scopes() []string {
+	if scope, ok := c["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	switch scp := c["scp"].(type) {
+	case string:
+		return strings.Fields(scp)
+	case []interface{}:
+		scopes := make([]string, 0, len(scp))
+		for _, entry := range scp {
+			if scope, ok := entry.(string); ok {
+				scopes = append(scopes, scope)
+			}
+		}
+		return scopes
+	}
+	return nil
+}
+
+// key returns the verification key for kid, re-reading the JWKS file if the key is unknown.
+// Tokens without kid are accepted only when the file holds a single key.
+func (a *JWTAuthenticator) key(kid string) (crypto.PublicKey, error) {
+	if key, found := a.lookup(kid); found {
+		return key, nil
+	}
+
+	a.mutex.Lock()
+	due := time.Since(a.lastReload) >= jwksReloadInterval
+	a.mutex.Unlock()
+	if due {
+		if err := a.reload(); err != nil {
+			return nil, err
+		}
+		if key, found := a.lookup(kid); found {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (a *JWTAuthenticator) lookup(kid string) (crypto.PublicKey, bool) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, true
+		}
+	}
+	key, found := a.keys[kid]
+	return key, found
+}
+
+// reload re-reads the JWKS file if it changed since the last read
+func (a *JWTAuthenticator) reload() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.lastReload = time.Now()
+
+	info, err := os.Stat(a.cfg.JWKSPath)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	if a.keys != nil && info.ModTime().Equal(a.modTime) {
+		return nil
+	}
+
+	keys, err := loadJWKS(a.cfg.JWKSPath)
+	if err != nil {
+		return err
+	}
+	a.keys = keys
+	a.modTime = info.ModTime()
+	return nil
+}
+
+// jwk is a JSON Web Key; only the RSA and EC public key fields are used
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadJWKS reads the public keys of a JWKS file
+func loadJWKS(path string) (map[string]crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS file %s: %w", path, err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	var errs []error
+	for i, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("keys[%d] (kid %q): %w", i, key.Kid, err))
+			continue
+		}
+		keys[key.Kid] = publicKey
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid JWKS file %s:\n%w", path, errors.Join(errs...))
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS file %s has no signing keys", path)
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid e")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		// Coordinates are padded to the curve size; build the uncompressed point 0x04 || x || y
+		size := (curve.Params().BitSize + 7) / 8
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return nil, errors.New("invalid x or y")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		publicKey, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+		if err != nil {
+			return nil, err
+		}
+		return publicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// algorithmHash returns the hash of a supported signing algorithm. "none" and HMAC
+// algorithms are rejected: only the issuer may sign tokens.
+func algorithmHash(alg string) (crypto.Hash, error) {
+	switch alg {
+	case "RS256", "ES256":
+		return crypto.SHA256, nil
+	case "RS384", "ES384":
+		return crypto.SHA384, nil
+	case "RS512", "ES512":
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+// curveAlgorithms maps each supported curve to the only algorithm allowed with it
+var curveAlgorithms = map[string]string{
+	"P-256": "ES256",
+	"P-384": "ES384",
+	"P-521": "ES512",
+}
+
+// verifySignature checks a signature, making sure the key type matches the algorithm
+func verifySignature(alg string, key crypto.PublicKey, digest []byte, hash crypto.Hash, signature []byte) error {
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm %s does not match RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(publicKey, hash, digest, signature); err != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if curveAlgorithms[publicKey.Curve.Params().Name] != alg {
+			return fmt.Errorf("algorithm %s does not match EC key", alg)
+		}
+		// JWS ECDSA signatures are r || s, each padded to the curve size
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(publicKey, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return errors.New("unsupported key")
+	}
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
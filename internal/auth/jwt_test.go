@@ -0,0 +1,322 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Keys are generated once; RSA key generation is slow
+var (
+	rsaKey   = mustGenerate(rsa.GenerateKey(rand.Reader, 2048))
+	ecKey    = mustGenerate(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	otherKey = mustGenerate(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+)
+
+func mustGenerate[K any](key K, err error) K {
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// writeJWKS writes the public keys of signers to a JWKS file, by kid
+func writeJWKS(t *testing.T, path string, signers map[string]crypto.Signer) {
+	t.Helper()
+	encode := base64.RawURLEncoding.EncodeToString
+
+	var keys []map[string]string
+	for kid, signer := range signers {
+		switch key := signer.Public().(type) {
+		case *rsa.PublicKey:
+			e := bigEndian(key.E)
+			keys = append(keys, map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": encode(key.N.Bytes()), "e": encode(e)})
+		case *ecdsa.PublicKey:
+			point, err := key.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			size := (len(point) - 1) / 2
+			keys = append(keys, map[string]string{"kty": "EC", "kid": kid, "crv": key.Curve.Params().Name, "x": encode(point[1 : 1+size]), "y": encode(point[1+size:])})
+		}
+	}
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// bigEndian returns the minimal big-endian bytes of a positive int
+func bigEndian(value int) []byte {
+	var data []byte
+	for ; value > 0; value >>= 8 {
+		data = append([]byte{byte(value)}, data...)
+	}
+	return data
+}
+
+// signJWT builds a token with the given header alg and kid, signed by signer as alg requires.
+// Signing with a key of another type than alg produces a token whose header and key disagree.
+func signJWT(t *testing.T, alg, kid string, signer crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	encode := func(value interface{}) string {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signingInput := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch key := signer.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	case nil:
+		// alg "none": no signature
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// newTestJWTAuthenticator trusts rsaKey as "rsa" and ecKey as "ec"
+func newTestJWTAuthenticator(t *testing.T) *JWTAuthenticator {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey})
+
+	authenticator, err := NewJWTAuthenticator(JWTConfig{
+		JWKSPath: path,
+		Issuer:   "https://issuer.example.com",
+		Audience: "turvo-integration",
+		Leeway:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator: %v", err)
+	}
+	return authenticator
+}
+
+// validClaims returns claims the test authenticator accepts, with changes applied
+func validClaims(changes map[string]interface{}) map[string]interface{} {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"sub":       "service-1",
+		"iss":       "https://issuer.example.com",
+		"aud":       "turvo-integration",
+		"exp":       now.Add(time.Hour).Unix(),
+		"nbf":       now.Add(-time.Minute).Unix(),
+		"scope":     "loads:read loads:write",
+		"tenant_id": "acme",
+	}
+	for claim, value := range changes {
+		if value == nil {
+			delete(claims, claim)
+		} else {
+			claims[claim] = value
+		}
+	}
+	return claims
+}
+
+func bearerRequest(token string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/loads", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	return request
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+	authenticator := newTestJWTAuthenticator(t)
+	now := time.Now()
+
+	hs256 := func() string {
+		// An HMAC token "signed" with public information, as in key confusion attacks
+		token := signJWT(t, "HS256", "rsa", nil, validClaims(nil))
+		signingInput := token[:strings.LastIndex(token, ".")]
+		mac := hmac.New(sha256.New, rsaKey.PublicKey.N.Bytes())
+		mac.Write([]byte(signingInput))
+		return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+	tampered := func() string {
+		parts := strings.Split(signJWT(t, "RS256", "rsa", rsaKey, validClaims(nil)), ".")
+		parts[1] = strings.Split(signJWT(t, "RS256", "rsa", rsaKey, validClaims(map[string]interface{}{"scope": "tenants:admin"})), ".")[1]
+		return strings.Join(parts, ".")
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   string // Expected error substring; empty if the token is valid
+	}{
+		{name: "RS256", token: signJWT(t, "RS256", "rsa", rsaKey, validClaims(nil))},
+		{name: "ES256", token: signJWT(t, "ES256", "ec", ecKey, validClaims(nil))},
+		{name: "alg none", token: signJWT(t, "none", "rsa", nil, validClaims(nil)), err: `unsupported algorithm "none"`},
+		{name: "HS256 with the public key as secret", token: hs256(), err: `unsupported algorithm "HS256"`},
+		{name: "RS256 header with an EC key", token: signJWT(t, "RS256", "ec", ecKey, validClaims(nil)), err: "algorithm RS256 does not match EC key"},
+		{name: "ES256 header with an RSA key", token: signJWT(t, "ES256", "rsa", rsaKey, validClaims(nil)), err: "algorithm ES256 does not match RSA key"},
+		{name: "ES384 header with a P-256 key", token: signJWT(t, "ES384", "ec", ecKey, validClaims(nil)), err: "algorithm ES384 does not match EC key"},
+		{name: "signed by an untrusted key", token: signJWT(t, "ES256", "ec", otherKey, validClaims(nil)), err: "invalid signature"},
+		{name: "tampered claims", token: tampered(), err: "invalid signature"},
+		{name: "unknown kid", token: signJWT(t, "ES256", "rotated", ecKey, validClaims(nil)), err: `unknown signing key "rotated"`},
+		{name: "malformed", token: "not.a-token", err: "malformed token"},
+		{name: "expired within leeway", token: signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()}))},
+		{name: "expired beyond leeway", token: signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()})), err: "token expired"},
+		{name: "no exp", token: signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"exp": nil})), err: "exp is required"},
+		{name: "nbf within leeway", token: signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"nbf": now.Add(30 * time.Second).Unix()}))},
+		{name: "nbf beyond leeway", token: signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"nbf": now.Add(2 * time.Minute).Unix()})), err: "token not valid yet"},
+		{name: "wrong issuer", token: signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"iss": "https://evil.example.com"})), err: `unexpected issuer "https://evil.example.com"`},
+		{name: "no issuer", token: signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"iss": nil})), err: `unexpected issuer ""`},
+		{name: "wrong audience", token: signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"aud": "other-api"})), err: "not for this audience"},
+		{name: "audience in a list", token: signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"aud": []string{"other-api", "turvo-integration"}}))},
+		{name: "no subject", token: signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"sub": nil})), err: "sub is required"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(bearerRequest(test.token))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if principal.Method != MethodJWT || principal.ID != "service-1" || principal.TenantID != "acme" {
+				t.Errorf("principal = %+v, want service-1 of tenant acme", principal)
+			}
+			if !principal.HasScope(ScopeLoadsRead) || !principal.HasScope(ScopeLoadsWrite) {
+				t.Errorf("scopes = %v, want loads:read and loads:write", principal.Scopes)
+			}
+		})
+	}
+}
+
+func TestJWTScopeClaims(t *testing.T) {
+	authenticator := newTestJWTAuthenticator(t)
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   string
+	}{
+		{name: "scope string", claims: validClaims(nil), want: "loads:read,loads:write"},
+		{name: "scp array", claims: validClaims(map[string]interface{}{"scope": nil, "scp": []string{"loads:read"}}), want: "loads:read"},
+		{name: "no scopes", claims: validClaims(map[string]interface{}{"scope": nil}), want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(bearerRequest(signJWT(t, "ES256", "ec", ecKey, test.claims)))
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if got := strings.Join(principal.Scopes, ","); got != test.want {
+				t.Errorf("scopes = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestJWTNotBearer(t *testing.T) {
+	authenticator := newTestJWTAuthenticator(t)
+	for _, header := range []string{"", "Basic dXNlcjpwYXNz", "Bearer"} {
+		request := httptest.NewRequest(http.MethodGet, "/loads", nil)
+		request.Header.Set("Authorization", header)
+		if _, err := authenticator.Authenticate(request); err != ErrNoCredentials {
+			t.Errorf("Authorization %q: error = %v, want ErrNoCredentials", header, err)
+		}
+	}
+}
+
+func TestJWTReloadsKeysForUnknownKid(t *testing.T) {
+	authenticator := newTestJWTAuthenticator(t)
+	token := signJWT(t, "ES256", "rotated", otherKey, validClaims(nil))
+
+	// The issuer rotates in a new key
+	writeJWKS(t, authenticator.cfg.JWKSPath, map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey, "rotated": otherKey})
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(authenticator.cfg.JWKSPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reloads are rate limited, so a token with an unknown kid right after the last one fails
+	if _, err := authenticator.Authenticate(bearerRequest(token)); err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Fatalf("error = %v, want an unknown signing key before the reload interval", err)
+	}
+
+	authenticator.mutex.Lock()
+	authenticator.lastReload = time.Now().Add(-jwksReloadInterval)
+	authenticator.mutex.Unlock()
+	if _, err := authenticator.Authenticate(bearerRequest(token)); err != nil {
+		t.Fatalf("Authenticate after the key was added: %v", err)
+	}
+}
+
+func TestRequireMissingScope(t *testing.T) {
+	authenticator := newTestJWTAuthenticator(t)
+	var events []AuditEvent
+	handler := Middleware(Config{
+		Authenticators: []Authenticator{authenticator},
+		Audit:          func(event AuditEvent) { events = append(events, event) },
+	})(Require(ScopeTenantsAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "scope granted", token: signJWT(t, "ES256", "ec", ecKey, validClaims(map[string]interface{}{"scope": "tenants:admin"})), status: http.StatusNoContent},
+		{name: "scope missing", token: signJWT(t, "ES256", "ec", ecKey, validClaims(nil)), status: http.StatusForbidden},
+		{name: "invalid token", token: signJWT(t, "ES256", "ec", otherKey, validClaims(nil)), status: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events = nil
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, bearerRequest(test.token))
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if test.status == http.StatusForbidden {
+				if !strings.Contains(recorder.Body.String(), "missing scope tenants:admin") {
+					t.Errorf("body = %q, want the missing scope", recorder.Body)
+				}
+				if len(events) != 1 || events[0].Outcome != AuditForbidden {
+					t.Errorf("audit events = %+v, want one forbidden", events)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a size-bounded, in-memory cache with per-entry expiry.
+// When full, the least recently used entry is evicted. It is safe for concurrent use.
+type LRU[K comparable, V any] struct {
+	capacity int
+	ttl      time.Duration
+
+	entries   map[K]*list.Element
+	evictList *list.List
+	mutex     sync.Mutex
+}
+
+type lruEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// NewLRU creates a cache holding at most capacity entries, each valid for ttl
+func NewLRU[K comparable, V any](capacity int, ttl time.Duration) *LRU[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &LRU[K, V]{
+		capacity:  capacity,
+		ttl:       ttl,
+		entries:   make(map[K]*list.Element),
+		evictList: list.New(),
+	}
+}
+
+// Get returns the cached value for key if present and not expired
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var zero V
+	element, found := c.entries[key]
+	if !found {
+		return zero, false
+	}
+
+	entry := element.Value.(*lruEntry[K, V])
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(element)
+		return zero, false
+	}
+
+	c.evictList.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set stores value under key, evicting the least recently used entry if the cache is full
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+
+	if element, found := c.entries[key]; found {
+		entry := element.Value.(*lruEntry[K, V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.evictList.MoveToFront(element)
+		return
+	}
+
+	element := c.evictList.PushFront(&lruEntry[K, V]{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+	c.entries[key] = element
+
+	if c.evictList.Len() > c.capacity {
+		c.removeElement(c.evictList.Back())
+	}
+}
+
+// Delete removes key from the cache
+func (c *LRU[K, V]) Delete(key K) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, found := c.entries[key]; found {
+		c.removeElement(element)
+	}
+}
+
+// Purge removes all entries from the cache
+func (c *LRU[K, V]) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = make(map[K]*list.Element)
+	c.evictList.Init()
+}
+
+// Len returns the number of entries in the cache, including expired ones not yet removed
+func (c *LRU[K, V]) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.evictList.Len()
+}
+
+func (c *LRU[K, V]) removeElement(element *list.Element) {
+	entry := element.Value.(*lruEntry[K, V])
+	delete(c.entries, entry.key)
+	c.evictList.Remove(element)
+}
@@ -0,0 +1,308 @@
+// Package config holds the service configuration. It is loaded from a YAML, JSON or TOML
+// file, then environment variables, then command-line flags, each overriding the one before.
+//
+// Every setting is a field with a yaml tag (its name in the file, and with its section the
+// flag name, e.g. -server.port) and usually an env tag. Fields tagged secret:"true" are
+// redacted when the configuration is printed. TOML files use the same names as YAML.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Turvo client modes (see turvo.ModeLive, turvo.ModeRecord and turvo.ModeReplay)
+var turvoModes = []string{"live", "record", "replay"}
+
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// Ways of handling unknown fields in request bodies
+var unknownFieldModes = []string{"warn", "reject"}
+
+// Trace exporters (see tracing.ExporterNone and tracing.ExporterOTLP)
+var traceExporters = []string{"none", "otlp"}
+
+type Config struct {
+	Server  Server  `yaml:"server"`
+	Turvo   Turvo   `yaml:"turvo"`
+	Cache   Cache   `yaml:"cache"`
+	Ready   Ready   `yaml:"ready"`
+	Tenants Tenants `yaml:"tenants"`
+	Auth    Auth    `yaml:"auth"`
+	CORS    CORS    `yaml:"cors"`
+	Notify  Notify  `yaml:"notify"`
+	Metrics Metrics `yaml:"metrics"`
+	Log     Log     `yaml:"log"`
+	Tracing Tracing `yaml:"tracing"`
+}
+
+// Server configures our HTTP server. A zero timeout means no timeout.
+type Server struct {
+	Port              string        `yaml:"port" env:"PORT"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"readTimeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout" env:"SERVER_IDLE_TIMEOUT"`
+	// ShutdownTimeout bounds draining in-flight requests and stopping background workers
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT"`
+	// MaxBodyBytes limits request bodies; larger ones are answered with 413
+	MaxBodyBytes int `yaml:"maxBodyBytes" env:"SERVER_MAX_BODY_BYTES"`
+	// UnknownFields is "warn" to ignore unknown fields of request bodies and list them in the
+	// response, or "reject" to answer 400
+	UnknownFields string `yaml:"unknownFields" env:"SERVER_UNKNOWN_FIELDS"`
+}
+
+// Turvo configures the Turvo API clients. The credentials are those of the default tenant.
+type Turvo struct {
+	BaseURL       string        `yaml:"baseURL" env:"TURVO_BASE_URL"`
+	AuthURL       string        `yaml:"authURL" env:"TURVO_AUTH_URL"`
+	APIKey        string        `yaml:"apiKey" env:"TURVO_API_KEY" secret:"true"` // Legacy: Use ClientName/ClientSecret instead
+	ClientName    string        `yaml:"clientName" env:"TURVO_CLIENT_NAME"`
+	ClientSecret  string        `yaml:"clientSecret" env:"TURVO_CLIENT_SECRET" secret:"true"`
+	Username      string        `yaml:"username" env:"TURVO_USERNAME"`
+	Password      string        `yaml:"password" env:"TURVO_PASSWORD" secret:"true"`
+	WebhookSecret string        `yaml:"webhookSecret" env:"TURVO_WEBHOOK_SECRET" secret:"true"`
+	MaxRetries    int           `yaml:"maxRetries" env:"TURVO_MAX_RETRIES"`
+	RetryBackoff  time.Duration `yaml:"retryBackoff" env:"TURVO_RETRY_BACKOFF"`
+	RateLimit     float64       `yaml:"rateLimit" env:"TURVO_RATE_LIMIT"` // Requests per second per tenant; 0 means unlimited
+	RateBurst     int           `yaml:"rateBurst" env:"TURVO_RATE_BURST"`
+	DetailWorkers int           `yaml:"detailWorkers" env:"DETAIL_WORKERS"`
+	Mode          string        `yaml:"mode" env:"TURVO_MODE"`
+	Cassette      string        `yaml:"cassette" env:"TURVO_CASSETTE"`
+	// SearchScanLimit bounds the shipments scanned per request for filters and sorts Turvo
+	// cannot apply
+	SearchScanLimit int `yaml:"searchScanLimit" env:"TURVO_SEARCH_SCAN_LIMIT"`
+	// Tokens expiring within TokenRefreshMargin are refreshed in the background, checked
+	// every TokenRefreshInterval; 0 disables background refresh
+	TokenRefreshInterval time.Duration `yaml:"tokenRefreshInterval" env:"TURVO_TOKEN_REFRESH_INTERVAL"`
+	TokenRefreshMargin   time.Duration `yaml:"tokenRefreshMargin" env:"TURVO_TOKEN_REFRESH_MARGIN"`
+}
+
+// Cache configures the per-tenant shipment details cache
+type Cache struct {
+	DetailsTTL  time.Duration `yaml:"detailsTTL" env:"DETAILS_CACHE_TTL"`
+	DetailsSize int           `yaml:"detailsSize" env:"DETAILS_CACHE_SIZE"`
+}
+
+// Ready configures the readiness check (GET /ready)
+type Ready struct {
+	StaleAfter time.Duration `yaml:"staleAfter" env:"READY_STALE_AFTER"` // Turvo is pinged if it has not answered for this long, and results are reused as long
+	Timeout    time.Duration `yaml:"timeout" env:"READY_TIMEOUT"`
+}
+
+// Tenants configures the default tenant, the tenant store and the mapping profiles
+type Tenants struct {
+	DefaultID      string   `yaml:"defaultID" env:"TENANT_ID"`
+	DefaultName    string   `yaml:"defaultName" env:"TENANT_NAME"`
+	APIKeys        []string `yaml:"apiKeys" env:"TENANT_API_KEYS" secret:"true"` // API keys of the default tenant
+	Store          string   `yaml:"store" env:"TENANTS_STORE"`
+	MappingProfile string   `yaml:"mappingProfile" env:"MAPPING_PROFILE"`
+}
+
+// Auth configures authentication of our REST API
+type Auth struct {
+	Disabled       bool          `yaml:"disabled" env:"AUTH_DISABLED"`
+	AdminAPIKey    string        `yaml:"adminAPIKey" env:"ADMIN_API_KEY" secret:"true"`
+	APIKeysFile    string        `yaml:"apiKeysFile" env:"API_KEYS_FILE"`
+	JWKSFile       string        `yaml:"jwksFile" env:"JWKS_FILE"`
+	JWTIssuer      string        `yaml:"jwtIssuer" env:"JWT_ISSUER"`
+	JWTAudience    string        `yaml:"jwtAudience" env:"JWT_AUDIENCE"`
+	JWTTenantClaim string        `yaml:"jwtTenantClaim" env:"JWT_TENANT_CLAIM"`
+	JWTLeeway      time.Duration `yaml:"jwtLeeway" env:"JWT_LEEWAY"`
+}
+
+// CORS configures which browser origins may call the API (see middleware.CORSConfig)
+type CORS struct {
+	AllowedOrigins   []string      `yaml:"allowedOrigins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `yaml:"allowedMethods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string      `yaml:"allowedHeaders" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string      `yaml:"exposedHeaders" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool          `yaml:"allowCredentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"maxAge" env:"CORS_MAX_AGE"`
+}
+
+// Notify configures our load change notifications
+type Notify struct {
+	WebhookURL string `yaml:"webhookURL" env:"NOTIFY_WEBHOOK_URL"` // Notifications go to the log if empty
+}
+
+// Metrics configures the Prometheus metrics endpoint
+type Metrics struct {
+	Enabled bool   `yaml:"enabled" env:"METRICS_ENABLED"`
+	Path    string `yaml:"path" env:"METRICS_PATH"`
+}
+
+// Log configures our JSON log lines
+type Log struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`   // debug also logs Turvo request and response bodies
+	Redact bool   `yaml:"redact" env:"LOG_REDACT"` // Redacts secrets, tokens, emails and phone numbers
+}
+
+// Tracing configures OpenTelemetry tracing (see tracing.Config)
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER"` // none or otlp
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT"` // OTEL_EXPORTER_OTLP_* variables apply if empty
+	ServiceName string  `yaml:"serviceName" env:"OTEL_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sampleRatio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+// Default returns the configuration used for settings that are not configured
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:              "8080",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      2 * time.Minute, // Listing loads with details can take a while
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+			MaxBodyBytes:      1 << 20,
+			UnknownFields:     "warn",
+		},
+		Turvo: Turvo{
+			BaseURL:       "https://my-sandbox.turvo.com",
+			AuthURL:       "https://my-sandbox-publicapi.turvo.com",
+			MaxRetries:    2,
+			RetryBackoff:  500 * time.Millisecond,
+			RateBurst:     1,
+			DetailWorkers: 8,
+			Mode:          "live",
+
+			SearchScanLimit:      1000,
+			TokenRefreshInterval: time.Minute,
+			TokenRefreshMargin:   5 * time.Minute,
+		},
+		Cache: Cache{
+			DetailsTTL:  5 * time.Minute,
+			DetailsSize: 10000,
+		},
+		Ready: Ready{
+			StaleAfter: 5 * time.Minute,
+			Timeout:    5 * time.Second,
+		},
+		Tenants: Tenants{
+			DefaultID: "default",
+		},
+		Auth: Auth{
+			JWTTenantClaim: "tenant_id",
+			JWTLeeway:      time.Minute,
+		},
+		CORS: CORS{
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", "X-Tenant-ID", "X-Request-ID"},
+			ExposedHeaders: []string{"X-Request-ID"},
+			MaxAge:         10 * time.Minute,
+		},
+		Metrics: Metrics{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		Log: Log{
+			Level:  "info",
+			Redact: true,
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			ServiceName: "turvo-integration-backend",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// HasTurvoCredentials reports whether the default tenant's Turvo credentials are configured
+func (c *Config) HasTurvoCredentials() bool {
+	t := c.Turvo
+	return t.ClientName != "" || t.ClientSecret != "" || t.Username != "" || t.Password != ""
+}
+
+// Validate checks the values of every setting and reports all problems at once. Settings
+// that name files (mapping profile, key files) are checked when the files are loaded.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, path, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port < 65536, "server.port", "must be a port number, got %q", c.Server.Port)
+	check(c.Server.ReadHeaderTimeout >= 0, "server.readHeaderTimeout", "must not be negative")
+	check(c.Server.ReadTimeout >= 0, "server.readTimeout", "must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.writeTimeout", "must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idleTimeout", "must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdownTimeout", "must be positive")
+	check(c.Server.MaxBodyBytes >= 1, "server.maxBodyBytes", "must be at least 1")
+	check(contains(unknownFieldModes, c.Server.UnknownFields), "server.unknownFields",
+		"must be one of %v, got %q", unknownFieldModes, c.Server.UnknownFields)
+
+	check(validURL(c.Turvo.BaseURL), "turvo.baseURL", "must be an http(s) URL, got %q", c.Turvo.BaseURL)
+	check(validURL(c.Turvo.AuthURL), "turvo.authURL", "must be an http(s) URL, got %q", c.Turvo.AuthURL)
+	if c.HasTurvoCredentials() {
+		check(c.Turvo.ClientName != "" && c.Turvo.ClientSecret != "", "turvo.clientName/clientSecret",
+			"both are required for authentication")
+		check(c.Turvo.Username != "" && c.Turvo.Password != "", "turvo.username/password",
+			"both are required for authentication")
+	}
+	check(c.Turvo.MaxRetries >= 0, "turvo.maxRetries", "must be 0 (no retries) or more")
+	check(c.Turvo.RetryBackoff >= 0, "turvo.retryBackoff", "must not be negative")
+	check(c.Turvo.RateLimit >= 0, "turvo.rateLimit", "must not be negative")
+	check(c.Turvo.RateBurst >= 1, "turvo.rateBurst", "must be at least 1")
+	check(c.Turvo.TokenRefreshInterval >= 0, "turvo.tokenRefreshInterval", "must not be negative")
+	check(c.Turvo.TokenRefreshMargin >= 0, "turvo.tokenRefreshMargin", "must not be negative")
+	check(c.Turvo.DetailWorkers >= 1, "turvo.detailWorkers", "must be at least 1")
+	check(c.Turvo.SearchScanLimit >= 1, "turvo.searchScanLimit", "must be at least 1")
+	if contains(turvoModes, c.Turvo.Mode) {
+		check(c.Turvo.Mode == "live" || c.Turvo.Cassette != "", "turvo.cassette", "is required in %s mode", c.Turvo.Mode)
+	} else {
+		check(false, "turvo.mode", "must be one of %v, got %q", turvoModes, c.Turvo.Mode)
+	}
+
+	// Webhook events update cached details, so the cache cannot be turned off
+	check(c.Cache.DetailsTTL > 0, "cache.detailsTTL", "must be positive")
+	check(c.Cache.DetailsSize >= 1, "cache.detailsSize", "must be at least 1")
+
+	check(c.Ready.StaleAfter > 0, "ready.staleAfter", "must be positive")
+	check(c.Ready.Timeout > 0, "ready.timeout", "must be positive")
+
+	check(c.Tenants.DefaultID != "", "tenants.defaultID", "must not be empty")
+
+	check(c.Auth.JWTTenantClaim != "", "auth.jwtTenantClaim", "must not be empty")
+	check(c.Auth.JWTLeeway >= 0, "auth.jwtLeeway", "must not be negative")
+
+	check(c.Notify.WebhookURL == "" || validURL(c.Notify.WebhookURL), "notify.webhookURL",
+		"must be an http(s) URL, got %q", c.Notify.WebhookURL)
+
+	if c.Metrics.Enabled {
+		check(strings.HasPrefix(c.Metrics.Path, "/") && c.Metrics.Path != "/", "metrics.path",
+			"must be a path such as /metrics, got %q", c.Metrics.Path)
+	}
+
+	check(contains(logLevels, strings.ToLower(c.Log.Level)), "log.level", "must be one of %v, got %q", logLevels, c.Log.Level)
+
+	check(contains(traceExporters, c.Tracing.Exporter), "tracing.exporter", "must be one of %v, got %q", traceExporters, c.Tracing.Exporter)
+	check(c.Tracing.Endpoint == "" || validURL(c.Tracing.Endpoint), "tracing.endpoint",
+		"must be an http(s) URL, got %q", c.Tracing.Endpoint)
+	check(c.Tracing.ServiceName != "", "tracing.serviceName", "must not be empty")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio", "must be between 0 and 1")
+
+	return errors.Join(errs...)
+}
+
+func validURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cfg *Config)
+		want   string // Problem reported, empty if valid
+	}{
+		{name: "defaults", change: func(cfg *Config) {}},
+		{name: "port not a number", change: func(cfg *Config) { cfg.Server.Port = "http" }, want: `server.port: must be a port number, got "http"`},
+		{name: "port 0", change: func(cfg *Config) { cfg.Server.Port = "0" }, want: "server.port: must be a port number"},
+		{name: "port too large", change: func(cfg *Config) { cfg.Server.Port = "65536" }, want: "server.port: must be a port number"},
+		{name: "highest port", change: func(cfg *Config) { cfg.Server.Port = "65535" }},
+		{name: "negative read header timeout", change: func(cfg *Config) { cfg.Server.ReadHeaderTimeout = -time.Second }, want: "server.readHeaderTimeout: must not be negative"},
+		{name: "negative read timeout", change: func(cfg *Config) { cfg.Server.ReadTimeout = -time.Second }, want: "server.readTimeout: must not be negative"},
+		{name: "negative write timeout", change: func(cfg *Config) { cfg.Server.WriteTimeout = -time.Second }, want: "server.writeTimeout: must not be negative"},
+		{name: "negative idle timeout", change: func(cfg *Config) { cfg.Server.IdleTimeout = -time.Second }, want: "server.idleTimeout: must not be negative"},
+		{name: "no server timeouts", change: func(cfg *Config) {
+			cfg.Server.ReadHeaderTimeout, cfg.Server.ReadTimeout, cfg.Server.WriteTimeout, cfg.Server.IdleTimeout = 0, 0, 0, 0
+		}},
+		{name: "zero shutdown timeout", change: func(cfg *Config) { cfg.Server.ShutdownTimeout = 0 }, want: "server.shutdownTimeout: must be positive"},
+		{name: "zero max body bytes", change: func(cfg *Config) { cfg.Server.MaxBodyBytes = 0 }, want: "server.maxBodyBytes: must be at least 1"},
+		{name: "unknown fields mode", change: func(cfg *Config) { cfg.Server.UnknownFields = "ignore" }, want: `server.unknownFields: must be one of [warn reject], got "ignore"`},
+		{name: "reject unknown fields", change: func(cfg *Config) { cfg.Server.UnknownFields = "reject" }},
+		{name: "base URL without a scheme", change: func(cfg *Config) { cfg.Turvo.BaseURL = "my-sandbox.turvo.com" }, want: `turvo.baseURL: must be an http(s) URL, got "my-sandbox.turvo.com"`},
+		{name: "auth URL with another scheme", change: func(cfg *Config) { cfg.Turvo.AuthURL = "ftp://turvo.com" }, want: "turvo.authURL: must be an http(s) URL"},
+		{
+			name: "client name without secret",
+			change: func(cfg *Config) {
+				cfg.Turvo.ClientName, cfg.Turvo.Username, cfg.Turvo.Password = "acme", "user", "hunter2"
+			},
+			want: "turvo.clientName/clientSecret: both are required for authentication",
+		},
+		{
+			name: "password without username",
+			change: func(cfg *Config) {
+				cfg.Turvo.ClientName, cfg.Turvo.ClientSecret, cfg.Turvo.Password = "acme", "secret", "hunter2"
+			},
+			want: "turvo.username/password: both are required for authentication",
+		},
+		{
+			name: "all credentials",
+			change: func(cfg *Config) {
+				cfg.Turvo.ClientName, cfg.Turvo.ClientSecret, cfg.Turvo.Username, cfg.Turvo.Password = "acme", "secret", "user", "hunter2"
+			},
+		},
+		{name: "negative max retries", change: func(cfg *Config) { cfg.Turvo.MaxRetries = -1 }, want: "turvo.maxRetries: must be 0 (no retries) or more"},
+		{name: "no retries", change: func(cfg *Config) { cfg.Turvo.MaxRetries = 0 }},
+		{name: "negative retry backoff", change: func(cfg *Config) { cfg.Turvo.RetryBackoff = -time.Second }, want: "turvo.retryBackoff: must not be negative"},
+		{name: "negative rate limit", change: func(cfg *Config) { cfg.Turvo.RateLimit = -1 }, want: "turvo.rateLimit: must not be negative"},
+		{name: "zero rate burst", change: func(cfg *Config) { cfg.Turvo.RateBurst = 0 }, want: "turvo.rateBurst: must be at least 1"},
+		{name: "negative token refresh interval", change: func(cfg *Config) { cfg.Turvo.TokenRefreshInterval = -time.Second }, want: "turvo.tokenRefreshInterval: must not be negative"},
+		{name: "no background token refresh", change: func(cfg *Config) { cfg.Turvo.TokenRefreshInterval = 0 }},
+		{name: "negative token refresh margin", change: func(cfg *Config) { cfg.Turvo.TokenRefreshMargin = -time.Second }, want: "turvo.tokenRefreshMargin: must not be negative"},
+		{name: "zero detail workers", change: func(cfg *Config) { cfg.Turvo.DetailWorkers = 0 }, want: "turvo.detailWorkers: must be at least 1"},
+		{name: "zero search scan limit", change: func(cfg *Config) { cfg.Turvo.SearchScanLimit = 0 }, want: "turvo.searchScanLimit: must be at least 1"},
+		{name: "unknown mode", change: func(cfg *Config) { cfg.Turvo.Mode = "mock" }, want: `turvo.mode: must be one of [live record replay], got "mock"`},
+		{name: "replay without a cassette", change: func(cfg *Config) { cfg.Turvo.Mode = "replay" }, want: "turvo.cassette: is required in replay mode"},
+		{name: "record without a cassette", change: func(cfg *Config) { cfg.Turvo.Mode = "record" }, want: "turvo.cassette: is required in record mode"},
+		{name: "replay with a cassette", change: func(cfg *Config) { cfg.Turvo.Mode, cfg.Turvo.Cassette = "replay", "sandbox.json" }},
+		{name: "zero details TTL", change: func(cfg *Config) { cfg.Cache.DetailsTTL = 0 }, want: "cache.detailsTTL: must be positive"},
+		{name: "zero details size", change: func(cfg *Config) { cfg.Cache.DetailsSize = 0 }, want: "cache.detailsSize: must be at least 1"},
+		{name: "zero ready stale after", change: func(cfg *Config) { cfg.Ready.StaleAfter = 0 }, want: "ready.staleAfter: must be positive"},
+		{name: "zero ready timeout", change: func(cfg *Config) { cfg.Ready.Timeout = 0 }, want: "ready.timeout: must be positive"},
+		{name: "empty default tenant", change: func(cfg *Config) { cfg.Tenants.DefaultID = "" }, want: "tenants.defaultID: must not be empty"},
+		{name: "empty JWT tenant claim", change: func(cfg *Config) { cfg.Auth.JWTTenantClaim = "" }, want: "auth.jwtTenantClaim: must not be empty"},
+		{name: "negative JWT leeway", change: func(cfg *Config) { cfg.Auth.JWTLeeway = -time.Second }, want: "auth.jwtLeeway: must not be negative"},
+		{name: "notify webhook without a host", change: func(cfg *Config) { cfg.Notify.WebhookURL = "https://" }, want: "notify.webhookURL: must be an http(s) URL"},
+		{name: "notify webhook", change: func(cfg *Config) { cfg.Notify.WebhookURL = "https://hooks.example.com/loads" }},
+		{name: "metrics path without a slash", change: func(cfg *Config) { cfg.Metrics.Path = "metrics" }, want: `metrics.path: must be a path such as /metrics, got "metrics"`},
+		{name: "metrics at the root", change: func(cfg *Config) { cfg.Metrics.Path = "/" }, want: "metrics.path: must be a path such as /metrics"},
+		{name: "metrics path unused when disabled", change: func(cfg *Config) { cfg.Metrics.Enabled, cfg.Metrics.Path = false, "" }},
+		{name: "unknown log level", change: func(cfg *Config) { cfg.Log.Level = "trace" }, want: `log.level: must be one of [debug info warn error], got "trace"`},
+		{name: "log level in capitals", change: func(cfg *Config) { cfg.Log.Level = "DEBUG" }},
+		{name: "unknown trace exporter", change: func(cfg *Config) { cfg.Tracing.Exporter = "jaeger" }, want: `tracing.exporter: must be one of [none otlp], got "jaeger"`},
+		{name: "tracing endpoint without a scheme", change: func(cfg *Config) { cfg.Tracing.Endpoint = "collector:4318" }, want: "tracing.endpoint: must be an http(s) URL"},
+		{name: "empty service name", change: func(cfg *Config) { cfg.Tracing.ServiceName = "" }, want: "tracing.serviceName: must not be empty"},
+		{name: "negative sample ratio", change: func(cfg *Config) { cfg.Tracing.SampleRatio = -0.1 }, want: "tracing.sampleRatio: must be between 0 and 1"},
+		{name: "sample ratio above 1", change: func(cfg *Config) { cfg.Tracing.SampleRatio = 1.5 }, want: "tracing.sampleRatio: must be between 0 and 1"},
+		{name: "no sampling", change: func(cfg *Config) { cfg.Tracing.SampleRatio = 0 }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Default()
+			test.change(cfg)
+			err := cfg.Validate()
+			if test.want == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("error = %v, want %q", err, test.want)
+			}
+			// Only the changed setting is reported
+			if lines := strings.Split(err.Error(), "\n"); len(lines) != 1 {
+				t.Errorf("problems = %q, want one", lines)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = ""
+	cfg.Cache.DetailsSize = 0
+	cfg.Tracing.Exporter = ""
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded")
+	}
+	want := []string{"server.port", "cache.detailsSize", "tracing.exporter"}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("problems = %q, want %v", lines, want)
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, want[i]+": ") {
+			t.Errorf("problem %d = %q, want %s", i, line, want[i])
+		}
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Turvo.ClientName = "acme-client"
+	cfg.Turvo.Username = "dispatch"
+	var secrets []string
+	for _, s := range settings(cfg) {
+		if !s.secret {
+			continue
+		}
+		switch value := "secret-" + s.path; s.value.Interface().(type) {
+		case string:
+			s.value.SetString(value)
+			secrets = append(secrets, value)
+		case []string:
+			s.value.Set(reflect.ValueOf([]string{value + "-1", value + "-2"}))
+			secrets = append(secrets, value+"-1", value+"-2")
+		default:
+			t.Fatalf("secret %s of unsupported type %s", s.path, s.value.Type())
+		}
+	}
+	if len(secrets) == 0 {
+		t.Fatal("no secret settings")
+	}
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	printed := out.String()
+	for _, secret := range secrets {
+		if strings.Contains(printed, secret) {
+			t.Errorf("printed %q", secret)
+		}
+	}
+	for _, want := range []string{
+		"clientSecret: " + redacted,
+		"password: " + redacted,
+		"adminAPIKey: " + redacted,
+		"apiKeys:\n    - " + redacted + "\n    - " + redacted + "\n",
+		"clientName: acme-client",
+		"username: dispatch",
+	} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("printed config\n%s\nwant it to contain %q", printed, want)
+		}
+	}
+
+	// Printing does not change the configuration
+	if cfg.Turvo.Password != "secret-turvo.password" || cfg.Tenants.APIKeys[0] != "secret-tenants.apiKeys-1" {
+		t.Errorf("secrets changed to %q and %q", cfg.Turvo.Password, cfg.Tenants.APIKeys)
+	}
+}
+
+func TestPrintLeavesEmptySecrets(t *testing.T) {
+	var out bytes.Buffer
+	if err := Default().Print(&out); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	if strings.Contains(out.String(), redacted) {
+		t.Errorf("printed config\n%s\nredacts unset secrets", out.String())
+	}
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the config file when the -config flag is not given
+const FileEnv = "CONFIG_FILE"
+
+// redacted replaces secrets when the configuration is printed
+const redacted = "<redacted>"
+
+// setting is a single configurable value
+type setting struct {
+	path   string // Name in the file and flag name, e.g. "server.port"
+	env    string
+	secret bool
+	value  reflect.Value
+}
+
+// Load builds the configuration from the defaults, the config file (-config or CONFIG_FILE),
+// environment variables and the flags in args, then validates it. All problems are reported
+// together; the configuration is returned even then, so it can be printed. If args asks for
+// help, the error is flag.ErrHelp.
+func Load(name string, args []string) (*Config, error) {
+	cfg := Default()
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := flags.String("config", "", "config file (YAML, JSON or TOML; env "+FileEnv+")")
+	type override struct{ flag, value string }
+	var overrides []override
+	for _, s := range settings(cfg) {
+		path := s.path
+		usage := "overrides " + path
+		if s.env != "" {
+			usage += " (env " + s.env + ")"
+		}
+		flags.Func(path, usage, func(value string) error {
+			overrides = append(overrides, override{path, value})
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return cfg, err
+	}
+	if flags.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	var errs []error
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv(FileEnv)
+	}
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	byPath := make(map[string]setting)
+	for _, s := range settings(cfg) {
+		byPath[s.path] = s
+		if s.env == "" {
+			continue
+		}
+		if value := os.Getenv(s.env); value != "" {
+			if err := set(s.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+
+	for _, o := range overrides {
+		if err := set(byPath[o.flag].value, o.value); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", o.flag, err))
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// loadFile decodes a config file over cfg, rejecting unknown settings
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		metadata, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		var errs []error
+		for _, key := range metadata.Undecoded() {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %s", path, key))
+		}
+		return errors.Join(errs...)
+	case ".yaml", ".yml", ".json":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err := decoder.Decode(cfg)
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			// Every unknown or mistyped setting is listed separately
+			errs := make([]error, 0, len(typeErr.Errors))
+			for _, problem := range typeErr.Errors {
+				errs = append(errs, fmt.Errorf("%s: %s", path, problem))
+			}
+			return errors.Join(errs...)
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s: unsupported config file type, use .yaml, .yml, .json or .toml", path)
+	}
+}
+
+// Print writes the configuration as YAML with secrets redacted
+func (c *Config) Print(w io.Writer) error {
+	clone := *c
+
+	for _, s := range settings(&clone) {
+		if !s.secret {
+			continue
+		}
+		switch s.value.Kind() {
+		case reflect.String:
+			if s.value.String() != "" {
+				s.value.SetString(redacted)
+			}
+		case reflect.Slice:
+			// A new slice, so the lists of c are not changed
+			list := make([]string, s.value.Len())
+			for i := range list {
+				list[i] = redacted
+			}
+			s.value.Set(reflect.ValueOf(list))
+		}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&clone); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// settings lists every setting of cfg, section by section
+func settings(cfg *Config) []setting {
+	var result []setting
+	sections := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		sectionName := yamlName(sections.Type().Field(i))
+		for j := 0; j < section.NumField(); j++ {
+			field := section.Type().Field(j)
+			result = append(result, setting{
+				path:   sectionName + "." + yamlName(field),
+				env:    field.Tag.Get("env"),
+				secret: field.Tag.Get("secret") == "true",
+				value:  section.Field(j),
+			})
+		}
+	}
+	return result
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	return name
+}
+
+// set parses value into a setting. Lists are comma-separated.
+func set(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(splitList(value)))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
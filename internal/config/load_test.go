@@ -0,0 +1,244 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeFile writes a config file to a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+server:
+  port: "8081"
+turvo:
+  maxRetries: 4
+  rateLimit: 2.5
+log:
+  level: warn
+cors:
+  allowedOrigins: [https://file.example.com]
+`)
+
+	tests := []struct {
+		name  string
+		file  bool // Pass the file with -config
+		env   map[string]string
+		args  []string
+		check func(cfg *Config) []any // Got and want values, in pairs
+	}{
+		{
+			name: "defaults",
+			check: func(cfg *Config) []any {
+				return []any{cfg.Server.Port, "8080", cfg.Turvo.MaxRetries, 2, cfg.Log.Level, "info", len(cfg.CORS.AllowedOrigins), 0}
+			},
+		},
+		{
+			name: "file over defaults",
+			file: true,
+			check: func(cfg *Config) []any {
+				return []any{cfg.Server.Port, "8081", cfg.Turvo.MaxRetries, 4, cfg.Turvo.RateLimit, 2.5, cfg.Log.Level, "warn",
+					cfg.CORS.AllowedOrigins, []string{"https://file.example.com"}, cfg.Cache.DetailsTTL, 5 * time.Minute}
+			},
+		},
+		{
+			name: "env over file",
+			file: true,
+			env:  map[string]string{"PORT": "8082", "TURVO_MAX_RETRIES": "0", "CORS_ALLOWED_ORIGINS": "https://a.example.com, ,https://b.example.com"},
+			check: func(cfg *Config) []any {
+				return []any{cfg.Server.Port, "8082", cfg.Turvo.MaxRetries, 0, cfg.Log.Level, "warn",
+					cfg.CORS.AllowedOrigins, []string{"https://a.example.com", "https://b.example.com"}}
+			},
+		},
+		{
+			name: "flags over env",
+			file: true,
+			env:  map[string]string{"PORT": "8082", "LOG_LEVEL": "error"},
+			args: []string{"-server.port", "8083", "-cache.detailsTTL=1m", "-log.redact=false"},
+			check: func(cfg *Config) []any {
+				return []any{cfg.Server.Port, "8083", cfg.Log.Level, "error", cfg.Turvo.MaxRetries, 4, cfg.Cache.DetailsTTL, time.Minute, cfg.Log.Redact, false}
+			},
+		},
+		{
+			name: "last flag wins",
+			args: []string{"-server.port", "8083", "-server.port", "8084"},
+			check: func(cfg *Config) []any {
+				return []any{cfg.Server.Port, "8084"}
+			},
+		},
+		{
+			name: "file from the environment",
+			env:  map[string]string{FileEnv: file},
+			check: func(cfg *Config) []any {
+				return []any{cfg.Server.Port, "8081"}
+			},
+		},
+		{
+			name: "-config over the environment",
+			file: true,
+			env:  map[string]string{FileEnv: filepath.Join(t.TempDir(), "missing.yaml")},
+			check: func(cfg *Config) []any {
+				return []any{cfg.Server.Port, "8081"}
+			},
+		},
+		{
+			name: "empty env values are ignored",
+			file: true,
+			env:  map[string]string{"PORT": ""},
+			check: func(cfg *Config) []any {
+				return []any{cfg.Server.Port, "8081"}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			args := test.args
+			if test.file {
+				args = append([]string{"-config", file}, args...)
+			}
+
+			cfg, err := Load("test", args)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			values := test.check(cfg)
+			for i := 0; i < len(values); i += 2 {
+				if !reflect.DeepEqual(values[i], values[i+1]) {
+					t.Errorf("setting %d = %v, want %v", i/2, values[i], values[i+1])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "server:\n  port: \"9000\"\nturvo:\n  retryBackoff: 2s\ncors:\n  allowedOrigins: [https://app.example.com]\n",
+		"config.json": `{"server": {"port": "9000"}, "turvo": {"retryBackoff": "2s"}, "cors": {"allowedOrigins": ["https://app.example.com"]}}`,
+		"config.toml": "[server]\nport = \"9000\"\n[turvo]\nretryBackoff = \"2s\"\n[cors]\nallowedOrigins = [\"https://app.example.com\"]\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := Load("test", []string{"-config", writeFile(t, name, content)})
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Server.Port != "9000" || cfg.Turvo.RetryBackoff != 2*time.Second || len(cfg.CORS.AllowedOrigins) != 1 {
+				t.Errorf("port %s, retryBackoff %s, origins %v; want 9000, 2s, [https://app.example.com]",
+					cfg.Server.Port, cfg.Turvo.RetryBackoff, cfg.CORS.AllowedOrigins)
+			}
+		})
+	}
+}
+
+func TestLoadReportsAllProblems(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+server:
+  prot: "8081"
+turvo:
+  maxRetries: many
+`)
+	t.Setenv("TURVO_RATE_LIMIT", "fast")
+	t.Setenv("LOG_LEVEL", "verbose")
+
+	cfg, err := Load("test", []string{"-config", file, "-cache.detailsTTL", "forever", "-turvo.detailWorkers", "0"})
+	if err == nil {
+		t.Fatal("Load succeeded")
+	}
+	for _, want := range []string{
+		"field prot not found",
+		"cannot unmarshal !!str `many`",
+		`TURVO_RATE_LIMIT: invalid number "fast"`,
+		`-cache.detailsTTL: invalid duration "forever"`,
+		"turvo.detailWorkers: must be at least 1",
+		`log.level: must be one of [debug info warn error], got "verbose"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q\nwant it to contain %q", err, want)
+		}
+	}
+	// The configuration is returned anyway, so it can be printed
+	if cfg == nil || cfg.Log.Level != "verbose" {
+		t.Errorf("config = %+v, want the loaded values", cfg)
+	}
+}
+
+func TestLoadArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "unknown flag", args: []string{"-server.prot", "1"}, want: "flag provided but not defined: -server.prot"},
+		{name: "extra arguments", args: []string{"serve"}, want: "unexpected arguments: serve"},
+		{name: "unsupported file type", args: []string{"-config", "config.ini"}, want: "unsupported config file type"},
+		{name: "missing file", args: []string{"-config", "missing.yaml"}, want: "failed to read config file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.name == "unsupported file type" {
+				test.args[1] = writeFile(t, "config.ini", "port = 1\n")
+			}
+			_, err := Load("test", test.args)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+// config print writes YAML that loads back into the same configuration
+func TestPrintLoadsBack(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com")
+	cfg, err := Load("test", []string{"-server.port", "9000", "-turvo.rateLimit", "2.5", "-tracing.sampleRatio", "0.25"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	printed, err := Load("test", []string{"-config", writeFile(t, "printed.yaml", out.String())})
+	if err != nil {
+		t.Fatalf("Load printed config: %v\n%s", err, out.String())
+	}
+	var again bytes.Buffer
+	if err := printed.Print(&again); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	if again.String() != out.String() {
+		t.Errorf("printed config loads as\n%s\nwant\n%s", again.String(), out.String())
+	}
+	if printed.Server.Port != "9000" || printed.Turvo.RateLimit != 2.5 || printed.Tracing.SampleRatio != 0.25 || len(printed.CORS.AllowedOrigins) != 1 {
+		t.Errorf("printed config loads as %+v, want the overridden settings", printed)
+	}
+
+	// Every section is printed
+	var sections map[string]any
+	if err := yaml.Unmarshal(out.Bytes(), &sections); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sections); got != reflect.TypeOf(Config{}).NumField() {
+		t.Errorf("%d sections printed, want %d", got, reflect.TypeOf(Config{}).NumField())
+	}
+}
@@ -0,0 +1,258 @@
+// Package decode decodes JSON request bodies and reports every field that does not fit the
+// target type by its JSON path (e.g. consignee.appTime or items[0].weight),
+// rather than stopping at the first problem like encoding/json.
+package decode
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// Problems
+const (
+	ProblemUnknown = "unknown field"
+)
+
+// Result lists the fields of a body that do not fit the target type
+type Result struct {
+	Unknown  []models.FieldProblem // Fields the target type does not have; they are ignored when decoding
+	Mistyped []models.FieldProblem // Fields with a value of the wrong type; the body is not decoded then
+}
+
+// SyntaxError reports a body that is not a single JSON object
+type SyntaxError struct {
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Message
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// JSON checks data against the type of v and decodes it into v unless a field is mistyped.
+// Unknown fields are reported but do not prevent decoding; the caller decides whether to
+// reject them. A body that is not a single JSON object is a *SyntaxError.
+func JSON(data []byte, v interface{}) (Result, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return Result{}, syntaxError(err)
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return Result{}, &SyntaxError{Message: "request body must contain a single JSON object"}
+	}
+	if _, ok := body.(map[string]interface{}); !ok {
+		return Result{}, &SyntaxError{Message: "request body must be a JSON object"}
+	}
+
+	var result Result
+	check("", body, reflect.TypeOf(v), &result)
+	if len(result.Mistyped) > 0 {
+		return result, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		// Not expected after the checks above, but reported like a mistyped body
+		result.Mistyped = append(result.Mistyped, models.FieldProblem{Problem: err.Error()})
+	}
+	return result, nil
+}
+
+func syntaxError(err error) error {
+	var jsonErr *json.SyntaxError
+	switch {
+	case errors.As(err, &jsonErr):
+		return &SyntaxError{Message: fmt.Sprintf("invalid JSON at offset %d: %s", jsonErr.Offset, jsonErr.Error())}
+	case errors.Is(err, io.EOF):
+		return &SyntaxError{Message: "request body is empty"}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &SyntaxError{Message: "invalid JSON: unexpected end of body"}
+	default:
+		return &SyntaxError{Message: "invalid JSON: " + err.Error()}
+	}
+}
+
+// check compares a value decoded with UseNumber against t, collecting problems in result
+func check(path string, value interface{}, t reflect.Type, result *Result) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if value == nil {
+		return // null leaves the field unset
+	}
+	mistyped := func(problem string) {
+		result.Mistyped = append(result.Mistyped, models.FieldProblem{Path: path, Problem: problem})
+	}
+
+	if t == timeType {
+		s, ok := value.(string)
+		if !ok {
+			mistyped("must be an RFC 3339 date-time string")
+			return
+		}
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			mistyped(fmt.Sprintf("must be an RFC 3339 date-time such as 2025-01-27T08:00:00Z, got %q", s))
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			mistyped("must be an object")
+			return
+		}
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(object) {
+			field, found := lookupField(fields, key)
+			if !found {
+				result.Unknown = append(result.Unknown, models.FieldProblem{Path: join(path, key), Problem: ProblemUnknown})
+				continue
+			}
+			check(join(path, key), object[key], field.Type, result)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			mistyped("must be an object")
+			return
+		}
+		for _, key := range sortedKeys(object) {
+			check(join(path, key), object[key], t.Elem(), result)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			mistyped("must be an array")
+			return
+		}
+		for i, item := range items {
+			check(fmt.Sprintf("%s[%d]", path, i), item, t.Elem(), result)
+		}
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			mistyped("must be a string")
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			mistyped("must be true or false")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, ok := value.(json.Number)
+		if !ok {
+			mistyped("must be an integer")
+			return
+		}
+		if n, err := number.Int64(); err != nil || reflect.Zero(t).OverflowInt(n) {
+			mistyped(fmt.Sprintf("must be an integer, got %s", number))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, ok := value.(json.Number)
+		if !ok {
+			mistyped("must be a non-negative integer")
+			return
+		}
+		if n, err := number.Int64(); err != nil || n < 0 || reflect.Zero(t).OverflowUint(uint64(n)) {
+			mistyped(fmt.Sprintf("must be a non-negative integer, got %s", number))
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := value.(json.Number); !ok {
+			mistyped("must be a number")
+		}
+	}
+}
+
+// ResolvePath returns the canonical form of a dotted JSON path (e.g. pickup.apptTime) into
+// the type of v, with names matched like JSON keys, or false if the type has no such field
+func ResolvePath(v interface{}, path string) (string, bool) {
+	t := reflect.TypeOf(v)
+	var resolved []string
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || t == timeType {
+			return "", false
+		}
+		field, found := lookupField(jsonFields(t), name)
+		if !found {
+			return "", false
+		}
+		resolved = append(resolved, jsonName(field))
+		t = field.Type
+	}
+	return strings.Join(resolved, "."), true
+}
+
+// jsonFields maps the JSON names of a struct's fields to the fields, including the fields
+// of embedded structs
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		if name, _, _ := strings.Cut(tag, ","); field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for embeddedName, embedded := range jsonFields(field.Type) {
+				if _, taken := fields[embeddedName]; !taken {
+					fields[embeddedName] = embedded
+				}
+			}
+			continue
+		}
+		fields[jsonName(field)] = field
+	}
+	return fields
+}
+
+// jsonName returns the JSON name of a struct field
+func jsonName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// lookupField finds the field for a key. Like encoding/json, an exact match is preferred
+// and a case-insensitive one accepted.
+func lookupField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if field, found := fields[key]; found {
+		return field, true
+	}
+	for name, field := range fields {
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// sortedKeys returns the keys of object in order, so problems are reported in a stable order
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,219 @@
+package decode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+type testBase struct {
+	ID string `json:"id"`
+}
+
+type testStop struct {
+	City     string    `json:"city"`
+	ApptTime time.Time `json:"apptTime"`
+}
+
+type testItem struct {
+	Weight float64 `json:"weight"`
+	Count  uint8   `json:"count"`
+}
+
+type testBody struct {
+	testBase
+	Name   string         `json:"name"`
+	Small  int8           `json:"small"`
+	Flag   bool           `json:"flag"`
+	When   *time.Time     `json:"when"`
+	Stop   *testStop      `json:"stop"`
+	Items  []testItem     `json:"items"`
+	Labels map[string]int `json:"labels"`
+}
+
+// problems formats problems as "path: problem"
+func problems(list []models.FieldProblem) []string {
+	formatted := make([]string, len(list))
+	for i, problem := range list {
+		formatted[i] = problem.Path + ": " + problem.Problem
+	}
+	return formatted
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		unknown  []string
+		mistyped []string
+		want     *testBody // Decoded body, if not mistyped
+	}{
+		{
+			name: "valid",
+			body: `{"id":"L-1","name":"Acme","small":-128,"flag":true,"when":"2025-01-27T13:00:00Z",
+				"stop":{"city":"Newark","apptTime":"2025-01-28T14:00:00Z"},"items":[{"weight":1.5,"count":255}],"labels":{"a":1}}`,
+			want: &testBody{
+				testBase: testBase{ID: "L-1"},
+				Name:     "Acme",
+				Small:    -128,
+				Flag:     true,
+				When:     timePtr(time.Date(2025, 1, 27, 13, 0, 0, 0, time.UTC)),
+				Stop:     &testStop{City: "Newark", ApptTime: time.Date(2025, 1, 28, 14, 0, 0, 0, time.UTC)},
+				Items:    []testItem{{Weight: 1.5, Count: 255}},
+				Labels:   map[string]int{"a": 1},
+			},
+		},
+		{
+			name: "null fields left unset",
+			body: `{"name":null,"stop":null,"items":[null]}`,
+			want: &testBody{Items: []testItem{{}}},
+		},
+		{
+			name:    "unknown fields by nested path",
+			body:    `{"name":"Acme","stop":{"appTime":"2025-01-28T14:00:00Z"},"items":[{"wieght":1}],"extra":1}`,
+			unknown: []string{"extra: unknown field", "items[0].wieght: unknown field", "stop.appTime: unknown field"},
+			want:    &testBody{Name: "Acme", Stop: &testStop{}, Items: []testItem{{}}},
+		},
+		{
+			name:     "mistyped nested fields",
+			body:     `{"stop":{"city":5},"flag":"yes","name":["a"],"stop2":{}}`,
+			unknown:  []string{"stop2: unknown field"},
+			mistyped: []string{"flag: must be true or false", "name: must be a string", "stop.city: must be a string"},
+		},
+		{
+			name:     "array indexes",
+			body:     `{"items":[{"weight":1},{"weight":"heavy"},{"weight":2,"count":1.5}]}`,
+			mistyped: []string{"items[1].weight: must be a number", "items[2].count: must be a non-negative integer, got 1.5"},
+		},
+		{
+			name:     "not an array or object",
+			body:     `{"items":{"weight":1},"stop":"Newark"}`,
+			mistyped: []string{"items: must be an array", "stop: must be an object"},
+		},
+		{
+			name:     "int overflow",
+			body:     `{"small":128}`,
+			mistyped: []string{"small: must be an integer, got 128"},
+		},
+		{
+			name:     "int beyond int64",
+			body:     `{"small":99999999999999999999}`,
+			mistyped: []string{"small: must be an integer, got 99999999999999999999"},
+		},
+		{
+			name:     "negative uint",
+			body:     `{"items":[{"count":-1}]}`,
+			mistyped: []string{"items[0].count: must be a non-negative integer, got -1"},
+		},
+		{
+			name:     "uint overflow",
+			body:     `{"items":[{"count":256}]}`,
+			mistyped: []string{"items[0].count: must be a non-negative integer, got 256"},
+		},
+		{
+			name: "bad RFC 3339 times",
+			body: `{"when":"2025-01-27","stop":{"apptTime":1737964800}}`,
+			mistyped: []string{
+				"stop.apptTime: must be an RFC 3339 date-time string",
+				`when: must be an RFC 3339 date-time such as 2025-01-27T08:00:00Z, got "2025-01-27"`,
+			},
+		},
+		{
+			name: "case-insensitive keys",
+			body: `{"NAME":"Acme","Stop":{"CITY":"Newark"},"ID":"L-1"}`,
+			want: &testBody{testBase: testBase{ID: "L-1"}, Name: "Acme", Stop: &testStop{City: "Newark"}},
+		},
+		{
+			name:     "map values in key order",
+			body:     `{"labels":{"zeta":"z","alpha":"a","mid":1,"beta":true}}`,
+			mistyped: []string{"labels.alpha: must be an integer", "labels.beta: must be an integer", "labels.zeta: must be an integer"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Maps are walked in random order, so stable problems are checked repeatedly
+			for range 10 {
+				var body testBody
+				result, err := JSON([]byte(test.body), &body)
+				if err != nil {
+					t.Fatalf("JSON: %v", err)
+				}
+				if got := problems(result.Unknown); !reflect.DeepEqual(got, nonNil(test.unknown)) {
+					t.Fatalf("unknown = %q, want %q", got, test.unknown)
+				}
+				if got := problems(result.Mistyped); !reflect.DeepEqual(got, nonNil(test.mistyped)) {
+					t.Fatalf("mistyped = %q, want %q", got, test.mistyped)
+				}
+				if test.want != nil && !reflect.DeepEqual(&body, test.want) {
+					t.Fatalf("decoded %+v, want %+v", body, *test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "empty body", body: "", want: "request body is empty"},
+		{name: "whitespace only", body: " \n", want: "request body is empty"},
+		{name: "trailing object", body: `{"name":"a"} {"name":"b"}`, want: "request body must contain a single JSON object"},
+		{name: "trailing garbage", body: `{"name":"a"}x`, want: "request body must contain a single JSON object"},
+		{name: "array", body: `[{"name":"a"}]`, want: "request body must be a JSON object"},
+		{name: "truncated", body: `{"name":`, want: "invalid JSON: unexpected end of body"},
+		{name: "invalid JSON", body: `{"name" "a"}`, want: "invalid JSON at offset"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body testBody
+			_, err := JSON([]byte(test.body), &body)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("error = %v, want a SyntaxError", err)
+			}
+			if !strings.HasPrefix(syntaxErr.Message, test.want) {
+				t.Errorf("message = %q, want %q", syntaxErr.Message, test.want)
+			}
+		})
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	tests := []struct {
+		path  string
+		want  string
+		found bool
+	}{
+		{path: "stop.apptTime", want: "stop.apptTime", found: true},
+		{path: "STOP.appttime", want: "stop.apptTime", found: true},
+		{path: "id", want: "id", found: true},
+		{path: "stop.appTime"},
+		{path: "stop.apptTime.hour"},
+		{path: "name.first"},
+	}
+	for _, test := range tests {
+		got, found := ResolvePath(testBody{}, test.path)
+		if got != test.want || found != test.found {
+			t.Errorf("ResolvePath(%q) = %q, %t, want %q, %t", test.path, got, found, test.want, test.found)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// nonNil returns an empty slice for nil, to compare with the formatted problems
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,44 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/service/health"
+)
+
+type Handler struct {
+	service *health.Service
+}
+
+func NewHandler(service *health.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the readiness route with the chi router
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/ready", h.Ready)
+}
+
+// Ready handles GET /ready - reports whether the tenants can reach Turvo. It answers 503 when
+// no tenant can, and 200 otherwise (see the status field for degraded tenants).
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	response := h.service.Readiness(r.Context())
+
+	status := http.StatusOK
+	if response.Status == models.ReadinessNotReady {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,135 @@
+package load
+
+import (
+	"encoding/csv"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// csvColumn is a leaf field of the load format, addressed by its JSON path
+type csvColumn struct {
+	path  string // e.g. "pickup.city"
+	index []int  // Field index path in models.Load
+}
+
+// loadColumns are every leaf field of the load format, in struct order
+var loadColumns = leafColumns(reflect.TypeOf(models.Load{}), "", nil)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func leafColumns(t reflect.Type, prefix string, index []int) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		path := prefix + name
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct && fieldType != timeType {
+			columns = append(columns, leafColumns(fieldType, path+".", fieldIndex)...)
+			continue
+		}
+		columns = append(columns, csvColumn{path: path, index: fieldIndex})
+	}
+	return columns
+}
+
+// csvColumns returns the columns of fields, which are canonical JSON paths; a section such as
+// "pickup" stands for all of its fields. Without fields, every column is returned.
+func csvColumns(fields []string) []csvColumn {
+	if len(fields) == 0 {
+		return loadColumns
+	}
+	var columns []csvColumn
+	for _, field := range fields {
+		for _, column := range loadColumns {
+			if column.path == field || strings.HasPrefix(column.path, field+".") {
+				columns = append(columns, column)
+			}
+		}
+	}
+	return columns
+}
+
+// csvEncoder writes loads as CSV rows under a header row of column paths
+type csvEncoder struct {
+	writer   *csv.Writer
+	columns  []csvColumn
+	location *time.Location // Time columns are rendered in this location
+	row      []string
+}
+
+func newCSVEncoder(w io.Writer, columns []csvColumn, location *time.Location) (*csvEncoder, error) {
+	e := &csvEncoder{
+		writer:   csv.NewWriter(w),
+		columns:  columns,
+		location: location,
+		row:      make([]string, len(columns)),
+	}
+	for i, column := range columns {
+		e.row[i] = column.path
+	}
+	return e, e.writer.Write(e.row)
+}
+
+// Encode writes the row of a load. Fields without a value are empty cells.
+func (e *csvEncoder) Encode(load models.Load) error {
+	value := reflect.ValueOf(load)
+	for i, column := range e.columns {
+		e.row[i] = ""
+		if field, err := value.FieldByIndexErr(column.index); err == nil {
+			e.row[i] = e.cell(field)
+		}
+	}
+	return e.writer.Write(e.row)
+}
+
+// Flush writes buffered rows to the underlying writer
+func (e *csvEncoder) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvEncoder) cell(value reflect.Value) string {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	if t, ok := value.Interface().(time.Time); ok {
+		return t.In(e.location).Format(time.RFC3339)
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return spreadsheetText(value.String())
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	}
+	return ""
+}
+
+// spreadsheetText prefixes text a spreadsheet would run as a formula with an apostrophe, so
+// a value such as "=HYPERLINK(...)" from Turvo is shown as text
+func spreadsheetText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
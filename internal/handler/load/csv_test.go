@@ -0,0 +1,197 @@
+package load
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// exportCSV requests a CSV export with the query and returns its rows, header row first
+func exportCSV(t *testing.T, handler http.Handler, query string) [][]string {
+	t.Helper()
+	recorder := serve(handler, http.MethodGet, "/loads/export?format=csv&"+query, "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+	checkCSVHeaders(t, recorder)
+	rows, err := csv.NewReader(recorder.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV %q: %v", recorder.Body, err)
+	}
+	return rows
+}
+
+func checkCSVHeaders(t *testing.T, recorder *httptest.ResponseRecorder) {
+	t.Helper()
+	if got := recorder.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/csv", got)
+	}
+	if got := recorder.Header().Get("Content-Disposition"); got != `attachment; filename="loads.csv"` {
+		t.Errorf("Content-Disposition = %q, want a loads.csv attachment", got)
+	}
+}
+
+// postMinimalLoad creates the minimal test load with the given replacements applied to its
+// JSON and returns its ID
+func postMinimalLoad(t *testing.T, handler http.Handler, replacements ...string) string {
+	t.Helper()
+	data, err := os.ReadFile("../../service/load/testdata/fidelity/minimal.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := serve(handler, http.MethodPost, "/loads", strings.NewReplacer(replacements...).Replace(string(data)))
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("POST /loads = %d: %s", recorder.Code, recorder.Body)
+	}
+	var response models.LoadCreateResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.ID
+}
+
+// column returns the cell of row under the header name
+func column(t *testing.T, rows [][]string, row int, name string) string {
+	t.Helper()
+	for i, header := range rows[0] {
+		if header == name {
+			return rows[row][i]
+		}
+	}
+	t.Fatalf("no column %s in %v", name, rows[0])
+	return ""
+}
+
+func TestExportCSVColumns(t *testing.T) {
+	handler, _ := newTestRouter(t, Config{})
+	id := postMinimalLoad(t, handler)
+
+	t.Run("sections flattened in the given order", func(t *testing.T) {
+		rows := exportCSV(t, handler, "fields=externalTMSLoadID,pickup,totalWeight")
+		want := "externalTMSLoadID," +
+			"pickup.externalTMSId,pickup.name,pickup.addressLine1,pickup.addressLine2,pickup.city,pickup.state," +
+			"pickup.zipcode,pickup.country,pickup.contact,pickup.phone,pickup.email,pickup.businessHours," +
+			"pickup.refNumber,pickup.readyTime,pickup.apptTime,pickup.apptNote,pickup.timezone,pickup.warehouseId," +
+			"totalWeight"
+		if got := strings.Join(rows[0], ","); got != want {
+			t.Errorf("header = %s\nwant %s", got, want)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("%d rows, want a header and one load", len(rows))
+		}
+		for name, want := range map[string]string{
+			"externalTMSLoadID": id,
+			"pickup.city":       "Newark",
+			"pickup.apptTime":   "",
+			"totalWeight":       "15000.5",
+		} {
+			if got := column(t, rows, 1, name); got != want {
+				t.Errorf("%s = %q, want %q", name, got, want)
+			}
+		}
+	})
+
+	t.Run("every column without fields", func(t *testing.T) {
+		rows := exportCSV(t, handler, "")
+		if len(rows[0]) != len(loadColumns) {
+			t.Errorf("%d columns, want %d", len(rows[0]), len(loadColumns))
+		}
+		for _, name := range []string{"customer.name", "consignee.apptTime", "carrier.name", "rateData.customerLhRateUsd", "specifications.hazmat"} {
+			column(t, rows, 0, name)
+		}
+		if got := column(t, rows, 1, "status"); got != "tendered" {
+			t.Errorf("status = %q, want tendered", got)
+		}
+	})
+}
+
+func TestExportCSVSpreadsheetText(t *testing.T) {
+	handler, _ := newTestRouter(t, Config{})
+	postMinimalLoad(t, handler,
+		`"Acme Corporation"`, `"=HYPERLINK(\"http://evil.example\"), \"Dock\" 4"`,
+		`"ABC Transport Inc."`, `"-ABC Transport,\nInc."`,
+	)
+
+	// The values come back intact through the CSV quoting, with formulas defused
+	rows := exportCSV(t, handler, "fields=customer.name,carrier.name,poNums")
+	for name, want := range map[string]string{
+		"customer.name": `'=HYPERLINK("http://evil.example"), "Dock" 4`,
+		"carrier.name":  "'-ABC Transport,\nInc.",
+		"poNums":        "PO-001, PO-002, PO-003",
+	} {
+		if got := column(t, rows, 1, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSpreadsheetText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "", want: ""},
+		{text: "Acme", want: "Acme"},
+		{text: "=1+1", want: "'=1+1"},
+		{text: "+15551234567", want: "'+15551234567"},
+		{text: "-5", want: "'-5"},
+		{text: "@SUM(A1)", want: "'@SUM(A1)"},
+		{text: "\tindented", want: "'\tindented"},
+		{text: "\rreturn", want: "'\rreturn"},
+		{text: "a=b", want: "a=b"},
+	}
+	for _, test := range tests {
+		if got := spreadsheetText(test.text); got != test.want {
+			t.Errorf("spreadsheetText(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestExportCSVTimezone(t *testing.T) {
+	handler, _ := newTestRouter(t, Config{})
+	postMinimalLoad(t, handler)
+
+	tests := []struct {
+		timezone  string
+		readyTime string
+		apptTime  string
+	}{
+		{timezone: "", readyTime: "2025-01-27T08:00:00Z", apptTime: "2025-01-28T14:00:00Z"},
+		{timezone: "America/Chicago", readyTime: "2025-01-27T02:00:00-06:00", apptTime: "2025-01-28T08:00:00-06:00"},
+		{timezone: "Asia/Kolkata", readyTime: "2025-01-27T13:30:00+05:30", apptTime: "2025-01-28T19:30:00+05:30"},
+	}
+	for _, test := range tests {
+		name, query := "UTC without timezone", "fields=pickup.readyTime,consignee.apptTime"
+		if test.timezone != "" {
+			name, query = test.timezone, query+"&timezone="+test.timezone
+		}
+		t.Run(name, func(t *testing.T) {
+			rows := exportCSV(t, handler, query)
+			if got := column(t, rows, 1, "pickup.readyTime"); got != test.readyTime {
+				t.Errorf("pickup.readyTime = %q, want %q", got, test.readyTime)
+			}
+			if got := column(t, rows, 1, "consignee.apptTime"); got != test.apptTime {
+				t.Errorf("consignee.apptTime = %q, want %q", got, test.apptTime)
+			}
+		})
+	}
+}
+
+func TestExportCSVEmpty(t *testing.T) {
+	handler, _ := newTestRouter(t, Config{})
+
+	recorder := serve(handler, http.MethodGet, "/loads/export?format=csv&fields=externalTMSLoadID,status", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+	checkCSVHeaders(t, recorder)
+	if got := recorder.Body.String(); got != "externalTMSLoadID,status\n" {
+		t.Errorf("body = %q, want only the header row", got)
+	}
+}
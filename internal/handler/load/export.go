@@ -0,0 +1,134 @@
+package load
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// exportEncoder writes exported loads in one format
+type exportEncoder interface {
+	Encode(load models.Load) error
+	Flush() error // Writes buffered output to the response
+}
+
+// ndjsonEncoder writes one JSON load per line, trimmed to fields if any
+type ndjsonEncoder struct {
+	encoder *json.Encoder
+	fields  []string
+}
+
+func (e *ndjsonEncoder) Encode(load models.Load) error {
+	if len(e.fields) == 0 {
+		return e.encoder.Encode(load)
+	}
+	selected, err := loadFields(load, e.fields)
+	if err != nil {
+		return err
+	}
+	return e.encoder.Encode(selected)
+}
+
+func (e *ndjsonEncoder) Flush() error {
+	return nil
+}
+
+// ExportLoads handles GET /loads/export - streams every load matching the filters as
+// newline-delimited JSON or CSV, flushing each Turvo page as it arrives
+func (h *Handler) ExportLoads(w http.ResponseWriter, r *http.Request) {
+	service, ok := loadService(w, r)
+	if !ok {
+		return
+	}
+
+	filters, problems := parseFilters(r, service, exportParams)
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "ndjson" && format != "csv" {
+		problems = append(problems, models.FieldProblem{Path: "format", Problem: fmt.Sprintf("must be ndjson or csv, got %q", format)})
+		sort.SliceStable(problems, func(i, j int) bool {
+			return paramIndex(exportParams, problems[i].Path) < paramIndex(exportParams, problems[j].Path)
+		})
+	}
+	if len(problems) > 0 {
+		writeRequestError(w, http.StatusBadRequest, "invalid query parameters", problems)
+		return
+	}
+	filters.BypassCache = noCache(r)
+
+	// Time columns are rendered in the filter timezone, which parseFilters has validated
+	location := time.UTC
+	if name := r.URL.Query().Get("timezone"); name != "" {
+		location, _ = time.LoadLocation(name)
+	}
+
+	// Each page gets a fresh write deadline, since the whole export can take far longer than
+	// the server's write timeout
+	controller := http.NewResponseController(w)
+	extendDeadline := func() {
+		if h.config.WriteTimeout > 0 {
+			controller.SetWriteDeadline(time.Now().Add(h.config.WriteTimeout))
+		}
+	}
+	extendDeadline()
+
+	// The response starts with the first page, so errors before it still get a status
+	var encoder exportEncoder
+	start := func() (err error) {
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="loads.csv"`)
+			encoder, err = newCSVEncoder(w, csvColumns(filters.Fields), location)
+			return err
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder = &ndjsonEncoder{encoder: json.NewEncoder(w), fields: filters.Fields}
+		return nil
+	}
+	flush := func() error {
+		if err := encoder.Flush(); err != nil {
+			return err
+		}
+		return controller.Flush()
+	}
+
+	summary, err := service.ExportLoads(r.Context(), filters, func(loads []models.Load) error {
+		if encoder == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		extendDeadline()
+		for _, load := range loads {
+			if err := encoder.Encode(load); err != nil {
+				return err
+			}
+		}
+		return flush()
+	})
+	switch {
+	case err != nil && encoder == nil:
+		writeServiceError(w, r, err)
+		return
+	case err != nil:
+		// The status is sent already; abort the connection so the client sees a truncated
+		// export instead of a complete one
+		slog.ErrorContext(r.Context(), "load export failed after streaming started", "exported", summary.Exported, "error", err)
+		panic(http.ErrAbortHandler)
+	case encoder == nil:
+		// Nothing matched: an empty export, with the CSV header row
+		if err = start(); err == nil {
+			err = flush()
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to write empty load export", "error", err)
+		}
+	}
+	if summary.PartialFailures > 0 {
+		slog.WarnContext(r.Context(), "load export could not fetch details of some loads", "partial_failures", summary.PartialFailures)
+	}
+}
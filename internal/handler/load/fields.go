@@ -0,0 +1,77 @@
+package load
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// fieldsResponse is a LoadListResponse with only the requested fields of each load
+type fieldsResponse struct {
+	*models.LoadListResponse
+	Data []map[string]interface{} `json:"data"`
+}
+
+// selectFields returns the response with each load trimmed to fields, which are canonical
+// JSON paths such as pickup.apptTime. Fields a load has no value for are left out.
+func selectFields(response *models.LoadListResponse, fields []string) (*fieldsResponse, error) {
+	selected := &fieldsResponse{
+		LoadListResponse: response,
+		Data:             make([]map[string]interface{}, len(response.Data)),
+	}
+	for i, load := range response.Data {
+		data, err := loadFields(load, fields)
+		if err != nil {
+			return nil, err
+		}
+		selected.Data[i] = data
+	}
+	return selected, nil
+}
+
+// loadFields returns the load trimmed to fields
+func loadFields(load models.Load, fields []string) (map[string]interface{}, error) {
+	data, err := json.Marshal(load)
+	if err != nil {
+		return nil, err
+	}
+	var whole map[string]interface{}
+	if err := json.Unmarshal(data, &whole); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]interface{})
+	for _, field := range fields {
+		path := strings.Split(field, ".")
+		if value, found := lookupPath(whole, path); found {
+			setPath(selected, path, value)
+		}
+	}
+	return selected, nil
+}
+
+func lookupPath(object map[string]interface{}, path []string) (interface{}, bool) {
+	value, found := object[path[0]]
+	if !found || len(path) == 1 {
+		return value, found
+	}
+	child, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookupPath(child, path[1:])
+}
+
+func setPath(object map[string]interface{}, path []string, value interface{}) {
+	if len(path) == 1 {
+		object[path[0]] = value
+		return
+	}
+	child, ok := object[path[0]].(map[string]interface{})
+	if !ok {
+		child = make(map[string]interface{})
+		object[path[0]] = child
+	}
+	setPath(child, path[1:], value)
+}
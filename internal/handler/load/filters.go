@@ -0,0 +1,274 @@
+package load
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/decode"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/service/load"
+)
+
+// filterParams are the query parameters of GET /loads
+var filterParams = []string{
+	"status",
+	"customerId",
+	"carrierId",
+	"freightLoadID",
+	"originCity",
+	"originState",
+	"destinationCity",
+	"destinationState",
+	"poNumber",
+	"pickupDateSearchFrom",
+	"pickupDateSearchTo",
+	"deliveryDateSearchFrom",
+	"deliveryDateSearchTo",
+	"createdDateSearchFrom",
+	"createdDateSearchTo",
+	"updatedDateSearchFrom",
+	"updatedDateSearchTo",
+	"timezone",
+	"sort",
+	"fields",
+	"expand",
+	"page",
+	"limit",
+	"includeDetails",
+}
+
+// exportParams are the query parameters of GET /loads/export: the filters of GET /loads
+// without paging, and the format
+var exportParams = append(slices.DeleteFunc(slices.Clone(filterParams), func(name string) bool {
+	return name == "page" || name == "limit"
+}), "format")
+
+// parseFilters parses the query parameters among names into LoadFilters. Every invalid,
+// repeated, empty or unknown parameter is returned as a problem, so a typo never widens a
+// report to all loads.
+func parseFilters(r *http.Request, service *load.Service, names []string) (models.LoadFilters, []models.FieldProblem) {
+	filters := models.LoadFilters{
+		Page:  1,
+		Limit: 20,
+	}
+	params, problems := queryParams(r.URL.Query(), names)
+	invalid := func(param, format string, args ...interface{}) {
+		problems = append(problems, models.FieldProblem{Path: param, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	// Parse status filter: one status or several separated by commas
+	if value, ok := params["status"]; ok {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.TrimSpace(status)
+			if status == "" {
+				invalid("status", "must be statuses separated by commas, got %q", value)
+				break
+			}
+			if _, err := service.APIToTurvoStatus("status", status); err != nil {
+				invalid("status", "%q is not a supported status (see GET /api/v1/statuses)", status)
+				continue
+			}
+			filters.Statuses = append(filters.Statuses, status)
+		}
+	}
+
+	// Parse ID filters
+	filters.CustomerID = params["customerId"]
+	filters.CarrierID = params["carrierId"]
+	filters.FreightLoadID = params["freightLoadID"]
+
+	// Parse lane and reference filters (matched on our side)
+	filters.OriginCity = params["originCity"]
+	filters.OriginState = params["originState"]
+	filters.DestinationCity = params["destinationCity"]
+	filters.DestinationState = params["destinationState"]
+	filters.PONumber = params["poNumber"]
+
+	// Parse timezone (default: the offset of a date-time, otherwise UTC)
+	location, zoned := time.UTC, false
+	if name, ok := params["timezone"]; ok {
+		if loc, err := time.LoadLocation(name); err != nil || name == "Local" {
+			invalid("timezone", "must be an IANA time zone such as America/New_York, got %q", name)
+		} else {
+			location, zoned = loc, true
+		}
+	}
+
+	// Parse date ranges, from the start of the first day to the end of the last day in the
+	// caller's timezone
+	dateRange := func(fromParam, toParam string) (from, to *time.Time) {
+		if value, ok := params[fromParam]; ok {
+			if day, err := parseDay(value, location, zoned); err != nil {
+				invalid(fromParam, "must be a date (2025-01-27) or an RFC 3339 date-time, got %q", value)
+			} else {
+				from = &day
+			}
+		}
+		if value, ok := params[toParam]; ok {
+			if day, err := parseDay(value, location, zoned); err != nil {
+				invalid(toParam, "must be a date (2025-01-27) or an RFC 3339 date-time, got %q", value)
+			} else {
+				endOfDay := day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+				to = &endOfDay
+			}
+		}
+		if from != nil && to != nil && to.Before(*from) {
+			invalid(toParam, "must not be before %s", fromParam)
+		}
+		return from, to
+	}
+	filters.PickupDateFrom, filters.PickupDateTo = dateRange("pickupDateSearchFrom", "pickupDateSearchTo")
+	filters.DeliveryDateFrom, filters.DeliveryDateTo = dateRange("deliveryDateSearchFrom", "deliveryDateSearchTo")
+	filters.CreatedFrom, filters.CreatedTo = dateRange("createdDateSearchFrom", "createdDateSearchTo")
+	filters.UpdatedFrom, filters.UpdatedTo = dateRange("updatedDateSearchFrom", "updatedDateSearchTo")
+
+	// Parse sort: fields separated by commas, each descending with a "-" prefix
+	if value, ok := params["sort"]; ok {
+		seen := make(map[string]bool)
+		for _, field := range strings.Split(value, ",") {
+			key := models.LoadSort{Field: strings.TrimSpace(field)}
+			if strings.HasPrefix(key.Field, "-") {
+				key.Field, key.Descending = key.Field[1:], true
+			}
+			switch {
+			case !isSortField(key.Field):
+				invalid("sort", "%q is not a sort field, use %s with an optional - prefix", field, strings.Join(load.SortFields, ", "))
+			case seen[key.Field]:
+				invalid("sort", "%q is given more than once", key.Field)
+			default:
+				seen[key.Field] = true
+				filters.Sort = append(filters.Sort, key)
+			}
+		}
+	}
+
+	// Parse fields: JSON paths of the load format separated by commas
+	if value, ok := params["fields"]; ok {
+		for _, field := range strings.Split(value, ",") {
+			path, found := decode.ResolvePath(models.Load{}, strings.TrimSpace(field))
+			switch {
+			case !found:
+				invalid("fields", "%q is not a field of a load, use JSON paths such as pickup.apptTime", field)
+			case !slices.Contains(filters.Fields, path):
+				filters.Fields = append(filters.Fields, path)
+			}
+		}
+	}
+
+	// Parse expand: top-level sections of the load format filled from shipment details
+	if value, ok := params["expand"]; ok {
+		for _, section := range strings.Split(value, ",") {
+			name, found := decode.ResolvePath(models.Load{}, strings.TrimSpace(section))
+			switch {
+			case !found || strings.Contains(name, "."):
+				invalid("expand", "%q is not a section of a load, such as carrier or rateData", section)
+			case !slices.Contains(filters.Expand, name):
+				filters.Expand = append(filters.Expand, name)
+			}
+		}
+	}
+
+	// Parse page (default: 1, min: 1)
+	if value, ok := params["page"]; ok {
+		if page, err := strconv.Atoi(value); err != nil || page < 1 {
+			invalid("page", "must be an integer of at least 1, got %q", value)
+		} else {
+			filters.Page = page
+		}
+	}
+
+	// Parse limit (default: 20, min: 1, max: 100)
+	if value, ok := params["limit"]; ok {
+		if limit, err := strconv.Atoi(value); err != nil || limit < 1 || limit > 100 {
+			invalid("limit", "must be an integer from 1 to 100, got %q", value)
+		} else {
+			filters.Limit = limit
+		}
+	}
+
+	// Parse includeDetails flag
+	if value, ok := params["includeDetails"]; ok {
+		if includeDetails, err := strconv.ParseBool(value); err != nil {
+			invalid("includeDetails", "must be true, false, 1 or 0, got %q", value)
+		} else {
+			filters.IncludeDetails = includeDetails
+		}
+	}
+
+	// List problems in parameter order, unknown parameters last
+	sort.SliceStable(problems, func(i, j int) bool {
+		return paramIndex(names, problems[i].Path) < paramIndex(names, problems[j].Path)
+	})
+	return filters, problems
+}
+
+// queryParams returns the single value of each parameter among names, and problems for
+// unknown, repeated and empty parameters
+func queryParams(query url.Values, names []string) (map[string]string, []models.FieldProblem) {
+	params := make(map[string]string)
+	var problems []models.FieldProblem
+	for _, name := range names {
+		values, ok := query[name]
+		switch {
+		case !ok:
+		case len(values) > 1:
+			problems = append(problems, models.FieldProblem{Path: name, Problem: "must be given once"})
+		case values[0] == "":
+			problems = append(problems, models.FieldProblem{Path: name, Problem: "must not be empty"})
+		default:
+			params[name] = values[0]
+		}
+	}
+
+	var unknown []string
+	for name := range query {
+		if !slices.Contains(names, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, models.FieldProblem{Path: name, Problem: "unknown query parameter"})
+	}
+	return params, problems
+}
+
+func isSortField(name string) bool {
+	for _, field := range load.SortFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// paramIndex returns the position of a parameter in names, or len(names) if it is unknown
+func paramIndex(names []string, name string) int {
+	if i := slices.Index(names, name); i >= 0 {
+		return i
+	}
+	return len(names)
+}
+
+// parseDay returns the start of the day a filter value falls on. Date-only values are days in
+// location. Date-times are converted to location if the caller gave a timezone, and otherwise
+// keep their own offset.
+func parseDay(value string, location *time.Location, zoned bool) (time.Time, error) {
+	if day, err := time.ParseInLocation(time.DateOnly, value, location); err == nil {
+		return day, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if zoned {
+		t = t.In(location)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+}
@@ -0,0 +1,227 @@
+package load
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/service/load"
+)
+
+func problemList(problems []models.FieldProblem) string {
+	list := make([]string, len(problems))
+	for i, problem := range problems {
+		list[i] = problem.Path + ": " + problem.Problem
+	}
+	return strings.Join(list, "; ")
+}
+
+func TestParseFiltersProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		names    []string // Default: filterParams
+		problems []string // "path: problem" prefixes, in order
+	}{
+		{name: "no parameters", query: ""},
+		{name: "unknown parameter", query: "pikupDateSearchFrom=2025-01-27", problems: []string{"pikupDateSearchFrom: unknown query parameter"}},
+		{name: "parameter names are case sensitive", query: "Status=covered", problems: []string{"Status: unknown query parameter"}},
+		{name: "repeated parameter", query: "status=covered&status=tendered", problems: []string{"status: must be given once"}},
+		{name: "empty parameter", query: "customerId=", problems: []string{"customerId: must not be empty"}},
+		{name: "unknown status", query: "status=covered,teleported", problems: []string{`status: "teleported" is not a supported status`}},
+		{name: "empty status in list", query: "status=covered,", problems: []string{"status: must be statuses separated by commas"}},
+		{name: "invalid calendar date", query: "pickupDateSearchFrom=2025-02-30", problems: []string{"pickupDateSearchFrom: must be a date"}},
+		{name: "date in another layout", query: "pickupDateSearchTo=01/27/2025", problems: []string{"pickupDateSearchTo: must be a date"}},
+		{name: "date-time without offset", query: "createdDateSearchFrom=2025-01-27T08:00:00", problems: []string{"createdDateSearchFrom: must be a date"}},
+		{name: "date-time with space", query: "createdDateSearchFrom=2025-01-27+08:00:00Z", problems: []string{"createdDateSearchFrom: must be a date"}},
+		{name: "invalid hour", query: "updatedDateSearchTo=2025-01-27T25:00:00Z", problems: []string{"updatedDateSearchTo: must be a date"}},
+		{name: "range ends before it starts", query: "deliveryDateSearchFrom=2025-01-28&deliveryDateSearchTo=2025-01-27", problems: []string{"deliveryDateSearchTo: must not be before deliveryDateSearchFrom"}},
+		{name: "range within one day", query: "deliveryDateSearchFrom=2025-01-27T18:00:00Z&deliveryDateSearchTo=2025-01-27T06:00:00Z"},
+		{name: "unknown timezone", query: "timezone=Mars/Olympus", problems: []string{"timezone: must be an IANA time zone"}},
+		{name: "Local timezone", query: "timezone=Local", problems: []string{"timezone: must be an IANA time zone"}},
+		{name: "page and limit out of range", query: "page=0&limit=101", problems: []string{"page: must be an integer of at least 1", "limit: must be an integer from 1 to 100"}},
+		{name: "includeDetails not a bool", query: "includeDetails=yes", problems: []string{"includeDetails: must be true, false, 1 or 0"}},
+		{name: "sort field unknown and repeated", query: "sort=-pickupDate,weight,pickupDate", problems: []string{`sort: "weight" is not a sort field`, `sort: "pickupDate" is given more than once`}},
+		{name: "unknown field", query: "fields=pickup.city,pickup.cty", problems: []string{`fields: "pickup.cty" is not a field of a load`}},
+		{name: "expand with a nested path", query: "expand=pickup.city", problems: []string{`expand: "pickup.city" is not a section of a load`}},
+		{
+			name:     "problems in parameter order, unknown last",
+			query:    "zeta=1&limit=0&alpha=1&status=covered&status=covered&pickupDateSearchFrom=x",
+			problems: []string{"status: must be given once", "pickupDateSearchFrom: must be a date", "limit: must be an integer", "alpha: unknown query parameter", "zeta: unknown query parameter"},
+		},
+		{name: "paging is not an export parameter", query: "page=2&format=csv", names: exportParams, problems: []string{"page: unknown query parameter"}},
+		{name: "format is not a list parameter", query: "format=csv", problems: []string{"format: unknown query parameter"}},
+	}
+
+	service := load.NewService(nil, load.Config{})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := test.names
+			if names == nil {
+				names = filterParams
+			}
+			request := httptest.NewRequest(http.MethodGet, "/loads?"+test.query, nil)
+			_, problems := parseFilters(request, service, names)
+
+			if len(problems) != len(test.problems) {
+				t.Fatalf("problems = %s, want %d", problemList(problems), len(test.problems))
+			}
+			for i, want := range test.problems {
+				if got := problems[i].Path + ": " + problems[i].Problem; !strings.HasPrefix(got, want) {
+					t.Errorf("problem %d = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFiltersValues(t *testing.T) {
+	service := load.NewService(nil, load.Config{})
+	request := httptest.NewRequest(http.MethodGet, "/loads?status=covered,%20tendered&originCity=Newark&sort=-pickupDate,created"+
+		"&fields=pickup.city,pickup.city,customer&expand=carrier&page=3&limit=50&includeDetails=1", nil)
+
+	filters, problems := parseFilters(request, service, filterParams)
+	if len(problems) > 0 {
+		t.Fatalf("problems = %s", problemList(problems))
+	}
+	if strings.Join(filters.Statuses, ",") != "covered,tendered" {
+		t.Errorf("statuses = %v", filters.Statuses)
+	}
+	if filters.OriginCity != "Newark" || filters.Page != 3 || filters.Limit != 50 || !filters.IncludeDetails {
+		t.Errorf("filters = %+v", filters)
+	}
+	wantSort := []models.LoadSort{{Field: "pickupDate", Descending: true}, {Field: "created"}}
+	if len(filters.Sort) != 2 || filters.Sort[0] != wantSort[0] || filters.Sort[1] != wantSort[1] {
+		t.Errorf("sort = %+v, want %+v", filters.Sort, wantSort)
+	}
+	if strings.Join(filters.Fields, ",") != "pickup.city,customer" {
+		t.Errorf("fields = %v, want duplicates dropped", filters.Fields)
+	}
+	if strings.Join(filters.Expand, ",") != "carrier" {
+		t.Errorf("expand = %v", filters.Expand)
+	}
+}
+
+func TestParseFiltersDateRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		from, to string // RFC 3339 with nanoseconds
+		hours    float64
+	}{
+		{
+			name:  "date-only values are UTC days by default",
+			query: "pickupDateSearchFrom=2025-01-27&pickupDateSearchTo=2025-01-27",
+			from:  "2025-01-27T00:00:00Z", to: "2025-01-27T23:59:59.999999999Z", hours: 24,
+		},
+		{
+			name:  "date-only values are days in the timezone",
+			query: "pickupDateSearchFrom=2025-01-27&pickupDateSearchTo=2025-01-28&timezone=America/Chicago",
+			from:  "2025-01-27T00:00:00-06:00", to: "2025-01-28T23:59:59.999999999-06:00", hours: 48,
+		},
+		{
+			name:  "day clocks spring forward",
+			query: "pickupDateSearchFrom=2025-03-09&pickupDateSearchTo=2025-03-09&timezone=America/New_York",
+			from:  "2025-03-09T00:00:00-05:00", to: "2025-03-09T23:59:59.999999999-04:00", hours: 23,
+		},
+		{
+			name:  "day clocks fall back",
+			query: "pickupDateSearchFrom=2025-11-02&pickupDateSearchTo=2025-11-02&timezone=America/New_York",
+			from:  "2025-11-02T00:00:00-04:00", to: "2025-11-02T23:59:59.999999999-05:00", hours: 25,
+		},
+		{
+			name:  "range across the spring change",
+			query: "pickupDateSearchFrom=2025-03-08&pickupDateSearchTo=2025-03-10&timezone=America/New_York",
+			from:  "2025-03-08T00:00:00-05:00", to: "2025-03-10T23:59:59.999999999-04:00", hours: 71,
+		},
+		{
+			name:  "date-time converted to the timezone",
+			query: "pickupDateSearchFrom=2025-03-10T03:30:00Z&pickupDateSearchTo=2025-03-10T03:30:00Z&timezone=America/New_York",
+			from:  "2025-03-09T00:00:00-05:00", to: "2025-03-09T23:59:59.999999999-04:00", hours: 23,
+		},
+		{
+			name:  "date-time keeps its offset without a timezone",
+			query: "pickupDateSearchFrom=2025-03-09T23:30:00-08:00&pickupDateSearchTo=2025-03-09T23:30:00-08:00",
+			from:  "2025-03-09T00:00:00-08:00", to: "2025-03-09T23:59:59.999999999-08:00", hours: 24,
+		},
+		{
+			name:  "date-time on the spring change",
+			query: "pickupDateSearchFrom=2025-03-09T02:30:00-05:00&pickupDateSearchTo=2025-03-09T02:30:00-05:00&timezone=America/New_York",
+			from:  "2025-03-09T00:00:00-05:00", to: "2025-03-09T23:59:59.999999999-04:00", hours: 23,
+		},
+	}
+
+	service := load.NewService(nil, load.Config{})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/loads?"+strings.ReplaceAll(test.query, "+", "%2B"), nil)
+			filters, problems := parseFilters(request, service, filterParams)
+			if len(problems) > 0 {
+				t.Fatalf("problems = %s", problemList(problems))
+			}
+			from, to := filters.PickupDateFrom, filters.PickupDateTo
+			if from == nil || to == nil {
+				t.Fatalf("range = %v to %v", from, to)
+			}
+			if got := from.Format(time.RFC3339Nano); got != test.from {
+				t.Errorf("from = %s, want %s", got, test.from)
+			}
+			if got := to.Format(time.RFC3339Nano); got != test.to {
+				t.Errorf("to = %s, want %s", got, test.to)
+			}
+			if got := to.Add(time.Nanosecond).Sub(*from).Hours(); got != test.hours {
+				t.Errorf("range covers %v hours, want %v", got, test.hours)
+			}
+		})
+	}
+}
+
+func TestParseDay(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		value    string
+		location *time.Location
+		zoned    bool
+		want     string // Empty if the value is invalid
+	}{
+		{name: "date in UTC", value: "2025-01-27", location: time.UTC, want: "2025-01-27T00:00:00Z"},
+		{name: "date in a timezone", value: "2025-03-09", location: newYork, zoned: true, want: "2025-03-09T00:00:00-05:00"},
+		{name: "date after spring forward", value: "2025-03-10", location: newYork, zoned: true, want: "2025-03-10T00:00:00-04:00"},
+		{name: "date-time in UTC", value: "2025-01-27T23:59:59Z", location: time.UTC, want: "2025-01-27T00:00:00Z"},
+		{name: "date-time converted", value: "2025-01-28T04:59:59Z", location: newYork, zoned: true, want: "2025-01-27T00:00:00-05:00"},
+		{name: "date-time keeps offset", value: "2025-01-28T04:59:59+05:30", location: time.UTC, want: "2025-01-28T00:00:00+05:30"},
+		{name: "date-time with fraction", value: "2025-01-27T08:00:00.5Z", location: time.UTC, want: "2025-01-27T00:00:00Z"},
+		{name: "leap day", value: "2024-02-29", location: time.UTC, want: "2024-02-29T00:00:00Z"},
+		{name: "not a leap year", value: "2025-02-29", location: time.UTC},
+		{name: "month 13", value: "2025-13-01", location: time.UTC},
+		{name: "single digit month", value: "2025-1-27", location: time.UTC},
+		{name: "date-time without offset", value: "2025-01-27T08:00:00", location: time.UTC},
+		{name: "date-time with space", value: "2025-01-27 08:00:00Z", location: time.UTC},
+		{name: "Unix time", value: "1737964800", location: time.UTC},
+		{name: "empty", value: "", location: time.UTC},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			day, err := parseDay(test.value, test.location, test.zoned)
+			if test.want == "" {
+				if err == nil {
+					t.Errorf("parseDay(%q) = %s, want an error", test.value, day.Format(time.RFC3339))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDay(%q): %v", test.value, err)
+			}
+			if got := day.Format(time.RFC3339); got != test.want {
+				t.Errorf("parseDay(%q) = %s, want %s", test.value, got, test.want)
+			}
+		})
+	}
+}
@@ -2,132 +2,202 @@ package load
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/lwlach/turvo-integration-backend/internal/auth"
+	"github.com/lwlach/turvo-integration-backend/internal/decode"
+	"github.com/lwlach/turvo-integration-backend/internal/logging"
 	"github.com/lwlach/turvo-integration-backend/internal/models"
 	"github.com/lwlach/turvo-integration-backend/internal/service/load"
+	"github.com/lwlach/turvo-integration-backend/internal/tenant"
 )
 
+// Config holds options of the load routes
+type Config struct {
+	MaxBodyBytes        int64         // Larger request bodies are answered with 413
+	RejectUnknownFields bool          // Answer 400 for unknown fields instead of listing them as warnings
+	WriteTimeout        time.Duration // Server write timeout, renewed for each page of an export
+}
+
+// Handler serves the load routes of the tenant selected by tenant.Middleware
 type Handler struct {
-	service *load.Service
+	config Config
 }
 
-func NewHandler(service *load.Service) *Handler {
+func NewHandler(config Config) *Handler {
 	return &Handler{
-		service: service,
+		config: config,
 	}
 }
 
-// RegisterRoutes registers the load routes with the chi router
+// RegisterRoutes registers the load routes with the chi router, each requiring its scope
 func (h *Handler) RegisterRoutes(r chi.Router) {
-	r.Get("/loads", h.GetLoads)
-	r.Post("/loads", h.CreateLoad)
+	r.With(auth.Require(auth.ScopeLoadsRead)).Get("/loads", h.GetLoads)
+	r.With(auth.Require(auth.ScopeLoadsRead)).Get("/loads/export", h.ExportLoads)
+	r.With(auth.Require(auth.ScopeLoadsWrite)).Post("/loads", h.CreateLoad)
+	r.With(auth.Require(auth.ScopeLoadsRead)).Get("/statuses", h.GetStatuses)
 }
 
 // GetLoads handles GET /loads - returns filtered and paginated loads from Turvo
 func (h *Handler) GetLoads(w http.ResponseWriter, r *http.Request) {
-	filters := parseFilters(r)
+	service, ok := loadService(w, r)
+	if !ok {
+		return
+	}
+
+	filters, problems := parseFilters(r, service, filterParams)
+	if len(problems) > 0 {
+		writeRequestError(w, http.StatusBadRequest, "invalid query parameters", problems)
+		return
+	}
+	filters.BypassCache = noCache(r)
 
-	response, err := h.service.GetLoads(filters)
+	response, err := service.GetLoads(r.Context(), filters)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, r, err)
 		return
 	}
 
+	var body interface{} = response
+	if len(filters.Fields) > 0 {
+		if body, err = selectFields(response, filters.Fields); err != nil {
+			http.Error(w, "failed to select fields: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := json.NewEncoder(w).Encode(body); err != nil {
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
 		return
 	}
 }
 
-// parseFilters parses query parameters into LoadFilters
-func parseFilters(r *http.Request) models.LoadFilters {
-	filters := models.LoadFilters{
-		Page:  1,
-		Limit: 20,
+// noCache reports whether the caller asked to bypass cached data (Cache-Control: no-cache or Pragma: no-cache)
+func noCache(r *http.Request) bool {
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive == "no-cache" || directive == "no-store" {
+			return true
+		}
 	}
+	return strings.EqualFold(r.Header.Get("Pragma"), "no-cache")
+}
 
-	// Parse status filter
-	if status := r.URL.Query().Get("status"); status != "" {
-		filters.Status = status
+// CreateLoad handles POST /loads - creates a new load in Turvo
+func (h *Handler) CreateLoad(w http.ResponseWriter, r *http.Request) {
+	service, ok := loadService(w, r)
+	if !ok {
+		return
 	}
 
-	// Parse customerId filter
-	if customerID := r.URL.Query().Get("customerId"); customerID != "" {
-		filters.CustomerID = customerID
+	var load models.Load
+	warnings, ok := h.decodeBody(w, r, &load)
+	if !ok {
+		return
 	}
 
-	// Parse pickupDateSearchFrom (start of day in UTC)
-	if dateFromStr := r.URL.Query().Get("pickupDateSearchFrom"); dateFromStr != "" {
-		if dateFrom, err := time.Parse(time.RFC3339, dateFromStr); err == nil {
-			// Set to start of day in UTC
-			startOfDay := time.Date(dateFrom.Year(), dateFrom.Month(), dateFrom.Day(), 0, 0, 0, 0, time.UTC)
-			filters.PickupDateFrom = &startOfDay
-		}
+	response, err := service.CreateLoad(r.Context(), &load)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
 	}
+	response.Warnings = warnings
 
-	// Parse pickupDateSearchTo (end of day in UTC)
-	if dateToStr := r.URL.Query().Get("pickupDateSearchTo"); dateToStr != "" {
-		if dateTo, err := time.Parse(time.RFC3339, dateToStr); err == nil {
-			// Set to end of day in UTC
-			endOfDay := time.Date(dateTo.Year(), dateTo.Month(), dateTo.Day(), 23, 59, 59, 999999999, time.UTC)
-			filters.PickupDateTo = &endOfDay
-		}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
 	}
+}
 
-	// Parse page (default: 1, min: 1)
-	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
-		if page, err := strconv.Atoi(pageStr); err == nil && page >= 1 {
-			filters.Page = page
+// decodeBody decodes the request body into v. Mistyped fields, and unknown fields if they
+// are rejected, are answered with 400 listing their JSON paths; otherwise unknown fields are
+// returned as warnings.
+func (h *Handler) decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) ([]models.FieldProblem, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.config.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeRequestError(w, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), nil)
+			return nil, false
 		}
+		writeRequestError(w, http.StatusBadRequest, "failed to read request body: "+err.Error(), nil)
+		return nil, false
 	}
 
-	// Parse limit (default: 20, min: 1, max: 100)
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if limit, err := strconv.Atoi(limitStr); err == nil {
-			if limit < 1 {
-				limit = 1
-			}
-			if limit > 100 {
-				limit = 100
-			}
-			filters.Limit = limit
-		}
+	result, err := decode.JSON(data, v)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, "invalid request body: "+err.Error(), nil)
+		return nil, false
 	}
 
-	// Parse includeDetails flag
-	if includeDetails := r.URL.Query().Get("includeDetails"); includeDetails != "" {
-		if includeDetails == "true" || includeDetails == "1" {
-			filters.IncludeDetails = true
-		}
+	rejected := result.Mistyped
+	if h.config.RejectUnknownFields {
+		rejected = append(rejected, result.Unknown...)
 	}
-
-	return filters
+	if len(rejected) > 0 {
+		writeRequestError(w, http.StatusBadRequest, "invalid request body", rejected)
+		return nil, false
+	}
+	return result.Unknown, true
 }
 
-// CreateLoad handles POST /loads - creates a new load in Turvo
-func (h *Handler) CreateLoad(w http.ResponseWriter, r *http.Request) {
-	var load models.Load
+// writeRequestError responds with a RequestErrorResponse
+func writeRequestError(w http.ResponseWriter, status int, message string, fields []models.FieldProblem) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.RequestErrorResponse{Error: message, Fields: fields})
+}
 
-	if err := json.NewDecoder(r.Body).Decode(&load); err != nil {
-		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+// GetStatuses handles GET /statuses - lists every supported load status with its Turvo code
+func (h *Handler) GetStatuses(w http.ResponseWriter, r *http.Request) {
+	service, ok := loadService(w, r)
+	if !ok {
 		return
 	}
 
-	response, err := h.service.CreateLoad(&load)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.StatusListResponse{Data: service.Statuses()}); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
 		return
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+// loadService returns the load service of the request's tenant
+func loadService(w http.ResponseWriter, r *http.Request) (*load.Service, bool) {
+	t, ok := tenant.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "no tenant selected", http.StatusBadRequest)
+		return nil, false
+	}
+	return t.Loads, true
+}
+
+// writeServiceError responds 400 for invalid requests and 500 for everything else. Other errors
+// can carry Turvo's response body, with customer data, so the caller only gets the request ID
+// to report and the redacted error goes to the log.
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *load.ValidationError
+	if errors.As(err, &validationErr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+
+	slog.ErrorContext(r.Context(), "load request failed", "error", logging.Redact(err.Error()))
+	message := "internal error"
+	if requestID := chimiddleware.GetReqID(r.Context()); requestID != "" {
+		message += ", request ID " + requestID
+	}
+	http.Error(w, message, http.StatusInternalServerError)
 }
@@ -0,0 +1,203 @@
+package load
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/lwlach/turvo-integration-backend/internal/auth"
+	"github.com/lwlach/turvo-integration-backend/internal/logging"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/service/load"
+	"github.com/lwlach/turvo-integration-backend/internal/tenant"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+// newTestRouter serves the load routes for one tenant backed by a fresh fake Turvo API,
+// with authentication disabled
+func newTestRouter(t *testing.T, config Config) (http.Handler, *turvotest.Server) {
+	t.Helper()
+	fake := turvotest.NewServer()
+	t.Cleanup(fake.Close)
+
+	client, err := turvo.NewClient(fake.Config())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	testTenant := &tenant.Tenant{ID: "test", Loads: load.NewService(client, load.Config{})}
+
+	if config.MaxBodyBytes == 0 {
+		config.MaxBodyBytes = 1 << 20
+	}
+	r := chi.NewRouter()
+	r.Use(chimiddleware.RequestID)
+	r.Use(auth.Middleware(auth.Config{Disabled: true}))
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithTenant(r.Context(), testTenant)))
+		})
+	})
+	NewHandler(config).RegisterRoutes(r)
+	return r, fake
+}
+
+func serve(handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// postLoads creates n copies of the minimal test load and returns their IDs
+func postLoads(t *testing.T, handler http.Handler, n int) []string {
+	t.Helper()
+	data, err := os.ReadFile("../../service/load/testdata/fidelity/minimal.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]string, n)
+	for i := range ids {
+		recorder := serve(handler, http.MethodPost, "/loads", string(data))
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("POST /loads = %d: %s", recorder.Code, recorder.Body)
+		}
+		var response models.LoadCreateResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = response.ID
+	}
+	return ids
+}
+
+func decodeList(t *testing.T, recorder *httptest.ResponseRecorder) models.LoadListResponse {
+	t.Helper()
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+	var response models.LoadListResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode %s: %v", recorder.Body, err)
+	}
+	return response
+}
+
+func TestCreateAndListLoads(t *testing.T) {
+	handler, fake := newTestRouter(t, Config{})
+	ids := postLoads(t, handler, 3)
+
+	response := decodeList(t, serve(handler, http.MethodGet, "/loads?limit=2&page=2", ""))
+	if len(response.Data) != 1 || response.Data[0].ExternalTMSLoadID != ids[2] {
+		t.Errorf("page 2 = %+v, want load %s", response.Data, ids[2])
+	}
+	if got := fake.Requests(turvotest.EndpointCreate); got != 3 {
+		t.Errorf("create requests = %d, want 3", got)
+	}
+}
+
+func TestGetLoadsTurvoErrors(t *testing.T) {
+	t.Run("list fails", func(t *testing.T) {
+		handler, fake := newTestRouter(t, Config{})
+		fake.InjectError(turvotest.EndpointList, http.StatusInternalServerError, -1)
+
+		recorder := serve(handler, http.MethodGet, "/loads", "")
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", recorder.Code)
+		}
+	})
+
+	t.Run("Turvo body not returned", func(t *testing.T) {
+		handler, fake := newTestRouter(t, Config{})
+		fake.InjectErrorBody(turvotest.EndpointList, http.StatusBadGateway,
+			`{"Status":"ERROR","details":{"errorMessage":"no access for dispatch@example.com, call 555-123-4567"}}`, -1)
+		var logged bytes.Buffer
+		defer slog.SetDefault(slog.Default())
+		logger, err := logging.New(&logged, logging.Config{Redact: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		slog.SetDefault(logger)
+
+		recorder := serve(handler, http.MethodGet, "/loads", "")
+		if recorder.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want 500", recorder.Code)
+		}
+		body := recorder.Body.String()
+		if strings.Contains(body, "no access") || strings.Contains(body, "example.com") {
+			t.Errorf("body = %q, want Turvo's response left out", body)
+		}
+		if !strings.Contains(body, "request ID ") {
+			t.Errorf("body = %q, want the request ID", body)
+		}
+
+		log := logged.String()
+		if !strings.Contains(log, "load request failed") || !strings.Contains(log, "no access for [REDACTED]") {
+			t.Errorf("log = %s, want the redacted error", log)
+		}
+		if strings.Contains(log, "dispatch@example.com") || strings.Contains(log, "555-123-4567") {
+			t.Errorf("log = %s, want personal data redacted", log)
+		}
+	})
+
+	t.Run("details fail", func(t *testing.T) {
+		handler, fake := newTestRouter(t, Config{})
+		ids := postLoads(t, handler, 2)
+		fake.InjectError(turvotest.EndpointGet, http.StatusServiceUnavailable, -1)
+
+		response := decodeList(t, serve(handler, http.MethodGet, "/loads?includeDetails=true", ""))
+		if len(response.Data) != len(ids) || len(response.PartialFailures) != len(ids) {
+			t.Errorf("got %d loads and %d partial failures, want %d of each", len(response.Data), len(response.PartialFailures), len(ids))
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		handler, fake := newTestRouter(t, Config{})
+		postLoads(t, handler, 1)
+		fake.ExpireTokens()
+
+		response := decodeList(t, serve(handler, http.MethodGet, "/loads", ""))
+		if len(response.Data) != 1 {
+			t.Errorf("got %d loads, want 1", len(response.Data))
+		}
+		if got := fake.Requests(turvotest.EndpointToken); got != 2 {
+			t.Errorf("token requests = %d, want 2", got)
+		}
+	})
+}
+
+func TestCreateLoadUnknownFields(t *testing.T) {
+	body := `{"status":"tendered","customer":{"externalTMSId":"834099"},"pickup":{"city":"Newark","state":"NJ","readyTime":"2025-01-27T08:00:00Z"},"consignee":{"city":"Philadelphia","state":"PA","apptTime":"2025-01-28T14:00:00Z"},"carrier":{"externalTMSId":"834145","nmae":"x"}}`
+
+	t.Run("warned", func(t *testing.T) {
+		handler, _ := newTestRouter(t, Config{})
+		recorder := serve(handler, http.MethodPost, "/loads", body)
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+		}
+		var response models.LoadCreateResponse
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		if len(response.Warnings) != 1 || response.Warnings[0].Path != "carrier.nmae" {
+			t.Errorf("warnings = %+v, want carrier.nmae", response.Warnings)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		handler, fake := newTestRouter(t, Config{RejectUnknownFields: true})
+		recorder := serve(handler, http.MethodPost, "/loads", body)
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want 400", recorder.Code)
+		}
+		if got := fake.Requests(turvotest.EndpointCreate); got != 0 {
+			t.Errorf("create requests = %d, want 0", got)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package tenant
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lwlach/turvo-integration-backend/internal/auth"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/tenant"
+)
+
+type Handler struct {
+	registry *tenant.Registry
+}
+
+// NewHandler creates the tenant admin handler. Its routes must run behind auth.Middleware;
+// they require the tenants:admin scope and credentials not bound to a tenant.
+func NewHandler(registry *tenant.Registry) *Handler {
+	return &Handler{
+		registry: registry,
+	}
+}
+
+// RegisterRoutes registers the tenant admin routes with the chi router
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Route("/admin/tenants", func(r chi.Router) {
+		r.Use(auth.Require(auth.ScopeTenantsAdmin), auth.RequireUnbound())
+		r.Get("/", h.ListTenants)
+		r.Post("/", h.RegisterTenant)
+		r.Get("/{tenantID}", h.GetTenant)
+		r.Put("/{tenantID}/credentials", h.RotateCredentials)
+		r.Post("/{tenantID}/api-keys", h.IssueAPIKey)
+		r.Delete("/{tenantID}/api-keys/{keyID}", h.RevokeAPIKey)
+	})
+}
+
+// ListTenants handles GET /admin/tenants
+func (h *Handler) ListTenants(w http.ResponseWriter, r *http.Request) {
+	response := models.TenantListResponse{Data: []models.Tenant{}}
+	for _, t := range h.registry.List() {
+		response.Data = append(response.Data, h.registry.Info(t))
+	}
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// GetTenant handles GET /admin/tenants/{tenantID}
+func (h *Handler) GetTenant(w http.ResponseWriter, r *http.Request) {
+	t, found := h.registry.Get(chi.URLParam(r, "tenantID"))
+	if !found {
+		http.Error(w, tenant.ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, h.registry.Info(t))
+}
+
+// RegisterTenant handles POST /admin/tenants - registers a tenant and returns its first API key
+func (h *Handler) RegisterTenant(w http.ResponseWriter, r *http.Request) {
+	var req models.TenantCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, apiKey, err := h.registry.Register(r.Context(), tenant.Spec{
+		ID:            req.ID,
+		Name:          req.Name,
+		Credentials:   req.Credentials,
+		WebhookSecret: req.WebhookSecret,
+	})
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "registered tenant", "tenant_id", t.ID)
+	writeJSON(w, r, http.StatusCreated, models.TenantCreateResponse{
+		Tenant: h.registry.Info(t),
+		APIKey: apiKey,
+	})
+}
+
+// RotateCredentials handles PUT /admin/tenants/{tenantID}/credentials - switches the tenant to new Turvo credentials
+func (h *Handler) RotateCredentials(w http.ResponseWriter, r *http.Request) {
+	var req models.TenantCredentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tenantID := chi.URLParam(r, "tenantID")
+	if err := h.registry.RotateCredentials(r.Context(), tenantID, req.Credentials); err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "rotated Turvo credentials", "tenant_id", tenantID)
+	t, _ := h.registry.Get(tenantID)
+	writeJSON(w, r, http.StatusOK, h.registry.Info(t))
+}
+
+// IssueAPIKey handles POST /admin/tenants/{tenantID}/api-keys - issues a new API key with the
+// scopes in the optional body (all tenant scopes by default).
+// With ?revokeExisting=true the tenant's other keys stop working.
+func (h *Handler) IssueAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req models.APIKeyCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tenantID := chi.URLParam(r, "tenantID")
+	revokeExisting := r.URL.Query().Get("revokeExisting") == "true"
+
+	key, apiKey, err := h.registry.IssueAPIKey(tenantID, req.Scopes, revokeExisting)
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "issued API key", "tenant_id", tenantID, "key_id", key.ID, "revoked_existing", revokeExisting)
+	writeJSON(w, r, http.StatusCreated, models.APIKeyResponse{
+		APIKeyInfo: models.APIKeyInfo{
+			ID:        key.ID,
+			Scopes:    key.Scopes,
+			CreatedAt: key.CreatedAt,
+		},
+		APIKey: apiKey,
+	})
+}
+
+// RevokeAPIKey handles DELETE /admin/tenants/{tenantID}/api-keys/{keyID}
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantID")
+	keyID := chi.URLParam(r, "keyID")
+
+	if err := h.registry.RevokeAPIKey(tenantID, keyID); err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "revoked API key", "tenant_id", tenantID, "key_id", keyID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeRegistryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, tenant.ErrNotFound), errors.Is(err, tenant.ErrAPIKeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, tenant.ErrExists), errors.Is(err, tenant.ErrStaticCredentials):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, tenant.ErrInvalid), errors.Is(err, tenant.ErrCredentialsInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode response", "error", err)
+	}
+}
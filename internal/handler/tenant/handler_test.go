@@ -0,0 +1,108 @@
+package tenant
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lwlach/turvo-integration-backend/internal/auth"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/tenant"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+const credentialsBody = `{"credentials":{"clientName":"` + turvotest.ClientName + `","clientSecret":"` + turvotest.ClientSecret +
+	`","username":"` + turvotest.Username + `","password":"` + turvotest.Password + `"}}`
+
+// adminPrincipal is an admin not bound to a tenant
+var adminPrincipal = &auth.Principal{Method: auth.MethodAPIKey, ID: "admin", Scopes: []string{auth.ScopeTenantsAdmin}}
+
+// newTestRouter serves the tenant admin routes to principal, with the default tenant
+// configured from the environment and "acme" registered at runtime
+func newTestRouter(t *testing.T, principal *auth.Principal) http.Handler {
+	t.Helper()
+	fake := turvotest.NewServer()
+	t.Cleanup(fake.Close)
+
+	registry := tenant.NewRegistry(tenant.Config{Turvo: fake.Config()})
+	credentials := models.TurvoCredentials{
+		ClientName:   turvotest.ClientName,
+		ClientSecret: turvotest.ClientSecret,
+		Username:     turvotest.Username,
+		Password:     turvotest.Password,
+	}
+	if _, err := registry.RegisterStatic(tenant.Spec{ID: "default", Credentials: credentials}, nil); err != nil {
+		t.Fatalf("RegisterStatic: %v", err)
+	}
+	if _, _, err := registry.Register(t.Context(), tenant.Spec{ID: "acme", Credentials: credentials}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	})
+	NewHandler(registry).RegisterRoutes(r)
+	return r
+}
+
+func TestRotateCredentials(t *testing.T) {
+	router := newTestRouter(t, adminPrincipal)
+	tests := []struct {
+		name     string
+		tenantID string
+		body     string
+		status   int
+	}{
+		{name: "registered tenant", tenantID: "acme", body: credentialsBody, status: http.StatusOK},
+		{name: "tenant configured from the environment", tenantID: "default", body: credentialsBody, status: http.StatusConflict},
+		{name: "unknown tenant", tenantID: "nobody", body: credentialsBody, status: http.StatusNotFound},
+		{name: "rejected by Turvo", tenantID: "acme", body: strings.Replace(credentialsBody, turvotest.Password, "wrong", 1), status: http.StatusBadRequest},
+		{name: "invalid body", tenantID: "acme", body: `{`, status: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/admin/tenants/"+test.tenantID+"/credentials", strings.NewReader(test.body))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != test.status {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestAdminRoutesRejectTenantBoundAdmin(t *testing.T) {
+	// An admin scope on a tenant's credentials must not reach other tenants
+	router := newTestRouter(t, &auth.Principal{
+		Method:   auth.MethodJWT,
+		ID:       "acme-service",
+		TenantID: "acme",
+		Scopes:   []string{auth.ScopeTenantsAdmin},
+	})
+	tests := []struct {
+		method string
+		target string
+		body   string
+	}{
+		{method: http.MethodGet, target: "/admin/tenants"},
+		{method: http.MethodPost, target: "/admin/tenants", body: `{"id":"globex",` + credentialsBody[1:]},
+		{method: http.MethodGet, target: "/admin/tenants/default"},
+		{method: http.MethodPut, target: "/admin/tenants/default/credentials", body: credentialsBody},
+		{method: http.MethodPost, target: "/admin/tenants/acme/api-keys", body: `{}`},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.target, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want 403: %s", recorder.Code, recorder.Body)
+			}
+		})
+	}
+}
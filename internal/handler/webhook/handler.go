@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/service/webhook"
+	"github.com/lwlach/turvo-integration-backend/internal/tenant"
+)
+
+// Maximum accepted webhook body size (1 MB)
+const maxBodyBytes = 1 << 20
+
+const (
+	// signatureHeader carries "sha256=<hex HMAC-SHA256 of the raw body>" keyed with the shared secret
+	signatureHeader = "X-Turvo-Signature"
+	// secretHeader carries the shared secret itself, for subscriptions that cannot sign
+	secretHeader = "X-Turvo-Webhook-Secret"
+)
+
+type Handler struct {
+	service *webhook.Service
+	tenants *tenant.Registry
+	secret  string // Used for tenants without their own webhook secret
+}
+
+func NewHandler(service *webhook.Service, tenants *tenant.Registry, secret string) *Handler {
+	return &Handler{
+		service: service,
+		tenants: tenants,
+		secret:  secret,
+	}
+}
+
+// RegisterRoutes registers the webhook routes with the chi router
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Post("/turvo/webhooks", h.ReceiveEvent)
+	r.Post("/turvo/webhooks/{tenantID}", h.ReceiveEvent)
+}
+
+// ReceiveEvent handles POST /turvo/webhooks/{tenantID} - receives shipment, status and location events
+// from a tenant's Turvo account. Without a tenant ID the events belong to the default tenant.
+func (h *Handler) ReceiveEvent(w http.ResponseWriter, r *http.Request) {
+	var t *tenant.Tenant
+	var found bool
+	if tenantID := chi.URLParam(r, "tenantID"); tenantID != "" {
+		t, found = h.tenants.Get(tenantID)
+	} else {
+		t, found = h.tenants.Default()
+	}
+	if !found {
+		http.Error(w, "unknown tenant", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	secret := t.WebhookSecret()
+	if secret == "" {
+		secret = h.secret
+	}
+	if !verify(r, body, secret) {
+		http.Error(w, "invalid webhook signature", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := webhook.ParseEvent(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.HandleEvent(r.Context(), t.ID, t.Loads, event)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to handle webhook event", "tenant_id", t.ID, "event_id", event.EventID, "error", err)
+		http.Error(w, "failed to process event", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.WebhookAckResponse{
+		EventID:   event.EventID,
+		Duplicate: result == webhook.Duplicate,
+		Outdated:  result == webhook.Outdated,
+	}); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// verify checks the request signature, or the shared secret header when no signature is sent
+func verify(r *http.Request, body []byte, secret string) bool {
+	// Reject everything if no secret is configured
+	if secret == "" {
+		return false
+	}
+
+	if signature := r.Header.Get(signatureHeader); signature != "" {
+		signature = strings.TrimPrefix(signature, "sha256=")
+		expected, err := hex.DecodeString(signature)
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		return hmac.Equal(mac.Sum(nil), expected)
+	}
+
+	if sent := r.Header.Get(secretHeader); sent != "" {
+		return subtle.ConstantTimeCompare([]byte(sent), []byte(secret)) == 1
+	}
+
+	return false
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/notify"
+	"github.com/lwlach/turvo-integration-backend/internal/service/webhook"
+	"github.com/lwlach/turvo-integration-backend/internal/tenant"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+const (
+	globalSecret = "global-secret"
+	acmeSecret   = "acme-secret"
+)
+
+// newTestRouter serves the webhook routes for the default tenant, which uses the global
+// secret, and "acme", which has its own
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+	fake := turvotest.NewServer()
+	t.Cleanup(fake.Close)
+
+	registry := tenant.NewRegistry(tenant.Config{Turvo: fake.Config(), DefaultTenantID: "default"})
+	credentials := models.TurvoCredentials{
+		ClientName:   turvotest.ClientName,
+		ClientSecret: turvotest.ClientSecret,
+		Username:     turvotest.Username,
+		Password:     turvotest.Password,
+	}
+	for _, spec := range []tenant.Spec{
+		{ID: "default", Credentials: credentials},
+		{ID: "acme", Credentials: credentials, WebhookSecret: acmeSecret},
+	} {
+		if _, err := registry.RegisterStatic(spec, nil); err != nil {
+			t.Fatalf("RegisterStatic: %v", err)
+		}
+	}
+
+	r := chi.NewRouter()
+	NewHandler(webhook.NewService(notify.NewLogNotifier()), registry, globalSecret).RegisterRoutes(r)
+	return r
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func statusEvent(eventID, occurredAt string) string {
+	return `{"eventId":"` + eventID + `","eventType":"SHIPMENT_STATUS_CHANGED","occurredAt":"` + occurredAt +
+		`","shipmentId":1000000001,"payload":{"status":{"code":{"key":"2103","value":"Dispatched"}}}}`
+}
+
+func deliver(handler http.Handler, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestReceiveEventVerification(t *testing.T) {
+	body := statusEvent("evt-1", "2025-01-27T09:15:00Z")
+
+	tests := []struct {
+		name    string
+		target  string
+		body    string
+		headers map[string]string
+		status  int
+	}{
+		{name: "valid signature", target: "/turvo/webhooks/acme", headers: map[string]string{signatureHeader: sign(acmeSecret, body)}, status: http.StatusOK},
+		{name: "signature without prefix", target: "/turvo/webhooks/acme", headers: map[string]string{signatureHeader: strings.TrimPrefix(sign(acmeSecret, body), "sha256=")}, status: http.StatusOK},
+		{name: "signature with another secret", target: "/turvo/webhooks/acme", headers: map[string]string{signatureHeader: sign(globalSecret, body)}, status: http.StatusUnauthorized},
+		{name: "signature of another body", target: "/turvo/webhooks/acme", headers: map[string]string{signatureHeader: sign(acmeSecret, body+" ")}, status: http.StatusUnauthorized},
+		{name: "malformed signature", target: "/turvo/webhooks/acme", headers: map[string]string{signatureHeader: "sha256=not-hex"}, status: http.StatusUnauthorized},
+		{name: "bad signature with a valid shared secret", target: "/turvo/webhooks/acme", headers: map[string]string{signatureHeader: "sha256=00", secretHeader: acmeSecret}, status: http.StatusUnauthorized},
+		{name: "valid shared secret", target: "/turvo/webhooks/acme", headers: map[string]string{secretHeader: acmeSecret}, status: http.StatusOK},
+		{name: "wrong shared secret", target: "/turvo/webhooks/acme", headers: map[string]string{secretHeader: "guess"}, status: http.StatusUnauthorized},
+		{name: "no signature or secret", target: "/turvo/webhooks/acme", status: http.StatusUnauthorized},
+		{name: "default tenant with the global secret", target: "/turvo/webhooks", headers: map[string]string{signatureHeader: sign(globalSecret, body)}, status: http.StatusOK},
+		{name: "default tenant with another tenant's secret", target: "/turvo/webhooks/default", headers: map[string]string{secretHeader: acmeSecret}, status: http.StatusUnauthorized},
+		{name: "unknown tenant", target: "/turvo/webhooks/nobody", headers: map[string]string{secretHeader: globalSecret}, status: http.StatusNotFound},
+		{name: "invalid event", target: "/turvo/webhooks/acme", body: `{"eventId":"evt-x"}`, headers: map[string]string{secretHeader: acmeSecret}, status: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			eventBody := body
+			if test.body != "" {
+				eventBody = test.body
+			}
+			recorder := deliver(newTestRouter(t), test.target, eventBody, test.headers)
+			if recorder.Code != test.status {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestReceiveEventAck(t *testing.T) {
+	handler := newTestRouter(t)
+	ack := func(body string) models.WebhookAckResponse {
+		t.Helper()
+		recorder := deliver(handler, "/turvo/webhooks/acme", body, map[string]string{signatureHeader: sign(acmeSecret, body)})
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+		}
+		var response models.WebhookAckResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want models.WebhookAckResponse
+	}{
+		{name: "new event", body: statusEvent("evt-2", "2025-01-27T10:00:00Z"), want: models.WebhookAckResponse{EventID: "evt-2"}},
+		{name: "redelivery", body: statusEvent("evt-2", "2025-01-27T10:00:00Z"), want: models.WebhookAckResponse{EventID: "evt-2", Duplicate: true}},
+		{name: "older event", body: statusEvent("evt-1", "2025-01-27T09:00:00Z"), want: models.WebhookAckResponse{EventID: "evt-1", Outdated: true}},
+		{name: "newer event", body: statusEvent("evt-3", "2025-01-27T11:00:00Z"), want: models.WebhookAckResponse{EventID: "evt-3"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ack(test.body); got != test.want {
+				t.Errorf("response = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package logging sets up structured JSON logging with log/slog.
+//
+// Every line logged with a request context carries the request's ID (see chi's
+// middleware.RequestID) and trace, so a request can be followed through our handlers and the
+// Turvo calls it makes. Secrets and personal data are redacted unless redaction is turned off.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes added from the context
+const (
+	RequestIDKey = "request_id"
+	TraceIDKey   = "trace_id"
+	SpanIDKey    = "span_id"
+)
+
+// Config holds logging options
+type Config struct {
+	Level  string // debug, info, warn or error (default: info)
+	Redact bool   // Redact secrets, emails and phone numbers (see Redact)
+}
+
+// ParseLevel parses a level name such as "debug"
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if name == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(strings.ToLower(name))); err != nil {
+		return level, fmt.Errorf("invalid log level %q, use debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// New returns a logger writing JSON lines to w
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	if cfg.Redact {
+		options.ReplaceAttr = redactAttr
+	}
+	return slog.New(&contextHandler{Handler: slog.NewJSONHandler(w, options)}), nil
+}
+
+// Setup makes a logger writing to w the default logger. Output of the log package goes
+// through it as well, at info level.
+func Setup(w io.Writer, cfg Config) error {
+	logger, err := New(w, cfg)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// contextHandler adds the request ID and the trace of the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := chimiddleware.GetReqID(ctx); requestID != "" {
+		record.AddAttrs(slog.String(RequestIDKey, requestID))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String(TraceIDKey, span.TraceID().String()), slog.String(SpanIDKey, span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
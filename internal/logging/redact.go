@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces redacted values
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are parts of attribute and JSON field names whose values are always redacted
+var sensitiveKeys = []string{"password", "secret", "token", "authorization", "apikey", "api_key", "api-key", "cookie", "email", "phone"}
+
+var (
+	// "clientSecret": "...", "access_token":"..." and the like in JSON bodies
+	sensitiveJSONField = regexp.MustCompile(`(?i)("[^"]*(?:password|secret|token|authorization|api_?key|email|phone)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// client_secret=..., password=... in query strings and form bodies
+	sensitiveParam = regexp.MustCompile(`(?i)\b([\w-]*(?:password|secret|token|api_?key)[\w-]*=)[^&\s"]+`)
+	bearerToken    = regexp.MustCompile(`(?i)\bbearer\s+[\w\-.~+/]+=*`)
+	email          = regexp.MustCompile(`[\w.%+-]+@[\w-]+(?:\.[\w-]+)*\.[A-Za-z]{2,}`)
+	// Phone numbers need a + prefix or separators, so IDs and timestamps are not mistaken for them
+	phone = regexp.MustCompile(`\+\d[\d\s().-]{8,}\d|\(?\b\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}\b`)
+)
+
+// Redact removes secrets and personal data from free text such as Turvo response bodies and
+// error messages: values of sensitive JSON fields and query parameters, bearer tokens, email
+// addresses and phone numbers
+func Redact(text string) string {
+	text = sensitiveJSONField.ReplaceAllString(text, `$1"`+Redacted+`"`)
+	text = sensitiveParam.ReplaceAllString(text, "${1}"+Redacted)
+	text = bearerToken.ReplaceAllString(text, "Bearer "+Redacted)
+	text = email.ReplaceAllString(text, Redacted)
+	return phone.ReplaceAllString(text, Redacted)
+}
+
+// sensitiveKey reports whether an attribute or field name holds a secret or personal data
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr is the slog.HandlerOptions.ReplaceAttr function of redacting loggers
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() == slog.KindGroup {
+		return attr
+	}
+	if sensitiveKey(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(attr.Value.String()))
+	case slog.KindAny:
+		return slog.Any(attr.Key, redactValue(attr.Value.Any()))
+	}
+	return attr
+}
+
+// redactValue redacts a value logged with slog.Any. Errors and fmt.Stringer values are redacted
+// as text. Maps, slices and structs are walked through their JSON form, so fields with sensitive
+// names are redacted like attributes; anything else is redacted as its formatted %v text.
+func redactValue(value any) any {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case error:
+		return Redact(value.Error())
+	case fmt.Stringer:
+		return Redact(value.String())
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return Redact(fmt.Sprintf("%v", value))
+	}
+	var decoded any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return Redact(fmt.Sprintf("%v", value))
+	}
+	return redactJSON(decoded)
+}
+
+// redactJSON redacts a decoded JSON value: values under sensitive keys entirely, and strings
+// with Redact
+func redactJSON(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if sensitiveKey(key) {
+				value[key] = Redacted
+				continue
+			}
+			value[key] = redactJSON(field)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = redactJSON(item)
+		}
+	case string:
+		return Redact(value)
+	}
+	return value
+}
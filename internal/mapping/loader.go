@@ -0,0 +1,136 @@
+package mapping
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileSet holds the default profile and per-tenant profiles
+type ProfileSet struct {
+	Default *Profile
+	Tenants map[string]*Profile
+}
+
+// profileFile is the layout of a mapping profile file. Tenant profiles only need the
+// fields that differ; everything else is taken from the file's default profile.
+// Statuses are merged by API status rather than replaced, so a profile can change or add
+// single statuses without repeating the whole table.
+type profileFile struct {
+	Default yaml.Node            `yaml:"default"`
+	Tenants map[string]yaml.Node `yaml:"tenants"`
+}
+
+// DefaultSet returns a profile set with only the built-in profile
+func DefaultSet() *ProfileSet {
+	return &ProfileSet{
+		Default: Default(),
+		Tenants: map[string]*Profile{},
+	}
+}
+
+// LoadFile reads a YAML or JSON mapping profile file. The file's default profile is applied
+// over the built-in defaults, and each tenant profile over the file's default. Unknown fields
+// and invalid values are reported together.
+func LoadFile(path string) (*ProfileSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping profile: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses mapping profiles from YAML or JSON
+func Parse(data []byte) (*ProfileSet, error) {
+	var file profileFile
+	if err := decodeStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid mapping profile: %w", err)
+	}
+
+	var errs []error
+
+	defaultProfile := Default()
+	if !file.Default.IsZero() {
+		if err := decodeProfile(&file.Default, defaultProfile); err != nil {
+			errs = append(errs, fmt.Errorf("default: %w", err))
+		}
+	}
+	if err := defaultProfile.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("default: %w", err))
+	}
+
+	set := &ProfileSet{
+		Default: defaultProfile,
+		Tenants: make(map[string]*Profile, len(file.Tenants)),
+	}
+
+	// Sort tenants so errors are reported in a stable order
+	tenantIDs := make([]string, 0, len(file.Tenants))
+	for tenantID := range file.Tenants {
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	sort.Strings(tenantIDs)
+
+	for _, tenantID := range tenantIDs {
+		node := file.Tenants[tenantID]
+		profile := defaultProfile.clone()
+		// Validate even if decoding failed, so all problems are reported at once
+		decodeErr := decodeProfile(&node, profile)
+		if decodeErr != nil {
+			errs = append(errs, fmt.Errorf("tenants.%s: %w", tenantID, decodeErr))
+		}
+		if err := profile.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("tenants.%s: %w", tenantID, err))
+			continue
+		}
+		if decodeErr == nil {
+			set.Tenants[tenantID] = profile
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return set, nil
+}
+
+// ForTenant returns the profile for a tenant, or the default profile if the tenant has none
+func (s *ProfileSet) ForTenant(tenantID string) *Profile {
+	if profile, found := s.Tenants[tenantID]; found {
+		return profile
+	}
+	return s.Default
+}
+
+// decodeProfile decodes a profile node over profile, merging its statuses into the existing table
+func decodeProfile(node *yaml.Node, profile *Profile) error {
+	base := profile.Statuses
+	profile.Statuses = nil
+	err := decodeNodeStrict(node, profile)
+	profile.Statuses = mergeStatuses(base, profile.Statuses)
+	return err
+}
+
+// decodeStrict decodes YAML (or JSON, which is valid YAML) and rejects unknown fields
+func decodeStrict(data []byte, out interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// decodeNodeStrict decodes a YAML node over out, rejecting unknown fields
+func decodeNodeStrict(node *yaml.Node, out interface{}) error {
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+	return decodeStrict(data, out)
+}
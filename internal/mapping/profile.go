@@ -0,0 +1,235 @@
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyValue is a Turvo code (key) with its display value
+type KeyValue struct {
+	Key   string `yaml:"key" json:"key"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// UnmarshalYAML replaces the whole code, so a profile that overrides only the key does not keep
+// the value it overrides (which Matches would still accept)
+func (kv *KeyValue) UnmarshalYAML(node *yaml.Node) error {
+	type keyValue KeyValue // Without the method, so decoding does not recurse
+	var decoded keyValue
+	if err := decodeNodeStrict(node, &decoded); err != nil {
+		return err
+	}
+	*kv = KeyValue(decoded)
+	return nil
+}
+
+// ExternalIDType is a Turvo external ID type. Besides its key and value, it matches types whose
+// value contains one of Contains (case-insensitive), since Turvo accounts label the same type
+// differently ("Truck #", "Truck number").
+type ExternalIDType struct {
+	Key      string   `yaml:"key" json:"key"`
+	Value    string   `yaml:"value" json:"value"`
+	Contains []string `yaml:"contains,omitempty" json:"contains,omitempty"`
+}
+
+// Code returns the key and value sent to Turvo for this type
+func (t ExternalIDType) Code() KeyValue {
+	return KeyValue{Key: t.Key, Value: t.Value}
+}
+
+// UnmarshalYAML replaces the whole type, like KeyValue.UnmarshalYAML
+func (t *ExternalIDType) UnmarshalYAML(node *yaml.Node) error {
+	type externalIDType ExternalIDType // Without the method, so decoding does not recurse
+	var decoded externalIDType
+	if err := decodeNodeStrict(node, &decoded); err != nil {
+		return err
+	}
+	*t = ExternalIDType(decoded)
+	return nil
+}
+
+// Profile holds the Drumkit↔Turvo codes that differ between Turvo tenants
+type Profile struct {
+	ExternalIDTypes ExternalIDTypes `yaml:"externalIdTypes" json:"externalIdTypes"`
+	StopTypes       StopTypes       `yaml:"stopTypes" json:"stopTypes"`
+	Equipment       Equipment       `yaml:"equipment" json:"equipment"`
+	Services        []ServiceRule   `yaml:"services" json:"services"`
+	Statuses        []StatusMapping `yaml:"statuses" json:"statuses"`
+	DefaultTimezone string          `yaml:"defaultTimezone" json:"defaultTimezone"`
+}
+
+// ExternalIDTypes are the Turvo external ID types our fields are stored as
+type ExternalIDTypes struct {
+	PurchaseOrder   ExternalIDType `yaml:"purchaseOrder" json:"purchaseOrder"`     // poNums
+	ReferenceNumber ExternalIDType `yaml:"referenceNumber" json:"referenceNumber"` // customer.refNumber
+	BOL             ExternalIDType `yaml:"bol" json:"bol"`                         // carrier.sealNumber
+	PRO             ExternalIDType `yaml:"pro" json:"pro"`                         // not mapped yet
+	Truck           ExternalIDType `yaml:"truck" json:"truck"`                     // carrier.externalTMSTruckId
+	Trailer         ExternalIDType `yaml:"trailer" json:"trailer"`                 // carrier.externalTMSTrailerId
+}
+
+// StopTypes identifies pickup stops in Turvo routes; every other stop is treated as a delivery
+type StopTypes struct {
+	Pickup KeyValue `yaml:"pickup" json:"pickup"`
+}
+
+// Equipment holds the equipment codes sent when creating shipments
+type Equipment struct {
+	DefaultType KeyValue `yaml:"defaultType" json:"defaultType"`
+	WeightUnits KeyValue `yaml:"weightUnits" json:"weightUnits"`
+	TempUnits   KeyValue `yaml:"tempUnits" json:"tempUnits"`
+}
+
+// ServiceRule maps Turvo stop services to specification flags. A service matches when its key
+// equals, or its value contains (case-insensitive), one of Match. Flag is set for a match on any
+// stop; Pickup and Delivery are set for matches on pickup and delivery stops respectively.
+type ServiceRule struct {
+	Match    []string `yaml:"match" json:"match"`
+	Flag     string   `yaml:"flag,omitempty" json:"flag,omitempty"`
+	Pickup   string   `yaml:"pickup,omitempty" json:"pickup,omitempty"`
+	Delivery string   `yaml:"delivery,omitempty" json:"delivery,omitempty"`
+}
+
+// SpecificationFlags are the specification fields a ServiceRule can set
+var SpecificationFlags = []string{
+	"liftgatePickup", "liftgateDelivery", "insidePickup", "insideDelivery", "tarps", "oversized",
+	"hazmat", "straps", "permits", "escorts", "seal", "customBonded", "labor",
+}
+
+// Default returns the built-in profile, matching Turvo's standard codes
+func Default() *Profile {
+	return &Profile{
+		ExternalIDTypes: ExternalIDTypes{
+			PurchaseOrder:   ExternalIDType{Key: "1400", Value: "Purchase shipment #"},
+			ReferenceNumber: ExternalIDType{Key: "1401", Value: "Reference Number"},
+			BOL:             ExternalIDType{Key: "7602", Value: "BOL #", Contains: []string{"bol"}},
+			PRO:             ExternalIDType{Key: "7603", Value: "PRO #", Contains: []string{"pro"}},
+			Truck:           ExternalIDType{Key: "7605", Value: "Truck #", Contains: []string{"truck"}},
+			Trailer:         ExternalIDType{Key: "7606", Value: "Trailer #", Contains: []string{"trailer"}},
+		},
+		StopTypes: StopTypes{
+			Pickup: KeyValue{Key: "1500", Value: "Pickup"},
+		},
+		Equipment: Equipment{
+			DefaultType: KeyValue{Key: "1200", Value: "Van"},
+			WeightUnits: KeyValue{Key: "1520", Value: "lb"},
+			TempUnits:   KeyValue{Key: "1510", Value: "°F"},
+		},
+		Services: []ServiceRule{
+			{Match: []string{"liftgate"}, Pickup: "liftgatePickup", Delivery: "liftgateDelivery"},
+			{Match: []string{"inside"}, Pickup: "insidePickup", Delivery: "insideDelivery"},
+			{Match: []string{"tarp"}, Flag: "tarps"},
+			{Match: []string{"hazmat"}, Flag: "hazmat"},
+			{Match: []string{"strap"}, Flag: "straps"},
+			{Match: []string{"seal"}, Flag: "seal"},
+		},
+		Statuses:        DefaultStatuses(),
+		DefaultTimezone: "America/New_York",
+	}
+}
+
+// Matches reports whether an external ID type is the given configured type
+func (kv KeyValue) Matches(other KeyValue) bool {
+	return (kv.Key != "" && kv.Key == other.Key) || (kv.Value != "" && strings.EqualFold(kv.Value, other.Value))
+}
+
+// Matches reports whether a Turvo external ID type is this type: same key, same value
+// (case-insensitive), or a value containing one of Contains (case-insensitive)
+func (t ExternalIDType) Matches(other KeyValue) bool {
+	if t.Code().Matches(other) {
+		return true
+	}
+	value := strings.ToLower(other.Value)
+	for _, part := range t.Contains {
+		if strings.Contains(value, strings.ToLower(part)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesService reports whether a Turvo service matches the rule
+func (r ServiceRule) MatchesService(service KeyValue) bool {
+	value := strings.ToLower(service.Value)
+	for _, match := range r.Match {
+		if match == service.Key || strings.Contains(value, strings.ToLower(match)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the profile and returns every problem found
+func (p *Profile) Validate() error {
+	var errs []error
+
+	requireKey := func(field string, kv KeyValue) {
+		if kv.Key == "" {
+			errs = append(errs, fmt.Errorf("%s.key is required", field))
+		}
+	}
+	requireType := func(field string, t ExternalIDType) {
+		requireKey(field, t.Code())
+		for i, part := range t.Contains {
+			if strings.TrimSpace(part) == "" {
+				errs = append(errs, fmt.Errorf("%s.contains[%d] must not be empty", field, i))
+			}
+		}
+	}
+	requireType("externalIdTypes.purchaseOrder", p.ExternalIDTypes.PurchaseOrder)
+	requireType("externalIdTypes.referenceNumber", p.ExternalIDTypes.ReferenceNumber)
+	requireType("externalIdTypes.bol", p.ExternalIDTypes.BOL)
+	requireType("externalIdTypes.pro", p.ExternalIDTypes.PRO)
+	requireType("externalIdTypes.truck", p.ExternalIDTypes.Truck)
+	requireType("externalIdTypes.trailer", p.ExternalIDTypes.Trailer)
+	requireKey("stopTypes.pickup", p.StopTypes.Pickup)
+	requireKey("equipment.defaultType", p.Equipment.DefaultType)
+	requireKey("equipment.weightUnits", p.Equipment.WeightUnits)
+	requireKey("equipment.tempUnits", p.Equipment.TempUnits)
+
+	for i, rule := range p.Services {
+		if len(rule.Match) == 0 {
+			errs = append(errs, fmt.Errorf("services[%d].match must not be empty", i))
+		}
+		if rule.Flag == "" && rule.Pickup == "" && rule.Delivery == "" {
+			errs = append(errs, fmt.Errorf("services[%d] must set flag, pickup or delivery", i))
+		}
+		for field, flag := range map[string]string{"flag": rule.Flag, "pickup": rule.Pickup, "delivery": rule.Delivery} {
+			if flag != "" && !isSpecificationFlag(flag) {
+				errs = append(errs, fmt.Errorf("services[%d].%s: unknown specification flag %q", i, field, flag))
+			}
+		}
+	}
+
+	errs = append(errs, validateStatuses(p.Statuses)...)
+
+	if p.DefaultTimezone == "" {
+		errs = append(errs, fmt.Errorf("defaultTimezone is required"))
+	} else if _, err := time.LoadLocation(p.DefaultTimezone); err != nil {
+		errs = append(errs, fmt.Errorf("defaultTimezone: unknown timezone %q", p.DefaultTimezone))
+	}
+
+	return errors.Join(errs...)
+}
+
+// clone returns a deep copy of the profile
+func (p *Profile) clone() *Profile {
+	data, _ := json.Marshal(p)
+	var copied Profile
+	json.Unmarshal(data, &copied)
+	return &copied
+}
+
+func isSpecificationFlag(flag string) bool {
+	for _, known := range SpecificationFlags {
+		if known == flag {
+			return true
+		}
+	}
+	return false
+}
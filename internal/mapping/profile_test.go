@@ -0,0 +1,196 @@
+package mapping
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExternalIDTypeMatches(t *testing.T) {
+	types := Default().ExternalIDTypes
+	tests := []struct {
+		name      string
+		idType    ExternalIDType
+		turvoType KeyValue
+		want      bool
+	}{
+		{name: "same key", idType: types.Truck, turvoType: KeyValue{Key: "7605", Value: "Equipment"}, want: true},
+		{name: "same value in another case", idType: types.Truck, turvoType: KeyValue{Key: "9999", Value: "TRUCK #"}, want: true},
+		{name: "value containing a label", idType: types.Truck, turvoType: KeyValue{Key: "9999", Value: "Truck number"}, want: true},
+		{name: "label of another type", idType: types.Truck, turvoType: KeyValue{Key: "7606", Value: "Trailer #"}, want: false},
+		{name: "bol label", idType: types.BOL, turvoType: KeyValue{Value: "Master BOL"}, want: true},
+		{name: "pro label", idType: types.PRO, turvoType: KeyValue{Value: "Carrier PRO number"}, want: true},
+		{name: "no contains, exact value only", idType: types.PurchaseOrder, turvoType: KeyValue{Value: "Purchase shipment # (old)"}, want: false},
+		{name: "custom contains", idType: ExternalIDType{Key: "1", Contains: []string{"Lading"}}, turvoType: KeyValue{Value: "Bill of lading"}, want: true},
+		{name: "empty type matches nothing", idType: ExternalIDType{}, turvoType: KeyValue{}, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.idType.Matches(test.turvoType); got != test.want {
+				t.Errorf("%+v.Matches(%+v) = %v, want %v", test.idType, test.turvoType, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseOverrideReplacesCodes(t *testing.T) {
+	set, err := Parse([]byte(`
+tenants:
+  acme:
+    externalIdTypes:
+      purchaseOrder: { key: "1450" }
+      truck: { key: "9005", value: "Tractor", contains: ["tractor"] }
+    stopTypes:
+      pickup: { key: "1550" }
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	profile := set.ForTenant("acme")
+
+	// Fields left out of an override are cleared rather than kept from the default
+	purchaseOrder := profile.ExternalIDTypes.PurchaseOrder
+	if purchaseOrder.Value != "" || purchaseOrder.Matches(KeyValue{Key: "1400", Value: "Purchase shipment #"}) {
+		t.Errorf("purchaseOrder = %+v, still matches the default type", purchaseOrder)
+	}
+	if profile.StopTypes.Pickup.Matches(KeyValue{Key: "1500", Value: "Pickup"}) {
+		t.Errorf("pickup = %+v, still matches the default stop type", profile.StopTypes.Pickup)
+	}
+
+	truck := profile.ExternalIDTypes.Truck
+	if truck.Matches(KeyValue{Value: "Truck #"}) {
+		t.Errorf("truck = %+v, still matches the default label", truck)
+	}
+	if !truck.Matches(KeyValue{Value: "Tractor ID"}) {
+		t.Errorf("truck = %+v, want it to match its own label", truck)
+	}
+
+	// Types the tenant does not override keep the defaults, contains included
+	if !profile.ExternalIDTypes.Trailer.Matches(KeyValue{Value: "Trailer number"}) {
+		t.Errorf("trailer = %+v, want the default contains", profile.ExternalIDTypes.Trailer)
+	}
+	// The default profile is not changed by the tenant's overrides
+	if set.Default.ExternalIDTypes.PurchaseOrder.Value != "Purchase shipment #" {
+		t.Errorf("default purchaseOrder = %+v", set.Default.ExternalIDTypes.PurchaseOrder)
+	}
+}
+
+func TestParseRejectsInvalidExternalIDTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		want    string
+	}{
+		{name: "unknown field", profile: `truck: { key: "7605", contain: ["truck"] }`, want: "field contain not found"},
+		{name: "missing key", profile: `truck: { value: "Truck #" }`, want: "externalIdTypes.truck.key is required"},
+		{name: "empty contains", profile: `bol: { key: "7602", contains: ["bol", " "] }`, want: "externalIdTypes.bol.contains[1] must not be empty"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte("default:\n  externalIdTypes:\n    " + test.profile + "\n"))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestTurvoStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string // Turvo key, empty if unknown
+	}{
+		{status: "covered", want: "2102"},
+		{status: "pending", want: "2120"},
+		{status: "PENDING", want: "2120"},
+		{status: "Tender - offered", want: "2117"},
+		{status: "en route", want: "2105"},
+		{status: " At-Pickup ", want: "2104"},
+		{status: "teleported"},
+		{status: "pending_review"},
+		{status: " "},
+	}
+	for _, test := range tests {
+		status, ok := Default().TurvoStatus(test.status)
+		if ok != (test.want != "") || status.Key != test.want {
+			t.Errorf("TurvoStatus(%q) = %+v, %t, want key %q", test.status, status, ok, test.want)
+		}
+	}
+
+	// Aliases are accepted on input but the API status is always returned
+	if got := Default().APIStatus("2120", "Draft"); got != "draft" {
+		t.Errorf("APIStatus(2120) = %q, want draft", got)
+	}
+	if got := Default().APIStatus("2199", "Partially Delivered"); got != "partially_delivered" {
+		t.Errorf("APIStatus of an unknown code = %q, want the normalized Turvo value", got)
+	}
+}
+
+func TestParseStatusOverrides(t *testing.T) {
+	set, err := Parse([]byte(`
+tenants:
+  acme:
+    statuses:
+      - { api: covered, key: "3102", value: "Carrier assigned" }
+      - { api: draft, key: "2120", value: "Draft" }
+      - { api: on_dock, key: "3150", value: "On dock", aliases: [docked] }
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	profile := set.ForTenant("acme")
+
+	tests := []struct {
+		status string
+		want   string // Turvo key, empty if unknown
+	}{
+		{status: "covered", want: "3102"},
+		{status: "Carrier assigned", want: "3102"},
+		{status: "on_dock", want: "3150"},
+		{status: "docked", want: "3150"},
+		{status: "delivered", want: "2107"},
+		// The override of draft leaves out the pending alias
+		{status: "draft", want: "2120"},
+		{status: "pending"},
+	}
+	for _, test := range tests {
+		status, ok := profile.TurvoStatus(test.status)
+		if ok != (test.want != "") || status.Key != test.want {
+			t.Errorf("TurvoStatus(%q) = %+v, %t, want key %q", test.status, status, ok, test.want)
+		}
+	}
+	if got := profile.APIStatus("3102", "Carrier assigned"); got != "covered" {
+		t.Errorf("APIStatus(3102) = %q, want covered", got)
+	}
+	if got := len(profile.Statuses); got != len(DefaultStatuses())+1 {
+		t.Errorf("%d statuses, want the defaults and on_dock", got)
+	}
+
+	// The default profile keeps its statuses
+	if status, _ := set.Default.TurvoStatus("covered"); status.Key != "2102" {
+		t.Errorf("default covered = %+v, want 2102", status)
+	}
+	if _, ok := set.Default.TurvoStatus("pending"); !ok {
+		t.Error("default profile lost the pending alias")
+	}
+}
+
+func TestParseRejectsInvalidStatuses(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses string
+		want     string
+	}{
+		{name: "missing key", statuses: `- { api: on_dock, value: "On dock" }`, want: "statuses[39].key is required"},
+		{name: "uppercase API status", statuses: `- { api: On-Dock, key: "3150", value: "On dock" }`, want: `statuses[39].api "On-Dock" must be lowercase with underscores`},
+		{name: "alias of another status", statuses: `- { api: on_dock, key: "3150", value: "On dock", aliases: [pending] }`, want: `statuses[39]: "pending" is already used by statuses[20]`},
+		{name: "key of another status", statuses: `- { api: on_dock, key: "2102", value: "On dock" }`, want: `statuses[39]: key "2102" is already used by statuses[2]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte("default:\n  statuses:\n    " + test.statuses + "\n"))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package mapping
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StatusMapping maps an API status to a Turvo status code. Aliases are extra API values
+// accepted on input (e.g. "pending" for draft); they are never returned.
+type StatusMapping struct {
+	API     string   `yaml:"api" json:"api"`
+	Key     string   `yaml:"key" json:"key"`
+	Value   string   `yaml:"value" json:"value"`
+	Aliases []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+}
+
+// DefaultStatuses returns Turvo's standard shipment status codes
+func DefaultStatuses() []StatusMapping {
+	return []StatusMapping{
+		{API: "quote_active", Key: "2100", Value: "Quote active"},
+		{API: "tendered", Key: "2101", Value: "Tendered"},
+		{API: "covered", Key: "2102", Value: "Covered"},
+		{API: "dispatched", Key: "2103", Value: "Dispatched"},
+		{API: "at_pickup", Key: "2104", Value: "At pickup"},
+		{API: "en_route", Key: "2105", Value: "En route"},
+		{API: "at_delivery", Key: "2106", Value: "At delivery"},
+		{API: "delivered", Key: "2107", Value: "Delivered"},
+		{API: "ready_for_billing", Key: "2108", Value: "Ready for billing"},
+		{API: "processing", Key: "2109", Value: "Processing"},
+		{API: "carrier_paid", Key: "2110", Value: "Carrier paid"},
+		{API: "customer_paid", Key: "2111", Value: "Customer paid"},
+		{API: "completed", Key: "2112", Value: "Completed"},
+		{API: "canceled", Key: "2113", Value: "Canceled"},
+		{API: "quote_inactive", Key: "2114", Value: "Quote inactive"},
+		{API: "picked_up", Key: "2115", Value: "Picked up"},
+		{API: "route_complete", Key: "2116", Value: "Route Complete"},
+		{API: "tender_offered", Key: "2117", Value: "Tender - offered"},
+		{API: "tender_accepted", Key: "2118", Value: "Tender - accepted"},
+		{API: "tender_rejected", Key: "2119", Value: "Tender - rejected"},
+		{API: "draft", Key: "2120", Value: "Draft", Aliases: []string{"pending"}},
+		{API: "shipment_ready", Key: "2121", Value: "Shipment Ready"},
+		{API: "acquiring_location", Key: "2123", Value: "Acquiring Location"},
+		{API: "customs_hold", Key: "2124", Value: "Customs Hold"},
+		{API: "arrived", Key: "2125", Value: "Arrived"},
+		{API: "available", Key: "2126", Value: "Available"},
+		{API: "out_gated", Key: "2127", Value: "Out Gated"},
+		{API: "in_gated", Key: "2129", Value: "In Gated"},
+		{API: "arriving_to_port", Key: "2131", Value: "Arriving to Port"},
+		{API: "berthing", Key: "2132", Value: "Berthing"},
+		{API: "unloading", Key: "2133", Value: "Unloading"},
+		{API: "ramped", Key: "2134", Value: "Ramped"},
+		{API: "deramped", Key: "2135", Value: "Deramped"},
+		{API: "departed", Key: "2136", Value: "Departed"},
+		{API: "held", Key: "2137", Value: "Held"},
+		{API: "out_for_delivery", Key: "2138", Value: "Out for Delivery"},
+		{API: "in_transshipment", Key: "2139", Value: "In TransShipment"},
+		{API: "on_hold", Key: "2140", Value: "On Hold"},
+		{API: "interline", Key: "2141", Value: "Interline"},
+	}
+}
+
+// TurvoStatus finds the Turvo status for an API status. Besides the API value it accepts
+// aliases, the Turvo display value ("Tender - offered") and spaced or dashed spellings
+// ("en route"). Unknown statuses are reported with ok false.
+func (p *Profile) TurvoStatus(apiStatus string) (StatusMapping, bool) {
+	normalized := NormalizeStatus(apiStatus)
+	if normalized == "" {
+		return StatusMapping{}, false
+	}
+
+	for _, status := range p.Statuses {
+		if status.API == normalized || strings.EqualFold(status.Value, strings.TrimSpace(apiStatus)) {
+			return status, true
+		}
+		for _, alias := range status.Aliases {
+			if alias == normalized {
+				return status, true
+			}
+		}
+	}
+	return StatusMapping{}, false
+}
+
+// APIStatus maps a Turvo status code to its API status. Codes missing from the table
+// are returned as the normalized Turvo value, so new Turvo statuses still show up.
+func (p *Profile) APIStatus(key, value string) string {
+	for _, status := range p.Statuses {
+		if status.Key == key {
+			return status.API
+		}
+	}
+	return NormalizeStatus(value)
+}
+
+// NormalizeStatus lowercases a status and turns spaces and dashes into underscores ("En route" becomes "en_route")
+func NormalizeStatus(status string) string {
+	normalized := strings.ToLower(strings.TrimSpace(status))
+	normalized = strings.ReplaceAll(normalized, " ", "_")
+	normalized = strings.ReplaceAll(normalized, "-", "_")
+	return normalized
+}
+
+// mergeStatuses applies overrides to a status table: an override replaces the entry with the
+// same API status and is appended otherwise
+func mergeStatuses(base, overrides []StatusMapping) []StatusMapping {
+	merged := append([]StatusMapping(nil), base...)
+	for _, override := range overrides {
+		replaced := false
+		for i := range merged {
+			if merged[i].API == override.API {
+				merged[i] = override
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, override)
+		}
+	}
+	return merged
+}
+
+// validateStatuses checks that every status is complete and that API values, aliases and
+// Turvo keys are unique
+func validateStatuses(statuses []StatusMapping) []error {
+	var errs []error
+	if len(statuses) == 0 {
+		return []error{fmt.Errorf("statuses must not be empty")}
+	}
+
+	apiValues := make(map[string]int)
+	keys := make(map[string]int)
+	for i, status := range statuses {
+		if status.API == "" {
+			errs = append(errs, fmt.Errorf("statuses[%d].api is required", i))
+		} else if status.API != NormalizeStatus(status.API) {
+			errs = append(errs, fmt.Errorf("statuses[%d].api %q must be lowercase with underscores", i, status.API))
+		}
+		if status.Key == "" {
+			errs = append(errs, fmt.Errorf("statuses[%d].key is required", i))
+		}
+		if status.Value == "" {
+			errs = append(errs, fmt.Errorf("statuses[%d].value is required", i))
+		}
+
+		for _, value := range append([]string{status.API}, status.Aliases...) {
+			if value == "" {
+				continue
+			}
+			if other, found := apiValues[value]; found {
+				errs = append(errs, fmt.Errorf("statuses[%d]: %q is already used by statuses[%d]", i, value, other))
+			}
+			apiValues[value] = i
+		}
+		if status.Key != "" {
+			if other, found := keys[status.Key]; found {
+				errs = append(errs, fmt.Errorf("statuses[%d]: key %q is already used by statuses[%d]", i, status.Key, other))
+			}
+			keys[status.Key] = i
+		}
+	}
+	return errs
+}
@@ -0,0 +1,119 @@
+// Package metrics holds the Prometheus metrics of the service and serves them for scraping.
+//
+// Metrics are registered with a registry of our own rather than the global default one, so
+// only our metrics and the Go runtime and process metrics are exposed.
+package metrics
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var registry = prometheus.NewRegistry()
+
+// Our REST API
+var (
+	// HTTPRequests counts requests by route pattern (e.g. /api/v1/loads/{id}), method and status
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time to serve HTTP requests, by route, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+)
+
+// The Turvo client. Endpoints are the request names used in the client's logs (e.g. "list shipments").
+var (
+	// TurvoRequests counts every attempt sent to Turvo; status is the HTTP status code or
+	// "error" when no response was received
+	TurvoRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "turvo_requests_total",
+		Help: "Requests sent to Turvo, retries included, by endpoint and status code.",
+	}, []string{"endpoint", "status"})
+
+	TurvoRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "turvo_request_duration_seconds",
+		Help:    "Latency of single requests to Turvo, by endpoint.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"endpoint"})
+
+	TurvoRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "turvo_retries_total",
+		Help: "Requests to Turvo retried after a transient failure, by endpoint.",
+	}, []string{"endpoint"})
+
+	TurvoReauths = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "turvo_reauthentications_total",
+		Help: "Requests to Turvo answered with 401 and sent again with a new token, by endpoint.",
+	}, []string{"endpoint"})
+
+	// TurvoTokenRefreshes counts token requests; result is "success" or "failure"
+	TurvoTokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "turvo_token_refreshes_total",
+		Help: "Access tokens requested from Turvo, by result.",
+	}, []string{"result"})
+)
+
+// Loads
+var (
+	LoadsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "loads_created_total",
+		Help: "Loads created in Turvo.",
+	})
+
+	// ValidationFailures counts rejected loads per invalid field; a load with several invalid
+	// fields counts once for each
+	ValidationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "load_validation_failures_total",
+		Help: "Loads rejected by validation, by field.",
+	}, []string{"field"})
+
+	DetailFallbacks = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "load_detail_fallbacks_total",
+		Help: "Loads listed with the basic shipment data because their details could not be fetched.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		TurvoRequests,
+		TurvoRequestDuration,
+		TurvoRetries,
+		TurvoReauths,
+		TurvoTokenRefreshes,
+		LoadsCreated,
+		ValidationFailures,
+		DetailFallbacks,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// indexPattern matches list indexes in field paths such as customerOrder[0].customer.id
+var indexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// RecordValidationFailures counts the fields named by validation problems. A problem starts
+// with the field path ("lane.start is required"); list indexes are dropped so the number of
+// label values stays bounded.
+func RecordValidationFailures(problems []string) {
+	for _, problem := range problems {
+		field, _, _ := strings.Cut(problem, " ")
+		ValidationFailures.WithLabelValues(indexPattern.ReplaceAllString(field, "[]")).Inc()
+	}
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecordValidationFailures(t *testing.T) {
+	fields := []string{"lane.start", "customerOrder[].customer.id", "status"}
+	before := make([]float64, len(fields))
+	for i, field := range fields {
+		before[i] = testutil.ToFloat64(ValidationFailures.WithLabelValues(field))
+	}
+
+	RecordValidationFailures([]string{
+		"lane.start is required",
+		"customerOrder[0].customer.id is required",
+		"customerOrder[12].customer.id is required",
+		`status "teleported" is not a supported status`,
+	})
+
+	want := []float64{1, 2, 1}
+	for i, field := range fields {
+		if got := testutil.ToFloat64(ValidationFailures.WithLabelValues(field)) - before[i]; got != want[i] {
+			t.Errorf("failures of %s = %v, want %v", field, got, want[i])
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	// Label values only show up once they are used
+	HTTPRequests.WithLabelValues("/api/v1/loads", "GET", "200").Add(0)
+	TurvoRequests.WithLabelValues("list shipments", "200").Add(0)
+	HTTPRequestDuration.WithLabelValues("/api/v1/loads", "GET", "200")
+	TurvoRequestDuration.WithLabelValues("list shipments")
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d", recorder.Code)
+	}
+	body, _ := io.ReadAll(recorder.Body)
+	exposed := string(body)
+
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/api/v1/loads",status="200"}`,
+		`turvo_requests_total{endpoint="list shipments",status="200"}`,
+		"# TYPE http_request_duration_seconds histogram",
+		"# TYPE turvo_request_duration_seconds histogram",
+		"# TYPE loads_created_total counter",
+		"# TYPE load_detail_fallbacks_total counter",
+		"go_goroutines",
+		"process_cpu_seconds_total",
+	} {
+		if !strings.Contains(exposed, want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+	// Only our registry is served, not the global default one
+	if strings.Contains(exposed, "promhttp_metric_handler_requests_total") {
+		t.Error("metrics of the default registry exposed")
+	}
+}
@@ -0,0 +1,241 @@
+// Package middleware holds HTTP middleware shared by all routes.
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures which browser origins may call the API
+type CORSConfig struct {
+	// AllowedOrigins are exact origins ("https://app.example.com"), origins with a wildcard
+	// subdomain ("https://*.example.com", which does not match "https://example.com") or "*"
+	// for any origin. Empty rejects every cross-origin request.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders are response headers browser code may read
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and Authorization headers. It cannot be
+	// combined with the "*" origin.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// CORS applies a CORS policy. Requests from origins that are not allowed are rejected with 403,
+// so cross-origin calls fail before reaching a handler instead of only being hidden from the
+// calling script.
+type CORS struct {
+	cfg       CORSConfig
+	anyOrigin bool
+	origins   []originPattern
+	methods   map[string]bool
+	headers   map[string]bool
+	maxAge    string
+}
+
+type originPattern struct {
+	scheme   string
+	host     string // Without the "*." of a wildcard pattern
+	port     string
+	wildcard bool
+}
+
+func NewCORS(cfg CORSConfig) (*CORS, error) {
+	c := &CORS{
+		cfg:     cfg,
+		methods: make(map[string]bool),
+		headers: make(map[string]bool),
+	}
+
+	var errs []error
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			c.anyOrigin = true
+			continue
+		}
+		pattern, err := parseOriginPattern(origin)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.origins = append(c.origins, pattern)
+	}
+	if c.anyOrigin && cfg.AllowCredentials {
+		errs = append(errs, errors.New(`allowed origin "*" cannot be combined with credentials, list the origins instead`))
+	}
+	if cfg.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("max age must not be negative, got %s", cfg.MaxAge))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	for _, method := range cfg.AllowedMethods {
+		c.methods[strings.ToUpper(method)] = true
+	}
+	for _, header := range cfg.AllowedHeaders {
+		c.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	if cfg.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+	return c, nil
+}
+
+// Handler applies the policy to next
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		// The response depends on the origin unless every origin gets the same "*"
+		if !c.anyOrigin || c.cfg.AllowCredentials {
+			w.Header().Add("Vary", "Origin")
+		}
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		// Not a browser cross-origin request
+		if origin == "" || (!preflight && sameOrigin(origin, r)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !c.allowOrigin(origin) {
+			http.Error(w, "origin not allowed: "+origin, http.StatusForbidden)
+			return
+		}
+
+		if preflight {
+			c.preflight(w, r, origin)
+			return
+		}
+
+		c.setOrigin(w, origin)
+		if len(c.cfg.ExposedHeaders) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(c.cfg.ExposedHeaders, ", "))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflight answers an OPTIONS preflight request, rejecting methods and headers that are not allowed
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if !c.methods[method] {
+		http.Error(w, "method not allowed: "+method, http.StatusForbidden)
+		return
+	}
+
+	var requested []string
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		if header == "" {
+			continue
+		}
+		if !c.headers[header] {
+			http.Error(w, "header not allowed: "+header, http.StatusForbidden)
+			return
+		}
+		requested = append(requested, header)
+	}
+
+	c.setOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.cfg.AllowedMethods, ", "))
+	if len(requested) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if c.maxAge != "" {
+		w.Header().Set("Access-Control-Max-Age", c.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *CORS) setOrigin(w http.ResponseWriter, origin string) {
+	if c.anyOrigin && !c.cfg.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if c.cfg.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *CORS) allowOrigin(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := originPort(scheme, u.Port())
+	for _, pattern := range c.origins {
+		if pattern.scheme != scheme || pattern.port != port {
+			continue
+		}
+		if pattern.wildcard {
+			if strings.HasSuffix(host, "."+pattern.host) {
+				return true
+			}
+		} else if host == pattern.host {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOriginPattern parses "scheme://host[:port]" where host may start with "*."
+func parseOriginPattern(origin string) (originPattern, error) {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		return originPattern{}, fmt.Errorf("invalid allowed origin %q: expected scheme://host[:port]", origin)
+	}
+
+	pattern := originPattern{
+		scheme: strings.ToLower(u.Scheme),
+		host:   strings.ToLower(u.Hostname()),
+	}
+	pattern.port = originPort(pattern.scheme, u.Port())
+	if strings.HasPrefix(pattern.host, "*.") {
+		pattern.wildcard = true
+		pattern.host = strings.TrimPrefix(pattern.host, "*.")
+	}
+	if pattern.host == "" || strings.Contains(pattern.host, "*") {
+		return originPattern{}, fmt.Errorf("invalid allowed origin %q: a wildcard is only allowed as the first label (https://*.example.com)", origin)
+	}
+	return pattern, nil
+}
+
+// originPort returns the port of an origin, filling in the scheme's default
+func originPort(scheme, port string) string {
+	if port != "" {
+		return port
+	}
+	switch scheme {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// sameOrigin reports whether origin is the host the request was sent to, which browsers
+// also send on same-origin POST requests
+func sameOrigin(origin string, r *http.Request) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
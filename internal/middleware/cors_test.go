@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCORS returns the policy applied to a handler answering 200
+func newTestCORS(t *testing.T, cfg CORSConfig) http.Handler {
+	t.Helper()
+	cors, err := NewCORS(cfg)
+	if err != nil {
+		t.Fatalf("NewCORS: %v", err)
+	}
+	return cors.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-1")
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+// corsRequest sends a request from origin; a preflight for method if preflight is set
+func corsRequest(handler http.Handler, origin, method string, preflight bool, headers string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, "http://api.example.com/api/v1/loads", nil)
+	if preflight {
+		request = httptest.NewRequest(http.MethodOptions, "http://api.example.com/api/v1/loads", nil)
+		request.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			request.Header.Set("Access-Control-Request-Headers", headers)
+		}
+	}
+	if origin != "" {
+		request.Header.Set("Origin", origin)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+var testCORSConfig = CORSConfig{
+	AllowedOrigins: []string{"https://app.example.com", "https://*.acme.example", "http://localhost:3000"},
+	AllowedMethods: []string{"GET", "POST"},
+	AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+	ExposedHeaders: []string{"X-Request-ID"},
+	MaxAge:         10 * time.Minute,
+}
+
+func TestCORSOrigins(t *testing.T) {
+	handler := newTestCORS(t, testCORSConfig)
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "exact origin", origin: "https://app.example.com", allowed: true},
+		{name: "exact origin with the default port", origin: "https://app.example.com:443", allowed: true},
+		{name: "exact origin in another case", origin: "https://APP.example.com", allowed: true},
+		{name: "other scheme", origin: "http://app.example.com"},
+		{name: "other port", origin: "https://app.example.com:8443"},
+		{name: "other host", origin: "https://evil.example.com"},
+		{name: "suffix of an exact origin", origin: "https://app.example.com.evil.example"},
+		{name: "wildcard subdomain", origin: "https://tms.acme.example", allowed: true},
+		{name: "nested wildcard subdomain", origin: "https://eu.tms.acme.example", allowed: true},
+		{name: "wildcard without a subdomain", origin: "https://acme.example"},
+		{name: "wildcard lookalike", origin: "https://notacme.example"},
+		{name: "localhost with its port", origin: "http://localhost:3000", allowed: true},
+		{name: "localhost on another port", origin: "http://localhost:3001"},
+		{name: "null origin", origin: "null"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := corsRequest(handler, test.origin, http.MethodGet, false, "")
+			if !test.allowed {
+				if recorder.Code != http.StatusForbidden {
+					t.Errorf("status = %d, want 403", recorder.Code)
+				}
+				if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "" {
+					t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+				}
+				return
+			}
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != test.origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, test.origin)
+			}
+			if got := recorder.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+				t.Errorf("Access-Control-Expose-Headers = %q, want X-Request-ID", got)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+				t.Errorf("Access-Control-Allow-Credentials = %q without credentials", got)
+			}
+			if got := recorder.Header().Values("Vary"); !strings.Contains(strings.Join(got, ","), "Origin") {
+				t.Errorf("Vary = %q, want Origin", got)
+			}
+		})
+	}
+}
+
+func TestCORSWithoutOrigin(t *testing.T) {
+	handler := newTestCORS(t, CORSConfig{})
+	tests := []struct {
+		name   string
+		origin string
+	}{
+		{name: "no origin"},
+		{name: "same origin", origin: "http://api.example.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := corsRequest(handler, test.origin, http.MethodPost, false, "")
+			if recorder.Code != http.StatusOK {
+				t.Errorf("status = %d, want 200 without a CORS policy check", recorder.Code)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+			}
+		})
+	}
+
+	// No allowed origins reject every cross-origin request
+	if got := corsRequest(handler, "https://app.example.com", http.MethodGet, false, "").Code; got != http.StatusForbidden {
+		t.Errorf("cross-origin status = %d, want 403", got)
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	handler := newTestCORS(t, CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}})
+	recorder := corsRequest(handler, "https://anywhere.example", http.MethodGet, false, "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+	if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := recorder.Header().Values("Vary"); len(got) != 0 {
+		t.Errorf("Vary = %q, want none for the same * answer", got)
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	cfg := testCORSConfig
+	cfg.AllowCredentials = true
+	handler := newTestCORS(t, cfg)
+
+	for _, preflight := range []bool{false, true} {
+		recorder := corsRequest(handler, "https://tms.acme.example", http.MethodGet, preflight, "")
+		if got := recorder.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+			t.Errorf("preflight %t: Access-Control-Allow-Credentials = %q, want true", preflight, got)
+		}
+		if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "https://tms.acme.example" {
+			t.Errorf("preflight %t: Access-Control-Allow-Origin = %q, want the origin", preflight, got)
+		}
+	}
+
+	if _, err := NewCORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}); err == nil {
+		t.Error(`NewCORS accepted the "*" origin with credentials`)
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := newTestCORS(t, testCORSConfig)
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+		status  int
+		allowed string // Access-Control-Allow-Headers
+	}{
+		{name: "allowed method", origin: "https://app.example.com", method: "GET", status: http.StatusNoContent},
+		{name: "allowed method in lowercase", origin: "https://app.example.com", method: "post", status: http.StatusNoContent},
+		{name: "allowed headers", origin: "https://app.example.com", method: "POST", headers: "content-type, x-api-key", status: http.StatusNoContent, allowed: "Content-Type, X-Api-Key"},
+		{name: "method not allowed", origin: "https://app.example.com", method: "DELETE", status: http.StatusForbidden},
+		{name: "header not allowed", origin: "https://app.example.com", method: "GET", headers: "Content-Type, X-Debug", status: http.StatusForbidden},
+		{name: "origin not allowed", origin: "https://evil.example.com", method: "GET", status: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := corsRequest(handler, test.origin, test.method, true, test.headers)
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if test.status != http.StatusNoContent {
+				if got := recorder.Header().Get("Access-Control-Allow-Methods"); got != "" {
+					t.Errorf("Access-Control-Allow-Methods = %q on a rejected preflight", got)
+				}
+				return
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+				t.Errorf("Access-Control-Allow-Methods = %q, want GET, POST", got)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Headers"); got != test.allowed {
+				t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, test.allowed)
+			}
+			if got := recorder.Header().Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+			}
+			// Preflights are answered here, not by the handler
+			if got := recorder.Header().Get("X-Request-ID"); got != "" {
+				t.Error("preflight reached the handler")
+			}
+		})
+	}
+
+	// Without a max age, browsers use their default
+	cfg := testCORSConfig
+	cfg.MaxAge = 0
+	if got := corsRequest(newTestCORS(t, cfg), "https://app.example.com", "GET", true, "").Header().Get("Access-Control-Max-Age"); got != "" {
+		t.Errorf("Access-Control-Max-Age = %q without a max age", got)
+	}
+}
+
+func TestNewCORSRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  CORSConfig
+		want string
+	}{
+		{name: "origin without a scheme", cfg: CORSConfig{AllowedOrigins: []string{"app.example.com"}}, want: "expected scheme://host[:port]"},
+		{name: "origin with a path", cfg: CORSConfig{AllowedOrigins: []string{"https://app.example.com/app"}}, want: "expected scheme://host[:port]"},
+		{name: "wildcard inside a host", cfg: CORSConfig{AllowedOrigins: []string{"https://app.*.example.com"}}, want: "a wildcard is only allowed as the first label"},
+		{name: "bare wildcard host", cfg: CORSConfig{AllowedOrigins: []string{"https://*"}}, want: "a wildcard is only allowed as the first label"},
+		{name: "negative max age", cfg: CORSConfig{MaxAge: -time.Second}, want: "max age must not be negative"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewCORS(test.cfg)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/lwlach/turvo-integration-backend/internal/metrics"
+)
+
+// Metrics records the count and latency of requests by route pattern, method and status.
+// Requests that match no route are recorded as "unmatched", so unknown paths do not create
+// new label values.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := routePattern(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := []string{route, r.Method, strconv.Itoa(status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routePattern returns the pattern of the route that served r, or "" if it matched none. The
+// pattern is complete once the router has matched the request. Paths that match no route of a
+// subrouter end with its "/*" mount pattern, e.g. /api/v1/*.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || strings.HasSuffix(rctx.RoutePattern(), "/*") {
+		return ""
+	}
+	return rctx.RoutePattern()
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lwlach/turvo-integration-backend/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// histogramCount returns the number of observations of a histogram
+func histogramCount(t *testing.T, histogram *prometheus.HistogramVec, labels ...string) (uint64, float64) {
+	t.Helper()
+	var metric dto.Metric
+	if err := histogram.WithLabelValues(labels...).(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
+
+func TestMetrics(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Metrics)
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Get("/loads/{id}", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+			w.Write([]byte("{}"))
+		})
+		r.Post("/loads", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "invalid load", http.StatusBadRequest)
+		})
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		labels  []string // Route, method and status recorded
+		latency time.Duration
+	}{
+		{name: "route pattern, not the path", method: http.MethodGet, target: "/api/v1/loads/1000000001", labels: []string{"/api/v1/loads/{id}", "GET", "200"}, latency: 20 * time.Millisecond},
+		{name: "status written by the handler", method: http.MethodPost, target: "/api/v1/loads", labels: []string{"/api/v1/loads", "POST", "400"}},
+		{name: "unknown path", method: http.MethodGet, target: "/nothing", labels: []string{"unmatched", "GET", "404"}},
+		{name: "unknown path of a subrouter", method: http.MethodGet, target: "/api/v1/nothing/here", labels: []string{"unmatched", "GET", "404"}},
+		{name: "method not allowed", method: http.MethodDelete, target: "/api/v1/loads", labels: []string{"unmatched", "DELETE", "405"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(test.labels...))
+			observations, seconds := histogramCount(t, metrics.HTTPRequestDuration, test.labels...)
+
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(test.method, test.target, nil))
+
+			if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(test.labels...)) - requests; got != 1 {
+				t.Errorf("requests %v = %v, want 1", test.labels, got)
+			}
+			count, sum := histogramCount(t, metrics.HTTPRequestDuration, test.labels...)
+			if count-observations != 1 {
+				t.Errorf("latency observations %v = %d, want 1", test.labels, count-observations)
+			}
+			if latency := sum - seconds; latency < test.latency.Seconds() {
+				t.Errorf("latency = %vs, want at least %s", latency, test.latency)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader returns the request ID (see chi's middleware.RequestID) to the caller, so
+// clients can quote it when reporting problems
+func RequestIDHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestID := chimiddleware.GetReqID(r.Context()); requestID != "" {
+			w.Header().Set(chimiddleware.RequestIDHeader, requestID)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
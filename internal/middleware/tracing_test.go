@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider recording every span and the W3C propagator, and
+// restores the global ones after the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return recorder
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracing(t *testing.T) {
+	recorder := recordSpans(t)
+	var handlerSpan trace.SpanContext
+	r := chi.NewRouter()
+	r.Use(Tracing)
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Get("/loads/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlerSpan = trace.SpanContextFromContext(r.Context())
+		})
+		r.Post("/loads", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "turvo unavailable", http.StatusInternalServerError)
+		})
+	})
+
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		traceparent string
+		span        string // Span name
+		route       string // http.route, empty if none
+		status      int
+		error       bool
+	}{
+		{name: "route pattern", method: http.MethodGet, target: "/api/v1/loads/1000000001", span: "GET /api/v1/loads/{id}", route: "/api/v1/loads/{id}", status: http.StatusOK},
+		{name: "caller's trace continued", method: http.MethodGet, target: "/api/v1/loads/1000000001", traceparent: "00-" + traceID + "-" + parentID + "-01",
+			span: "GET /api/v1/loads/{id}", route: "/api/v1/loads/{id}", status: http.StatusOK},
+		{name: "invalid traceparent ignored", method: http.MethodGet, target: "/api/v1/loads/1000000001", traceparent: "00-not-a-trace-01", span: "GET /api/v1/loads/{id}", route: "/api/v1/loads/{id}", status: http.StatusOK},
+		{name: "server error", method: http.MethodPost, target: "/api/v1/loads", span: "POST /api/v1/loads", route: "/api/v1/loads", status: http.StatusInternalServerError, error: true},
+		{name: "unknown path", method: http.MethodGet, target: "/api/v1/nothing", span: "GET", status: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.target, nil)
+			if test.traceparent != "" {
+				request.Header.Set("traceparent", test.traceparent)
+			}
+			handlerSpan = trace.SpanContext{}
+			before := len(recorder.Ended())
+			r.ServeHTTP(httptest.NewRecorder(), request)
+
+			ended := recorder.Ended()
+			if len(ended) != before+1 {
+				t.Fatalf("%d spans ended, want 1", len(ended)-before)
+			}
+			span := ended[len(ended)-1]
+			if span.Name() != test.span || span.SpanKind() != trace.SpanKindServer {
+				t.Errorf("span = %s %s, want server span %s", span.SpanKind(), span.Name(), test.span)
+			}
+			if route, _ := attributeValue(span, "http.route"); route.AsString() != test.route {
+				t.Errorf("http.route = %q, want %q", route.AsString(), test.route)
+			}
+			if status, _ := attributeValue(span, "http.response.status_code"); status.AsInt64() != int64(test.status) {
+				t.Errorf("status code = %d, want %d", status.AsInt64(), test.status)
+			}
+			if got := span.Status().Code == codes.Error; got != test.error {
+				t.Errorf("status = %+v, want error %t", span.Status(), test.error)
+			}
+
+			// The trace and parent come from a valid traceparent only
+			continued := test.name == "caller's trace continued"
+			if got := span.SpanContext().TraceID().String() == traceID; got != continued {
+				t.Errorf("trace ID = %s, continued = %t, want %t", span.SpanContext().TraceID(), got, continued)
+			}
+			if continued && (span.Parent().SpanID().String() != parentID || !span.Parent().IsRemote()) {
+				t.Errorf("parent = %s, want the caller's span %s", span.Parent().SpanID(), parentID)
+			}
+			if !continued && span.Parent().IsValid() {
+				t.Errorf("parent = %s, want a new trace", span.Parent().SpanID())
+			}
+			if test.method == http.MethodGet && test.status == http.StatusOK && handlerSpan.SpanID() != span.SpanContext().SpanID() {
+				t.Errorf("handler ran in span %s, want %s", handlerSpan.SpanID(), span.SpanContext().SpanID())
+			}
+		})
+	}
+}
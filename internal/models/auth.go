@@ -1,5 +1,13 @@
 package models
 
+// TurvoCredentials are the credentials of a Turvo account
+type TurvoCredentials struct {
+	ClientName   string `json:"clientName"`
+	ClientSecret string `json:"clientSecret"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+}
+
 // TurvoAuthRequest represents the request body for Turvo authentication
 type TurvoAuthRequest struct {
 	GrantType    string `json:"grant_type"`
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Readiness statuses
+const (
+	ReadinessReady    = "ready"     // Every tenant can reach Turvo
+	ReadinessDegraded = "degraded"  // Some tenants cannot reach Turvo
+	ReadinessNotReady = "not_ready" // No tenant can reach Turvo
+)
+
+// ReadinessResponse represents the response of GET /ready
+type ReadinessResponse struct {
+	Status    string            `json:"status"`
+	CheckedAt time.Time         `json:"checkedAt"`
+	Tenants   []TenantReadiness `json:"tenants"`
+}
+
+// TenantReadiness describes whether a tenant can reach Turvo
+type TenantReadiness struct {
+	ID                  string     `json:"id"`
+	Status              string     `json:"status"` // ReadinessReady or ReadinessNotReady
+	Error               string     `json:"error,omitempty"`
+	TokenExpiresAt      *time.Time `json:"tokenExpiresAt,omitempty"`
+	LastTurvoResponseAt *time.Time `json:"lastTurvoResponseAt,omitempty"`
+	LastEventAt         *time.Time `json:"lastEventAt,omitempty"`    // Last Turvo webhook event applied
+	SyncLagSeconds      *float64   `json:"syncLagSeconds,omitempty"` // Delay between the last event occurring in Turvo and us applying it
+}
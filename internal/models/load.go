@@ -166,19 +166,59 @@ type Specifications struct {
 
 // LoadFilters represents filter parameters for listing loads
 type LoadFilters struct {
-	Status         string
-	CustomerID     string
-	PickupDateFrom *time.Time
-	PickupDateTo   *time.Time
+	Statuses         []string // Any of these statuses matches
+	CustomerID       string
+	CarrierID        string
+	FreightLoadID    string
+	PickupDateFrom   *time.Time
+	PickupDateTo     *time.Time
+	DeliveryDateFrom *time.Time
+	DeliveryDateTo   *time.Time
+	CreatedFrom      *time.Time
+	CreatedTo        *time.Time
+	UpdatedFrom      *time.Time
+	UpdatedTo        *time.Time
+	// Filters Turvo cannot apply; they are matched against shipment details on our side
+	OriginCity       string
+	OriginState      string
+	DestinationCity  string
+	DestinationState string
+	PONumber         string // A PO number or customer reference number
+
+	Sort           []LoadSort // Sort keys, most significant first; Turvo's order if empty
 	Page           int
 	Limit          int
-	IncludeDetails bool // If true, fetch detailed shipment information for each load
+	IncludeDetails bool     // If true, fetch detailed shipment information for each load
+	Expand         []string // Top-level sections (e.g. "carrier") filled from shipment details without IncludeDetails
+	Fields         []string // JSON paths (e.g. "pickup.apptTime") the caller wants; sections they need are expanded
+	BypassCache    bool     // If true, fetch shipment details from Turvo even if they are cached
+}
+
+// LoadSort is a sort key of a load list
+type LoadSort struct {
+	Field      string // See the Sort* constants of the load service
+	Descending bool
+}
+
+// Searched reports whether the filters include any that are matched on our side
+func (f LoadFilters) Searched() bool {
+	return f.OriginCity != "" || f.OriginState != "" || f.DestinationCity != "" || f.DestinationState != "" || f.PONumber != ""
 }
 
 // LoadListResponse represents the paginated response for listing loads
 type LoadListResponse struct {
-	Data       []Load     `json:"data"`
-	Pagination Pagination `json:"pagination"`
+	Data            []Load               `json:"data"`
+	Pagination      Pagination           `json:"pagination"`
+	PartialFailures []LoadPartialFailure `json:"partialFailures,omitempty"`
+	// SearchIncomplete is set when filtering or sorting on our side stopped at the scan limit
+	// before Turvo ran out of shipments, so matches may be missing or out of order
+	SearchIncomplete bool `json:"searchIncomplete,omitempty"`
+}
+
+// LoadPartialFailure identifies a load in a list response that was returned without full details
+type LoadPartialFailure struct {
+	ExternalTMSLoadID string `json:"externalTMSLoadID"`
+	Reason            string `json:"reason"`
 }
 
 // Pagination represents pagination metadata
@@ -191,6 +231,32 @@ type Pagination struct {
 
 // LoadCreateResponse represents the response from creating a load
 type LoadCreateResponse struct {
-	ID        string    `json:"id"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID        string         `json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Warnings  []FieldProblem `json:"warnings,omitempty"` // Fields of the request that were ignored
+}
+
+// FieldProblem describes a field of a request body by its JSON path, e.g. consignee.appTime
+type FieldProblem struct {
+	Path    string `json:"path"`
+	Problem string `json:"problem"`
+}
+
+// RequestErrorResponse represents a rejected request body
+type RequestErrorResponse struct {
+	Error  string         `json:"error"`
+	Fields []FieldProblem `json:"fields,omitempty"`
+}
+
+// StatusListResponse represents the response from listing supported statuses
+type StatusListResponse struct {
+	Data []LoadStatus `json:"data"`
+}
+
+// LoadStatus describes a supported load status and the Turvo status code it maps to
+type LoadStatus struct {
+	Status     string   `json:"status"`
+	TurvoCode  string   `json:"turvoCode"`
+	TurvoValue string   `json:"turvoValue"`
+	Aliases    []string `json:"aliases,omitempty"`
 }
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// TenantCreateRequest represents the request body for registering a tenant
+type TenantCreateRequest struct {
+	ID            string           `json:"id"`
+	Name          string           `json:"name"`
+	Credentials   TurvoCredentials `json:"credentials"`
+	WebhookSecret string           `json:"webhookSecret,omitempty"`
+}
+
+// TenantCredentialsRequest represents the request body for rotating a tenant's Turvo credentials
+type TenantCredentialsRequest struct {
+	Credentials TurvoCredentials `json:"credentials"`
+}
+
+// Tenant describes a registered tenant; credentials and API keys are never returned
+type Tenant struct {
+	ID                   string       `json:"id"`
+	Name                 string       `json:"name"`
+	Default              bool         `json:"default"`
+	APIKeys              []APIKeyInfo `json:"apiKeys"`
+	CreatedAt            time.Time    `json:"createdAt"`
+	CredentialsRotatedAt time.Time    `json:"credentialsRotatedAt"`
+}
+
+// APIKeyInfo describes an API key without the key itself
+type APIKeyInfo struct {
+	ID        string    `json:"id"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// APIKeyCreateRequest represents the optional request body for issuing an API key
+type APIKeyCreateRequest struct {
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// TenantListResponse represents the response from listing tenants
+type TenantListResponse struct {
+	Data []Tenant `json:"data"`
+}
+
+// TenantCreateResponse represents the response from registering a tenant.
+// The API key is only shown once.
+type TenantCreateResponse struct {
+	Tenant Tenant `json:"tenant"`
+	APIKey string `json:"apiKey"`
+}
+
+// APIKeyResponse represents a newly issued API key; the key is only shown once
+type APIKeyResponse struct {
+	APIKeyInfo
+	APIKey string `json:"apiKey"`
+}
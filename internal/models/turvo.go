@@ -21,12 +21,22 @@ type TurvoPagination struct {
 
 // TurvoShipmentFilters represents filter parameters for Turvo's list shipments API
 type TurvoShipmentFilters struct {
-	Status        string // Status code (e.g., "2101")
-	CustomerID    string // Customer ID
-	PickupDateGte string // Pickup date greater than or equal (RFC3339 format)
-	PickupDateLte string // Pickup date less than or equal (RFC3339 format)
-	Start         int    // Start index for pagination
-	PageSize      int    // Page size for pagination
+	Statuses        []string // Status codes (e.g., "2101"); any of them matches
+	CustomerID      string   // Customer ID
+	CarrierID       string   // Carrier ID
+	CustomID        string   // Shipment custom ID (our freightLoadID)
+	PickupDateGte   string   // Pickup date greater than or equal (RFC3339 format)
+	PickupDateLte   string   // Pickup date less than or equal (RFC3339 format)
+	DeliveryDateGte string   // Delivery date greater than or equal (RFC3339 format)
+	DeliveryDateLte string   // Delivery date less than or equal (RFC3339 format)
+	CreatedGte      string   // Creation time greater than or equal (RFC3339 format)
+	CreatedLte      string   // Creation time less than or equal (RFC3339 format)
+	UpdatedGte      string   // Last update greater than or equal (RFC3339 format)
+	UpdatedLte      string   // Last update less than or equal (RFC3339 format)
+	SortBy          string   // Field to sort by (e.g., "pickupDate"); Turvo's order if empty
+	SortDescending  bool     // Sort by SortBy in descending order
+	Start           int      // Start index for pagination
+	PageSize        int      // Page size for pagination
 }
 
 // TurvoShipment represents Turvo's shipment model from the list endpoint
@@ -0,0 +1,69 @@
+package models
+
+import "encoding/json"
+
+// Turvo webhook event types we subscribe to
+const (
+	TurvoEventShipmentCreated  = "SHIPMENT_CREATED"
+	TurvoEventShipmentUpdated  = "SHIPMENT_UPDATED"
+	TurvoEventStatusChanged    = "SHIPMENT_STATUS_CHANGED"
+	TurvoEventLocationUpdated  = "SHIPMENT_LOCATION_UPDATED"
+	TurvoEventShipmentDeleted  = "SHIPMENT_DELETED"
+	TurvoEventShipmentCanceled = "SHIPMENT_CANCELED"
+)
+
+// TurvoWebhookEnvelope represents the common envelope of every event Turvo pushes to us.
+// The payload is decoded into a typed event based on EventType.
+type TurvoWebhookEnvelope struct {
+	EventID    string          `json:"eventId"`
+	EventType  string          `json:"eventType"`
+	OccurredAt string          `json:"occurredAt,omitempty"`
+	ShipmentID int             `json:"shipmentId"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+}
+
+// TurvoShipmentEvent is sent when a shipment is created, updated, canceled or deleted.
+// Shipment carries the full shipment in the same format as the GET shipment endpoint.
+type TurvoShipmentEvent struct {
+	Shipment *TurvoShipmentCreateDetails `json:"shipment,omitempty"`
+}
+
+// TurvoStatusEvent is sent when the status of a shipment changes
+type TurvoStatusEvent struct {
+	Status         TurvoCreateStatus `json:"status"`
+	PreviousStatus *TurvoStatusCode  `json:"previousStatus,omitempty"`
+}
+
+// TurvoLocationEvent is sent when Turvo receives a new tracking location for a shipment
+type TurvoLocationEvent struct {
+	Location TurvoEventLocation `json:"location"`
+}
+
+// TurvoEventLocation represents a tracking location reported by Turvo
+type TurvoEventLocation struct {
+	Lat        float64 `json:"lat"`
+	Lon        float64 `json:"lon"`
+	City       string  `json:"city,omitempty"`
+	State      string  `json:"state,omitempty"`
+	Country    string  `json:"country,omitempty"`
+	RecordedAt string  `json:"recordedAt,omitempty"`
+}
+
+// TurvoWebhookEvent is a parsed webhook event. Exactly one of Shipment, Status or Location
+// is set, depending on EventType.
+type TurvoWebhookEvent struct {
+	EventID    string
+	EventType  string
+	OccurredAt string
+	ShipmentID int
+	Shipment   *TurvoShipmentEvent
+	Status     *TurvoStatusEvent
+	Location   *TurvoLocationEvent
+}
+
+// WebhookAckResponse represents our response to a Turvo webhook delivery
+type WebhookAckResponse struct {
+	EventID   string `json:"eventId"`
+	Duplicate bool   `json:"duplicate"`
+	Outdated  bool   `json:"outdated,omitempty"` // Older than an event already applied to the shipment, so not applied
+}
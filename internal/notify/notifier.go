@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// Notification types sent to downstream consumers
+const (
+	TypeLoadUpdated   = "load.updated"
+	TypeStatusChanged = "load.status_changed"
+	TypeLocation      = "load.location"
+	TypeLoadRemoved   = "load.removed"
+)
+
+// Notification represents a change to a load that we tell our own consumers about
+type Notification struct {
+	Type           string                     `json:"type"`
+	TenantID       string                     `json:"tenantId"`
+	EventID        string                     `json:"eventId"`
+	LoadID         string                     `json:"loadId"`
+	Load           *models.Load               `json:"load,omitempty"`
+	Status         string                     `json:"status,omitempty"`
+	PreviousStatus string                     `json:"previousStatus,omitempty"`
+	Location       *models.TurvoEventLocation `json:"location,omitempty"`
+	OccurredAt     time.Time                  `json:"occurredAt"`
+}
+
+// Notifier delivers notifications about load changes
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier writes notifications to the application log
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Notify logs the notification
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	slog.InfoContext(ctx, "notification",
+		"type", notification.Type,
+		"tenant_id", notification.TenantID,
+		"load_id", notification.LoadID,
+		"event_id", notification.EventID,
+		"status", notification.Status,
+	)
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to a configured URL
+type WebhookNotifier struct {
+	url        string
+	httpClient *resty.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	client := resty.New().
+		SetTimeout(10*time.Second).
+		SetHeader("Content-Type", "application/json")
+
+	return &WebhookNotifier{
+		url:        url,
+		httpClient: client,
+	}
+}
+
+// Notify posts the notification to the configured URL
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	resp, err := n.httpClient.R().
+		SetContext(ctx).
+		SetBody(notification).
+		Post(n.url)
+
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("notification endpoint error: %s", resp.Status())
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/tenant"
+)
+
+// Config holds tunables for the readiness check
+type Config struct {
+	StaleAfter time.Duration // Turvo is pinged if it has not answered for this long, and results are reused as long (default: 5 minutes)
+	Timeout    time.Duration // Tenants whose check takes longer are reported not ready (default: 5 seconds)
+}
+
+// Service checks whether the tenants can reach Turvo
+type Service struct {
+	tenants *tenant.Registry
+	cfg     Config
+
+	// Probes reuse the last result while it is fresh and share a running check, so they do
+	// not call Turvo each time or wait in line
+	mutex     sync.Mutex
+	last      models.ReadinessResponse
+	lastValid bool
+	running   chan struct{} // Closed when the running check finishes; nil if none runs
+}
+
+func NewService(tenants *tenant.Registry, cfg Config) *Service {
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = 5 * time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	return &Service{
+		tenants: tenants,
+		cfg:     cfg,
+	}
+}
+
+// Readiness checks every tenant. A tenant is ready if its client holds or can get a valid token
+// and Turvo answered within StaleAfter (pinging Turvo if not). The service is degraded rather
+// than not ready while at least one tenant is ready, so one tenant's bad credentials do not
+// take the API down for the others, and not ready without tenants.
+//
+// A result is reused for StaleAfter, and calls made while a check runs get its result. The
+// response is shared between callers and must not be modified.
+func (s *Service) Readiness(ctx context.Context) models.ReadinessResponse {
+	s.mutex.Lock()
+	if s.lastValid && time.Since(s.last.CheckedAt) < s.cfg.StaleAfter {
+		defer s.mutex.Unlock()
+		return s.last
+	}
+	if running := s.running; running != nil {
+		s.mutex.Unlock()
+		<-running // Bounded by the check timeout
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		return s.last
+	}
+	running := make(chan struct{})
+	s.running = running
+	s.mutex.Unlock()
+
+	// The result is shared, so the check does not stop when this caller goes away
+	response := s.check(context.WithoutCancel(ctx))
+
+	s.mutex.Lock()
+	s.last, s.lastValid = response, true
+	s.running = nil
+	close(running)
+	s.mutex.Unlock()
+	return response
+}
+
+// check checks every tenant, giving up on those not done within the timeout
+func (s *Service) check(ctx context.Context) models.ReadinessResponse {
+	// Checks still running after the timeout are cancelled when we return
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tenants := s.tenants.List()
+	results := make([]models.TenantReadiness, len(tenants))
+	done := make(chan int, len(tenants))
+	for i, t := range tenants {
+		go func() {
+			results[i] = s.checkTenant(ctx, t)
+			done <- i
+		}()
+	}
+
+	finished := make([]bool, len(tenants))
+	timeout := time.After(s.cfg.Timeout)
+wait:
+	for range tenants {
+		select {
+		case i := <-done:
+			finished[i] = true
+		case <-timeout:
+			break wait
+		}
+	}
+
+	response := models.ReadinessResponse{
+		CheckedAt: time.Now().UTC(),
+		Tenants:   make([]models.TenantReadiness, len(tenants)),
+	}
+	ready := 0
+	for i, t := range tenants {
+		result := models.TenantReadiness{
+			ID:     t.ID,
+			Status: models.ReadinessNotReady,
+			Error:  "check timed out",
+		}
+		if finished[i] {
+			result = results[i]
+		} else {
+			slog.WarnContext(ctx, "readiness check timed out", "tenant_id", t.ID, "timeout", s.cfg.Timeout.String())
+		}
+		describe(t, &result)
+		if result.Status == models.ReadinessReady {
+			ready++
+		}
+		response.Tenants[i] = result
+	}
+
+	switch {
+	case len(tenants) == 0:
+		response.Status = models.ReadinessNotReady
+	case ready == len(tenants):
+		response.Status = models.ReadinessReady
+	case ready > 0:
+		response.Status = models.ReadinessDegraded
+	default:
+		response.Status = models.ReadinessNotReady
+	}
+	return response
+}
+
+// checkTenant checks one tenant. Details of failures are logged rather than returned, since
+// /ready is not authenticated and Turvo's error bodies may contain customer data.
+func (s *Service) checkTenant(ctx context.Context, t *tenant.Tenant) models.TenantReadiness {
+	client := t.Client()
+	result := models.TenantReadiness{
+		ID:     t.ID,
+		Status: models.ReadinessNotReady,
+	}
+
+	if err := client.EnsureToken(ctx); err != nil {
+		slog.WarnContext(ctx, "readiness: cannot get a Turvo token", "tenant_id", t.ID, "error", err)
+		result.Error = "cannot get a Turvo token"
+	} else if time.Since(client.LastSuccess()) > s.cfg.StaleAfter {
+		if err := client.Ping(ctx); err != nil {
+			slog.WarnContext(ctx, "readiness: Turvo does not answer", "tenant_id", t.ID, "error", err)
+			result.Error = "Turvo does not answer"
+		}
+	}
+	if result.Error == "" {
+		result.Status = models.ReadinessReady
+	}
+	return result
+}
+
+// describe adds the tenant's token expiry, last Turvo answer and webhook feed state
+func describe(t *tenant.Tenant, result *models.TenantReadiness) {
+	client := t.Client()
+	result.TokenExpiresAt = timePtr(client.TokenExpiry())
+	result.LastTurvoResponseAt = timePtr(client.LastSuccess())
+	if sync := t.Loads.SyncStatus(); !sync.LastEventAt.IsZero() {
+		result.LastEventAt = timePtr(sync.LastEventAt)
+		lag := sync.Lag.Seconds()
+		result.SyncLagSeconds = &lag
+	}
+}
+
+// timePtr returns nil for the zero time, so it is left out of the response
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/tenant"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+// newTestRegistry returns a registry backed by a fresh fake Turvo API, with a tenant for each
+// password: turvotest.Password can get a token, anything else cannot
+func newTestRegistry(t *testing.T, passwords map[string]string) (*tenant.Registry, *turvotest.Server) {
+	t.Helper()
+	fake := turvotest.NewServer()
+	t.Cleanup(fake.Close)
+
+	registry := tenant.NewRegistry(tenant.Config{Turvo: fake.Config()})
+	for id, password := range passwords {
+		credentials := models.TurvoCredentials{
+			ClientName:   turvotest.ClientName,
+			ClientSecret: turvotest.ClientSecret,
+			Username:     turvotest.Username,
+			Password:     password,
+		}
+		if _, err := registry.RegisterStatic(tenant.Spec{ID: id, Credentials: credentials}, nil); err != nil {
+			t.Fatalf("RegisterStatic: %v", err)
+		}
+	}
+	return registry, fake
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name      string
+		passwords map[string]string
+		latency   time.Duration // Of token requests
+		status    string
+		tenants   map[string]string // Status by tenant ID
+		errors    map[string]string // Error by tenant ID
+	}{
+		{
+			name:      "ready",
+			passwords: map[string]string{"acme": turvotest.Password, "globex": turvotest.Password},
+			status:    models.ReadinessReady,
+			tenants:   map[string]string{"acme": models.ReadinessReady, "globex": models.ReadinessReady},
+		},
+		{
+			name:      "degraded",
+			passwords: map[string]string{"acme": turvotest.Password, "broken": "wrong"},
+			status:    models.ReadinessDegraded,
+			tenants:   map[string]string{"acme": models.ReadinessReady, "broken": models.ReadinessNotReady},
+			errors:    map[string]string{"broken": "cannot get a Turvo token"},
+		},
+		{
+			name:      "not ready",
+			passwords: map[string]string{"broken": "wrong"},
+			status:    models.ReadinessNotReady,
+			tenants:   map[string]string{"broken": models.ReadinessNotReady},
+			errors:    map[string]string{"broken": "cannot get a Turvo token"},
+		},
+		{
+			name:      "timeout",
+			passwords: map[string]string{"acme": turvotest.Password},
+			latency:   300 * time.Millisecond,
+			status:    models.ReadinessNotReady,
+			tenants:   map[string]string{"acme": models.ReadinessNotReady},
+			errors:    map[string]string{"acme": "check timed out"},
+		},
+		{
+			name:   "no tenants",
+			status: models.ReadinessNotReady,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry, fake := newTestRegistry(t, test.passwords)
+			fake.SetLatency(turvotest.EndpointToken, test.latency)
+			service := NewService(registry, Config{Timeout: 100 * time.Millisecond})
+
+			response := service.Readiness(context.Background())
+			if response.Status != test.status {
+				t.Errorf("status = %s, want %s", response.Status, test.status)
+			}
+			if len(response.Tenants) != len(test.tenants) {
+				t.Fatalf("tenants = %+v, want %d", response.Tenants, len(test.tenants))
+			}
+			for _, result := range response.Tenants {
+				if result.Status != test.tenants[result.ID] {
+					t.Errorf("tenant %s status = %s, want %s", result.ID, result.Status, test.tenants[result.ID])
+				}
+				if result.Error != test.errors[result.ID] {
+					t.Errorf("tenant %s error = %q, want %q", result.ID, result.Error, test.errors[result.ID])
+				}
+				if result.Status == models.ReadinessReady && (result.TokenExpiresAt == nil || result.LastTurvoResponseAt == nil) {
+					t.Errorf("tenant %s = %+v, want its token expiry and last Turvo response", result.ID, result)
+				}
+			}
+		})
+	}
+}
+
+func TestReadinessReusesResult(t *testing.T) {
+	registry, fake := newTestRegistry(t, map[string]string{"broken": "wrong"})
+	service := NewService(registry, Config{StaleAfter: 200 * time.Millisecond})
+
+	first := service.Readiness(context.Background())
+	requests := fake.Requests(turvotest.EndpointToken)
+	if second := service.Readiness(context.Background()); !second.CheckedAt.Equal(first.CheckedAt) {
+		t.Errorf("checked again at %s, want the result of %s", second.CheckedAt, first.CheckedAt)
+	}
+	if got := fake.Requests(turvotest.EndpointToken); got != requests {
+		t.Errorf("token requests = %d, want %d (result reused)", got, requests)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if third := service.Readiness(context.Background()); !third.CheckedAt.After(first.CheckedAt) {
+		t.Error("stale result reused")
+	}
+	if got := fake.Requests(turvotest.EndpointToken); got == requests {
+		t.Error("no token request after the result went stale")
+	}
+}
+
+func TestReadinessSharesRunningCheck(t *testing.T) {
+	registry, fake := newTestRegistry(t, map[string]string{"acme": turvotest.Password})
+	fake.SetLatency(turvotest.EndpointToken, 50*time.Millisecond)
+	service := NewService(registry, Config{})
+
+	// A probe that gives up does not cancel the check the others wait for
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make([]models.ReadinessResponse, 5)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Go(func() {
+			if i == 0 {
+				results[i] = service.Readiness(ctx)
+				return
+			}
+			results[i] = service.Readiness(context.Background())
+		})
+	}
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	if got := fake.Requests(turvotest.EndpointToken); got != 1 {
+		t.Errorf("token requests = %d, want 1 shared check", got)
+	}
+	for i, result := range results {
+		if result.Status != models.ReadinessReady {
+			t.Errorf("probe %d status = %s, want ready", i, result.Status)
+		}
+	}
+}
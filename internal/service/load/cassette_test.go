@@ -0,0 +1,157 @@
+package load
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+var update = flag.Bool("update", false, "re-record testdata cassettes and rewrite golden files")
+
+const (
+	cassettePath     = "testdata/cassettes/complete.json"
+	cassetteLoadPath = "testdata/cassettes/complete.load.golden"
+)
+
+// recordCassette creates the complete test load against the fake Turvo API and reads it back,
+// recording both requests to the cassette
+func recordCassette(t *testing.T) {
+	t.Helper()
+	fake := turvotest.NewServer()
+	defer fake.Close()
+
+	if err := os.Remove(cassettePath); err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	config := fake.Config()
+	config.Mode = turvo.ModeRecord
+	config.CassettePath = cassettePath
+	client, err := turvo.NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	service := NewService(client, Config{})
+	load := readLoad(t, "complete.json")
+	response, err := service.CreateLoad(context.Background(), &load)
+	if err != nil {
+		t.Fatalf("CreateLoad: %v", err)
+	}
+	id, _ := strconv.Atoi(response.ID)
+	if _, err := service.getShipmentDetails(context.Background(), id, true); err != nil {
+		t.Fatalf("getShipmentDetails: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("write cassette: %v", err)
+	}
+}
+
+func TestCassetteReplay(t *testing.T) {
+	if *update {
+		recordCassette(t)
+	}
+
+	// Replay never reaches the fake; it only supplies a client configuration
+	fake := turvotest.NewServer()
+	fake.Close()
+	config := fake.Config()
+	config.Mode = turvo.ModeReplay
+	config.CassettePath = cassettePath
+	client, err := turvo.NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	service := NewService(client, Config{})
+	cassette, err := turvo.LoadCassette(cassettePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("drumkitToTurvo", func(t *testing.T) {
+		load := readLoad(t, "complete.json")
+		if err := service.validateLoad(&load); err != nil {
+			t.Fatalf("validateLoad: %v", err)
+		}
+		var recorded *turvo.Interaction
+		for i, interaction := range cassette.Interactions {
+			if interaction.Request.Method == "POST" && interaction.Request.URL == "/v1/shipments" {
+				recorded = &cassette.Interactions[i]
+			}
+		}
+		if recorded == nil {
+			t.Fatal("cassette has no shipment create request")
+		}
+		var recordedShipment models.TurvoShipmentCreate
+		if err := json.Unmarshal(recorded.Request.Body, &recordedShipment); err != nil {
+			t.Fatal(err)
+		}
+
+		// customerOrderSourceId is random on every create, so the recorded one is kept
+		shipment := service.drumkitToTurvo(&load)
+		for i := range shipment.CustomerOrder {
+			if i < len(recordedShipment.CustomerOrder) {
+				shipment.CustomerOrder[i].CustomerOrderSourceID = recordedShipment.CustomerOrder[i].CustomerOrderSourceID
+			}
+		}
+		body, err := json.Marshal(shipment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !jsonEqual(t, turvo.RedactJSON(body), recorded.Request.Body) {
+			t.Errorf("shipment sent to Turvo differs from the recording:\n got: %s\nwant: %s", turvo.RedactJSON(body), recorded.Request.Body)
+		}
+	})
+
+	t.Run("turvoDetailsToDrumkit", func(t *testing.T) {
+		load := readLoad(t, "complete.json")
+		response, err := service.CreateLoad(context.Background(), &load)
+		if err != nil {
+			t.Fatalf("replayed CreateLoad: %v", err)
+		}
+		id, _ := strconv.Atoi(response.ID)
+		details, err := service.getShipmentDetails(context.Background(), id, true)
+		if err != nil {
+			t.Fatalf("replayed getShipmentDetails: %v", err)
+		}
+
+		got, err := json.MarshalIndent(service.turvoDetailsToDrumkit(details), "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, '\n')
+		if *update {
+			if err := os.WriteFile(cassetteLoadPath, got, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(cassetteLoadPath)
+		if err != nil {
+			t.Fatalf("%v (run with -update to create it)", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("load mapped from the recorded shipment differs from %s:\n%s", cassetteLoadPath, got)
+		}
+	})
+}
+
+// jsonEqual reports whether two JSON documents hold the same value, ignoring formatting
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var left, right interface{}
+	if err := json.Unmarshal(a, &left); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &right); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(left, right)
+}
@@ -0,0 +1,102 @@
+package load
+
+import (
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// SyncStatus describes how current the webhook feed from Turvo is
+type SyncStatus struct {
+	LastEventAt time.Time     // When the last event was applied; zero if none was
+	Lag         time.Duration // How long after it occurred in Turvo the last event was applied
+}
+
+// RecordEvent notes that an event which occurred in Turvo at occurredAt was applied
+func (s *Service) RecordEvent(occurredAt time.Time) {
+	now := time.Now()
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+	s.sync = SyncStatus{
+		LastEventAt: now,
+		Lag:         max(now.Sub(occurredAt), 0),
+	}
+}
+
+// SyncStatus returns the state of the webhook feed
+func (s *Service) SyncStatus() SyncStatus {
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+	return s.sync
+}
+
+// ApplyShipmentEvent updates the cached shipment from a Turvo shipment event.
+// It returns the updated load, or nil when the shipment was removed from the cache.
+func (s *Service) ApplyShipmentEvent(event *models.TurvoWebhookEvent) *models.Load {
+	// Deleted shipments are dropped from the cache
+	if event.EventType == models.TurvoEventShipmentDeleted {
+		s.InvalidateShipment(event.ShipmentID)
+		return nil
+	}
+
+	// Without a shipment payload we only know that something changed, so drop the stale entry
+	if event.Shipment == nil || event.Shipment.Shipment == nil {
+		s.InvalidateShipment(event.ShipmentID)
+		return nil
+	}
+
+	s.detailsCache.Set(event.ShipmentID, event.Shipment.Shipment)
+
+	load := s.turvoDetailsToDrumkit(event.Shipment.Shipment)
+	return &load
+}
+
+// ApplyStatusEvent updates the status of the cached shipment from a Turvo status event.
+// It returns the updated load (nil if the shipment is not cached) and the API status before the change.
+func (s *Service) ApplyStatusEvent(event *models.TurvoWebhookEvent) (*models.Load, string) {
+	// Prefer the previous status reported by Turvo, fall back to what we have cached
+	previousStatus := ""
+	if event.Status.PreviousStatus != nil {
+		previousStatus = s.TurvoStatusToAPI(event.Status.PreviousStatus.Key, event.Status.PreviousStatus.Value)
+	}
+
+	cached, found := s.detailsCache.Get(event.ShipmentID)
+	if !found {
+		return nil, previousStatus
+	}
+
+	if previousStatus == "" && cached.Status != nil {
+		previousStatus = s.TurvoStatusToAPI(cached.Status.Code.Key, cached.Status.Code.Value)
+	}
+
+	// Copy before changing, other requests may be reading the cached shipment
+	updated := *cached
+	status := event.Status.Status
+	updated.Status = &status
+	s.detailsCache.Set(event.ShipmentID, &updated)
+
+	load := s.turvoDetailsToDrumkit(&updated)
+	return &load, previousStatus
+}
+
+// ApplyLocationEvent returns the cached load for a Turvo location event (nil if the shipment is not cached).
+// Our load model has no tracking location, so the cached shipment itself is not changed.
+func (s *Service) ApplyLocationEvent(event *models.TurvoWebhookEvent) *models.Load {
+	load, found := s.GetCachedLoad(event.ShipmentID)
+	if !found {
+		return nil
+	}
+
+	return load
+}
+
+// GetCachedLoad returns the load for a cached shipment without calling Turvo
+func (s *Service) GetCachedLoad(shipmentID int) (*models.Load, bool) {
+	shipment, found := s.detailsCache.Get(shipmentID)
+	if !found {
+		return nil, false
+	}
+
+	load := s.turvoDetailsToDrumkit(shipment)
+	return &load, true
+}
@@ -0,0 +1,129 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// exportPageSize is the number of shipments requested from Turvo per page while exporting
+const exportPageSize = 100
+
+// ExportSummary counts what an export went through
+type ExportSummary struct {
+	Scanned         int // Shipments listed by Turvo
+	Exported        int // Loads passed to emit
+	PartialFailures int // Loads whose details could not be fetched
+}
+
+// ExportLoads passes every load matching the filters to emit, one Turvo page at a time, so
+// callers can stream them without holding all of them. Page and limit are ignored. Pages are
+// requested until Turvo returns an empty one, since its moreAvailable flag is not reliable.
+// Shipments added while we page shift exported ones into the next page; those are emitted
+// once. Only the previous page is remembered, so memory does not grow with the export, which
+// covers fewer than a page of shipments added between two requests.
+//
+// Loads whose details cannot be fetched are emitted with list data, or left out if filters
+// are matched on our side. Sorts Turvo cannot apply would need every load in memory and are
+// rejected. An error from emit stops the export and is returned as is.
+func (s *Service) ExportLoads(ctx context.Context, filters models.LoadFilters, emit func([]models.Load) error) (summary ExportSummary, err error) {
+	ctx, span := s.startSpan(ctx, "load.ExportLoads",
+		attribute.Bool("load.include_details", filters.IncludeDetails),
+	)
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("load.export.scanned", summary.Scanned),
+			attribute.Int("load.export.exported", summary.Exported),
+			attribute.Int("load.detail_fallbacks", summary.PartialFailures),
+		)
+		tracing.End(span, err)
+	}()
+
+	filters.Expand = expandSections(filters.Expand, filters.Fields)
+	span.SetAttributes(attribute.StringSlice("load.expand", filters.Expand))
+
+	turvoFilters, err := s.mapToTurvoFilters(filters)
+	if err != nil {
+		return summary, err
+	}
+	if len(filters.Sort) > 0 && turvoFilters.SortBy == "" {
+		return summary, &ValidationError{Problems: []string{
+			fmt.Sprintf("sort: exports can only be sorted by one of %s, %s, %s or %s",
+				SortPickupDate, SortDeliveryDate, SortCreated, SortUpdated),
+		}}
+	}
+
+	// Matching on our side needs every section of the details
+	fullDetails := filters.Searched() || filters.IncludeDetails
+	needsDetails := fullDetails || len(filters.Expand) > 0
+	var sections []string
+	if !fullDetails {
+		sections = filters.Expand
+	}
+
+	var previous map[int]bool // IDs of the previous page
+	turvoFilters.Start = 0
+	turvoFilters.PageSize = exportPageSize
+	for {
+		shipments, _, err := s.turvoClient.ListShipmentsWithFiltersAndPagination(ctx, turvoFilters)
+		if err != nil {
+			return summary, err
+		}
+		if len(shipments) == 0 {
+			return summary, nil
+		}
+		summary.Scanned += len(shipments)
+		turvoFilters.Start += len(shipments)
+
+		// Skip shipments of the previous page, which shift into this one when shipments are
+		// added while we page
+		var fresh []models.TurvoShipment
+		page := make(map[int]bool, len(shipments))
+		for _, shipment := range shipments {
+			if !previous[shipment.ID] && !page[shipment.ID] {
+				fresh = append(fresh, shipment)
+			}
+			page[shipment.ID] = true
+		}
+		previous = page
+		// A page of nothing but repeats means Turvo ignored the start offset
+		if len(fresh) == 0 {
+			return summary, fmt.Errorf("turvo listed only shipments already exported at start %d", turvoFilters.Start-len(shipments))
+		}
+
+		loads := make([]models.Load, 0, len(fresh))
+		if needsDetails {
+			detailed, failures := s.loadsWithDetails(ctx, fresh, filters.BypassCache, sections)
+			summary.PartialFailures += len(failures)
+			failed := make(map[string]bool, len(failures))
+			for _, failure := range failures {
+				failed[failure.ExternalTMSLoadID] = true
+			}
+			for _, load := range detailed {
+				if !filters.Searched() {
+					loads = append(loads, load)
+				} else if failed[load.ExternalTMSLoadID] {
+					slog.WarnContext(ctx, "load left out of export, details unavailable", "shipment_id", load.ExternalTMSLoadID)
+				} else if matchesSearch(load, filters) {
+					loads = append(loads, load)
+				}
+			}
+		} else {
+			for _, shipment := range fresh {
+				loads = append(loads, s.turvoToDrumkit(&shipment))
+			}
+		}
+
+		if len(loads) == 0 {
+			continue
+		}
+		if err := emit(loads); err != nil {
+			return summary, err
+		}
+		summary.Exported += len(loads)
+	}
+}
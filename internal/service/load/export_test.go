@@ -0,0 +1,137 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+// exportIDs exports with the filters and returns the IDs of the emitted loads, in order. The
+// export is cancelled if it pages for too long, so one that never ends fails instead of hanging.
+func exportIDs(t *testing.T, service *Service, filters models.LoadFilters, onPage func()) ([]string, ExportSummary, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	var ids []string
+	summary, err := service.ExportLoads(ctx, filters, func(loads []models.Load) error {
+		ids = append(ids, loadIDs(loads)...)
+		if onPage != nil {
+			onPage()
+		}
+		return nil
+	})
+	return ids, summary, err
+}
+
+func TestExportLoadsStopsAtEmptyPage(t *testing.T) {
+	tests := []struct {
+		name          string
+		override      bool // Report moreAvailable instead of the real value
+		moreAvailable bool
+	}{
+		{name: "moreAvailable from Turvo"},
+		{name: "moreAvailable always true", override: true, moreAvailable: true},
+		{name: "moreAvailable always false", override: true, moreAvailable: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, fake := newTestService(t, Config{})
+			ids := createLoads(t, service, exportPageSize+5)
+			if test.override {
+				fake.SetMoreAvailable(test.moreAvailable)
+			}
+
+			exported, summary, err := exportIDs(t, service, models.LoadFilters{}, nil)
+			if err != nil {
+				t.Fatalf("ExportLoads: %v", err)
+			}
+			if strings.Join(exported, ",") != strings.Join(ids, ",") {
+				t.Errorf("exported %d loads, want the %d created in order", len(exported), len(ids))
+			}
+			if summary.Scanned != len(ids) || summary.Exported != len(ids) {
+				t.Errorf("summary = %+v, want %d scanned and exported", summary, len(ids))
+			}
+			if got := fake.Requests(turvotest.EndpointList); got != 3 {
+				t.Errorf("list requests = %d, want 3 (two pages and an empty one)", got)
+			}
+		})
+	}
+}
+
+func TestExportLoadsEmpty(t *testing.T) {
+	service, fake := newTestService(t, Config{})
+
+	exported, summary, err := exportIDs(t, service, models.LoadFilters{}, nil)
+	if err != nil {
+		t.Fatalf("ExportLoads: %v", err)
+	}
+	if len(exported) != 0 || summary != (ExportSummary{}) {
+		t.Errorf("exported %v with summary %+v, want nothing", exported, summary)
+	}
+	if got := fake.Requests(turvotest.EndpointList); got != 1 {
+		t.Errorf("list requests = %d, want 1", got)
+	}
+}
+
+func TestExportLoadsOverlappingPages(t *testing.T) {
+	service, fake := newTestService(t, Config{})
+	ids := createLoads(t, service, exportPageSize+20)
+
+	// Newest first, so a shipment added after the first page shifts its last one into the
+	// second page
+	added := 0
+	filters := models.LoadFilters{Sort: []models.LoadSort{{Field: SortCreated, Descending: true}}}
+	exported, _, err := exportIDs(t, service, filters, func() {
+		if added == 0 {
+			fake.AddShipment(models.TurvoShipmentCreateDetails{})
+			added++
+		}
+	})
+	if err != nil {
+		t.Fatalf("ExportLoads: %v", err)
+	}
+
+	want := slices.Clone(ids)
+	slices.Reverse(want)
+	if strings.Join(exported, ",") != strings.Join(want, ",") {
+		t.Errorf("exported %d loads, want each of the %d created once, newest first", len(exported), len(ids))
+	}
+}
+
+func TestExportLoadsOnlyRepeats(t *testing.T) {
+	service, fake := newTestService(t, Config{})
+	createLoads(t, service, exportPageSize+1)
+	fake.SetIgnoreStart(true)
+
+	exported, _, err := exportIDs(t, service, models.LoadFilters{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "only shipments already exported") {
+		t.Fatalf("error = %v, want an error about repeated shipments", err)
+	}
+	if len(exported) != exportPageSize {
+		t.Errorf("exported %d loads before the error, want %d", len(exported), exportPageSize)
+	}
+	if got := fake.Requests(turvotest.EndpointList); got != 2 {
+		t.Errorf("list requests = %d, want 2", got)
+	}
+}
+
+func TestExportLoadsRejectsInMemorySort(t *testing.T) {
+	service, fake := newTestService(t, Config{})
+	createLoads(t, service, 1)
+
+	filters := models.LoadFilters{Sort: []models.LoadSort{{Field: SortCustomerName}}}
+	_, _, err := exportIDs(t, service, filters, nil)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("error = %v, want a ValidationError", err)
+	}
+	if got := fake.Requests(turvotest.EndpointList); got != 0 {
+		t.Errorf("list requests = %d, want 0", got)
+	}
+}
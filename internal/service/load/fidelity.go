@@ -0,0 +1,170 @@
+package load
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo"
+)
+
+// Outcomes of a field in a mapping round trip
+const (
+	FieldSurvived = "survived" // Returned unchanged
+	FieldChanged  = "changed"  // Returned with a different value
+	FieldLost     = "lost"     // Sent but not returned
+	FieldAdded    = "added"    // Not sent but returned (e.g. IDs assigned by Turvo)
+)
+
+// simulatedShipmentID is the ID the simulated Turvo response assigns, so reports are reproducible
+const simulatedShipmentID = 1000000001
+
+// FieldFidelity describes what happened to one field of a load in a round trip
+type FieldFidelity struct {
+	Path     string `json:"path"`
+	Outcome  string `json:"outcome"`
+	Sent     string `json:"sent,omitempty"`
+	Returned string `json:"returned,omitempty"`
+}
+
+// FidelityReport lists every field of a round trip with its outcome
+type FidelityReport struct {
+	Fields   []FieldFidelity `json:"fields"`
+	Survived int             `json:"survived"`
+	Changed  int             `json:"changed"`
+	Lost     int             `json:"lost"`
+	Added    int             `json:"added"`
+}
+
+// RoundTrip maps a load to Turvo with drumkitToTurvo, simulates Turvo's stored shipment and maps it
+// back with turvoDetailsToDrumkit, then reports which fields survive, change or are lost.
+// The load must pass the same validation as a create request.
+func (s *Service) RoundTrip(load *models.Load) (*FidelityReport, error) {
+	if err := s.validateLoad(load); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	shipment := s.drumkitToTurvo(load)
+	stored := turvo.SimulateCreatedShipment(shipment, simulatedShipmentID)
+	returned := s.turvoDetailsToDrumkit(&stored)
+
+	sent, err := flattenLoad(load)
+	if err != nil {
+		return nil, err
+	}
+	got, err := flattenLoad(&returned)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(sent)+len(got))
+	for path := range sent {
+		paths = append(paths, path)
+	}
+	for path := range got {
+		if _, found := sent[path]; !found {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	report := &FidelityReport{}
+	for _, path := range paths {
+		sentValue, wasSent := sent[path]
+		returnedValue, wasReturned := got[path]
+
+		field := FieldFidelity{
+			Path:     path,
+			Sent:     sentValue,
+			Returned: returnedValue,
+		}
+		switch {
+		case wasSent && !wasReturned:
+			field.Outcome = FieldLost
+			report.Lost++
+		case !wasSent && wasReturned:
+			field.Outcome = FieldAdded
+			report.Added++
+		case sentValue == returnedValue:
+			field.Outcome = FieldSurvived
+			report.Survived++
+		default:
+			field.Outcome = FieldChanged
+			report.Changed++
+		}
+		report.Fields = append(report.Fields, field)
+	}
+
+	return report, nil
+}
+
+// WriteTable prints the report as an aligned text table followed by a summary line
+func (r *FidelityReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tOUTCOME\tSENT\tRETURNED")
+	for _, field := range r.Fields {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", field.Path, field.Outcome, field.Sent, field.Returned)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d survived, %d changed, %d lost, %d added\n", r.Survived, r.Changed, r.Lost, r.Added)
+	return err
+}
+
+// WriteMarkdown prints the report as a Markdown table
+func (r *FidelityReport) WriteMarkdown(w io.Writer) error {
+	fmt.Fprintln(w, "| Field | Outcome | Sent | Returned |")
+	fmt.Fprintln(w, "|-------|---------|------|----------|")
+	for _, field := range r.Fields {
+		fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", field.Path, field.Outcome, markdownCell(field.Sent), markdownCell(field.Returned))
+	}
+	_, err := fmt.Fprintf(w, "\n%d survived, %d changed, %d lost, %d added\n", r.Survived, r.Changed, r.Lost, r.Added)
+	return err
+}
+
+func markdownCell(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(value, "|", "\\|") + "`"
+}
+
+// flattenLoad turns a load into a map of JSON paths (e.g. "pickup.city") to their JSON-encoded leaf values
+func flattenLoad(load *models.Load) (map[string]string, error) {
+	data, err := json.Marshal(load)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode load: %w", err)
+	}
+
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to decode load: %w", err)
+	}
+
+	fields := make(map[string]string)
+	flattenValue("", tree, fields)
+	return fields, nil
+}
+
+func flattenValue(prefix string, value interface{}, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			flattenValue(path, child, fields)
+		}
+	case string:
+		fields[prefix] = v
+	default:
+		encoded, _ := json.Marshal(v)
+		fields[prefix] = string(encoded)
+	}
+}
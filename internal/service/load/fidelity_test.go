@@ -0,0 +1,69 @@
+package load
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// TestRoundTripGolden compares the fidelity report of every load in testdata/fidelity with its
+// .golden file; run with -update to rewrite them
+func TestRoundTripGolden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/fidelity/*.json")
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no loads in testdata/fidelity: %v", err)
+	}
+
+	// The mappings do not call Turvo, so no client is needed
+	service := NewService(nil, Config{})
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var load models.Load
+			if err := json.Unmarshal(data, &load); err != nil {
+				t.Fatalf("invalid load: %v", err)
+			}
+
+			report, err := service.RoundTrip(&load)
+			if err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			}
+			var got bytes.Buffer
+			if err := report.WriteTable(&got); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := strings.TrimSuffix(input, ".json") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("mapping fidelity differs from %s\n--- want\n%s--- got\n%s", golden, want, got.Bytes())
+			}
+		})
+	}
+}
+
+func TestRoundTripRejectsInvalidLoad(t *testing.T) {
+	service := NewService(nil, Config{})
+	load := readLoad(t, "minimal.json")
+	load.Pickup = nil
+
+	if _, err := service.RoundTrip(&load); err == nil {
+		t.Error("RoundTrip accepted a load without a pickup")
+	}
+}
@@ -0,0 +1,60 @@
+package load
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// summaryFields are the JSON paths of the load format that turvoToDrumkit fills from the list
+// endpoint's summaries with the same values as the details. Every other field needs a
+// GetShipment call, including the carrier MC number and the consignee, which the summaries
+// only approximate with the carrier's Turvo ID and the first party of the shipment.
+var summaryFields = map[string]bool{
+	"externalTMSLoadID":      true,
+	"freightLoadID":          true,
+	"status":                 true,
+	"customer.externalTMSId": true,
+	"customer.name":          true,
+	"carrier.name":           true,
+}
+
+// expandSections adds the top-level sections (e.g. "pickup") that shipment details are
+// needed for to fill the requested fields to the expanded sections
+func expandSections(expand, fields []string) []string {
+	sections := append([]string(nil), expand...)
+	for _, field := range fields {
+		if summaryFields[field] {
+			continue
+		}
+		section, _, _ := strings.Cut(field, ".")
+		if !contains(sections, section) {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// mergeSections returns the summary load with the given top-level sections taken from the
+// detailed load
+func mergeSections(summary, detailed models.Load, sections []string) models.Load {
+	merged := reflect.ValueOf(&summary).Elem()
+	source := reflect.ValueOf(detailed)
+	for i := 0; i < merged.NumField(); i++ {
+		name, _, _ := strings.Cut(merged.Type().Field(i).Tag.Get("json"), ",")
+		if contains(sections, name) {
+			merged.Field(i).Set(source.Field(i))
+		}
+	}
+	return summary
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package load
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+// fieldValue returns the value at a JSON path of the load, nil if it has none
+func fieldValue(t *testing.T, load models.Load, path string) any {
+	t.Helper()
+	data, err := json.Marshal(load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+// Fields taken from the list summaries must read the same as with details, or asking for
+// them with fields would answer differently than expand or includeDetails
+func TestSummaryFieldsMatchDetails(t *testing.T) {
+	service, fake := newTestService(t, Config{})
+	load := readLoad(t, "complete.json")
+	if _, err := service.CreateLoad(context.Background(), &load); err != nil {
+		t.Fatalf("CreateLoad: %v", err)
+	}
+	detailed, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 20, IncludeDetails: true})
+	if err != nil {
+		t.Fatalf("GetLoads with details: %v", err)
+	}
+
+	// The summaries also name a carrier ID and a party, which are not the MC number and the
+	// consignee; details leave them out or read differently
+	paths := []string{"carrier.mcNumber", "consignee.name", "consignee.externalTMSId"}
+	for path := range summaryFields {
+		paths = append(paths, path)
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			want := fieldValue(t, detailed.Data[0], path)
+			if want == nil && summaryFields[path] {
+				t.Fatalf("complete load has no %s with details", path)
+			}
+
+			before := fake.Requests(turvotest.EndpointGet)
+			page, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 20, Fields: []string{path}, BypassCache: true})
+			if err != nil {
+				t.Fatalf("GetLoads: %v", err)
+			}
+			if got := fieldValue(t, page.Data[0], path); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s = %v, want %v as with details", path, got, want)
+			}
+			fetched := fake.Requests(turvotest.EndpointGet) > before
+			if fetched == summaryFields[path] {
+				t.Errorf("details fetched = %t, want %t", fetched, !summaryFields[path])
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package load
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// searchPageSize is the number of shipments requested from Turvo per page while searching
+const searchPageSize = 100
+
+// searchLoads serves lists Turvo cannot answer by itself: filters matched on our side (see
+// LoadFilters.Searched) and sorts Turvo cannot apply. It pages through the shipments matching
+// the Turvo filters and keeps the loads that match, until Turvo runs out of shipments or
+// searchScanLimit shipments were scanned. Without sortInMemory it stops early once it has the
+// requested page plus one match. Loads whose details cannot be fetched are reported as
+// partial failures, and left out if filters are matched on our side.
+func (s *Service) searchLoads(ctx context.Context, filters models.LoadFilters, turvoFilters models.TurvoShipmentFilters, sortInMemory bool) (*models.LoadListResponse, error) {
+	skip := (filters.Page - 1) * filters.Limit
+	// Matching and sorting by stop dates need every section of the details
+	fullDetails := filters.Searched() || filters.IncludeDetails || (sortInMemory && sortNeedsDetails(filters.Sort))
+	needsDetails := fullDetails || len(filters.Expand) > 0
+	var sections []string
+	if !fullDetails {
+		sections = filters.Expand
+	}
+	var matches []scannedLoad
+	var partialFailures []models.LoadPartialFailure
+
+	turvoFilters.Start = 0
+	scanned, moreAvailable := 0, true
+	for moreAvailable && (sortInMemory || len(matches) <= skip+filters.Limit) && scanned < s.searchScanLimit {
+		turvoFilters.PageSize = min(searchPageSize, s.searchScanLimit-scanned)
+		shipments, pagination, err := s.turvoClient.ListShipmentsWithFiltersAndPagination(ctx, turvoFilters)
+		if err != nil {
+			return nil, err
+		}
+		scanned += len(shipments)
+		turvoFilters.Start += len(shipments)
+		moreAvailable = pagination.MoreAvailable && len(shipments) > 0
+
+		if !needsDetails {
+			for _, shipment := range shipments {
+				matches = append(matches, scannedLoad{load: s.turvoToDrumkit(&shipment), shipment: shipment})
+			}
+			continue
+		}
+		loads, failures := s.loadsWithDetails(ctx, shipments, filters.BypassCache, sections)
+		// Without details a load cannot be matched, but it can still be sorted with list data
+		failed := make(map[string]bool, len(failures))
+		for _, failure := range failures {
+			if filters.Searched() {
+				failed[failure.ExternalTMSLoadID] = true
+				failure.Reason = "not searched, details unavailable: " + failure.Reason
+			}
+			partialFailures = append(partialFailures, failure)
+		}
+		for i, load := range loads {
+			if !failed[load.ExternalTMSLoadID] && matchesSearch(load, filters) {
+				matches = append(matches, scannedLoad{load: load, shipment: shipments[i]})
+			}
+		}
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("load.search.scanned", scanned),
+		attribute.Int("load.search.matches", len(matches)),
+		attribute.Bool("load.search.sorted", sortInMemory),
+	)
+
+	if sortInMemory {
+		s.sortLoads(matches, filters.Sort)
+	}
+	page := []models.Load{}
+	for i := skip; i < len(matches) && i < skip+filters.Limit; i++ {
+		page = append(page, matches[i].load)
+	}
+
+	// Without a match beyond this page, or with every match scanned, the pages are those of
+	// the matches found
+	hasNextPage := len(matches) > skip+filters.Limit
+	pages := filters.Page + 1
+	if !hasNextPage || sortInMemory {
+		pages = max(1, (len(matches)+filters.Limit-1)/filters.Limit)
+	}
+
+	return &models.LoadListResponse{
+		Data: page,
+		Pagination: models.Pagination{
+			Total: len(matches),
+			Pages: pages,
+			Page:  filters.Page,
+			Limit: filters.Limit,
+		},
+		PartialFailures:  partialFailures,
+		SearchIncomplete: moreAvailable && (sortInMemory || !hasNextPage),
+	}, nil
+}
+
+// matchesSearch reports whether a load with details matches the filters applied on our side.
+// Cities, states and reference numbers are compared case-insensitively.
+func matchesSearch(load models.Load, filters models.LoadFilters) bool {
+	var originCity, originState, destinationCity, destinationState string
+	if load.Pickup != nil {
+		originCity, originState = load.Pickup.City, load.Pickup.State
+	}
+	if load.Consignee != nil {
+		destinationCity, destinationState = load.Consignee.City, load.Consignee.State
+	}
+
+	return matchesText(originCity, filters.OriginCity) &&
+		matchesText(originState, filters.OriginState) &&
+		matchesText(destinationCity, filters.DestinationCity) &&
+		matchesText(destinationState, filters.DestinationState) &&
+		(filters.PONumber == "" || hasReference(load, filters.PONumber))
+}
+
+// matchesText reports whether value equals filter, ignoring case and surrounding spaces; an
+// empty filter matches everything
+func matchesText(value, filter string) bool {
+	return filter == "" || strings.EqualFold(strings.TrimSpace(value), strings.TrimSpace(filter))
+}
+
+// hasReference reports whether a load has the number among its PO numbers or as its customer
+// reference number
+func hasReference(load models.Load, number string) bool {
+	for _, poNumber := range strings.Split(load.PoNums, ",") {
+		if matchesText(poNumber, number) && strings.TrimSpace(poNumber) != "" {
+			return true
+		}
+	}
+	return load.Customer != nil && load.Customer.RefNumber != "" && matchesText(load.Customer.RefNumber, number)
+}
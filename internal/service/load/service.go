@@ -1,35 +1,105 @@
 package load
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lwlach/turvo-integration-backend/internal/cache"
+	"github.com/lwlach/turvo-integration-backend/internal/mapping"
+	"github.com/lwlach/turvo-integration-backend/internal/metrics"
 	"github.com/lwlach/turvo-integration-backend/internal/models"
-	"github.com/lwlach/turvo-integration-backend/internal/turvo"
+	"github.com/lwlach/turvo-integration-backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// TurvoAPI is the part of the Turvo client the load service depends on.
+// It is implemented by *turvo.Client; tests can point a real client at turvotest.Server.
+type TurvoAPI interface {
+	ListShipmentsWithFiltersAndPagination(ctx context.Context, filters models.TurvoShipmentFilters) ([]models.TurvoShipment, models.TurvoPagination, error)
+	CreateShipment(ctx context.Context, shipment *models.TurvoShipmentCreate) (*models.TurvoShipmentCreateResponse, error)
+	GetShipment(ctx context.Context, shipmentID int) (*models.TurvoShipmentCreateDetails, error)
+}
+
+// ShipmentCache caches detailed Turvo shipments keyed by shipment ID
+type ShipmentCache interface {
+	Get(shipmentID int) (*models.TurvoShipmentCreateDetails, bool)
+	Set(shipmentID int, shipment *models.TurvoShipmentCreateDetails)
+	Delete(shipmentID int)
+}
+
+// Config holds tunables for the load service
+type Config struct {
+	DetailsCacheTTL  time.Duration    // How long fetched shipment details are reused (default: 5 minutes)
+	DetailsCacheSize int              // Maximum number of cached shipment details (default: 10000)
+	DetailWorkers    int              // Maximum concurrent GetShipment calls per list request (default: 8)
+	SearchScanLimit  int              // Maximum shipments scanned for filters and sorts applied on our side (default: 1000)
+	Profile          *mapping.Profile // Tenant-specific Turvo codes (default: mapping.Default())
+	TenantID         string           // Added to spans
+}
+
 type Service struct {
-	turvoClient *turvo.Client
+	turvoClient TurvoAPI
+
+	// Shipment details from GetShipment and Turvo webhook events
+	detailsCache ShipmentCache
+
+	detailWorkers   int
+	searchScanLimit int
+	profile         *mapping.Profile
+	tenantID        string
+
+	// Webhook feed state, see RecordEvent
+	sync      SyncStatus
+	syncMutex sync.Mutex
 }
 
-func NewService(turvoClient *turvo.Client) *Service {
+func NewService(turvoClient TurvoAPI, cfg Config) *Service {
+	if cfg.DetailsCacheTTL <= 0 {
+		cfg.DetailsCacheTTL = 5 * time.Minute
+	}
+	if cfg.DetailsCacheSize <= 0 {
+		cfg.DetailsCacheSize = 10000
+	}
+	if cfg.DetailWorkers <= 0 {
+		cfg.DetailWorkers = 8
+	}
+	if cfg.SearchScanLimit <= 0 {
+		cfg.SearchScanLimit = 1000
+	}
+	if cfg.Profile == nil {
+		cfg.Profile = mapping.Default()
+	}
+
 	return &Service{
-		turvoClient: turvoClient,
+		turvoClient:     turvoClient,
+		detailsCache:    cache.NewLRU[int, *models.TurvoShipmentCreateDetails](cfg.DetailsCacheSize, cfg.DetailsCacheTTL),
+		detailWorkers:   cfg.DetailWorkers,
+		searchScanLimit: cfg.SearchScanLimit,
+		profile:         cfg.Profile,
+		tenantID:        cfg.TenantID,
 	}
 }
 
+// startSpan starts a span of the service, tagged with its tenant
+func (s *Service) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, trace.WithAttributes(append(attrs, tracing.TenantID.String(s.tenantID))...))
+}
+
 // GetAllLoads fetches all loads from Turvo and converts them to Drumkit format
 // Deprecated: Use GetLoads with filters instead
-func (s *Service) GetAllLoads() ([]models.Load, error) {
+func (s *Service) GetAllLoads(ctx context.Context) ([]models.Load, error) {
 	filters := models.LoadFilters{
 		Page:  1,
 		Limit: 1000, // Large limit to get all
 	}
-	response, err := s.GetLoads(filters)
+	response, err := s.GetLoads(ctx, filters)
 	if err != nil {
 		return nil, err
 	}
@@ -37,56 +107,57 @@ func (s *Service) GetAllLoads() ([]models.Load, error) {
 }
 
 // GetLoads fetches loads from Turvo with filtering and pagination
-func (s *Service) GetLoads(filters models.LoadFilters) (*models.LoadListResponse, error) {
+func (s *Service) GetLoads(ctx context.Context, filters models.LoadFilters) (_ *models.LoadListResponse, err error) {
+	ctx, span := s.startSpan(ctx, "load.GetLoads",
+		attribute.Bool("load.include_details", filters.IncludeDetails),
+		attribute.Int("load.page", filters.Page),
+		attribute.Int("load.limit", filters.Limit),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	// Requested fields the list data lacks need their sections of the details
+	filters.Expand = expandSections(filters.Expand, filters.Fields)
+	span.SetAttributes(attribute.StringSlice("load.expand", filters.Expand))
+
 	// Map our filters to Turvo filters
-	turvoFilters := s.mapToTurvoFilters(filters)
+	turvoFilters, err := s.mapToTurvoFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Filters and sorts Turvo cannot apply need a search on our side
+	sortInMemory := len(filters.Sort) > 0 && turvoFilters.SortBy == ""
+	if filters.Searched() || sortInMemory {
+		return s.searchLoads(ctx, filters, turvoFilters, sortInMemory)
+	}
 
 	// Fetch shipments from Turvo with filters
-	turvoShipments, turvoPagination, err := s.turvoClient.ListShipmentsWithFiltersAndPagination(turvoFilters)
+	turvoShipments, turvoPagination, err := s.turvoClient.ListShipmentsWithFiltersAndPagination(ctx, turvoFilters)
 	if err != nil {
 		return nil, err
 	}
 
 	// Convert to loads
-	loads := make([]models.Load, len(turvoShipments))
-
-	if filters.IncludeDetails {
-		// Fetch detailed shipment information concurrently using goroutines
-		var wg sync.WaitGroup
-		var mu sync.Mutex
-
-		for i, shipment := range turvoShipments {
-			wg.Add(1)
-			go func(index int, shipmentID int, baseShipment models.TurvoShipment) {
-				defer wg.Done()
-
-				// Fetch detailed shipment information
-				detailedShipment, err := s.turvoClient.GetShipment(shipmentID)
-				var load models.Load
-				if err != nil {
-					// If fetching details fails, fall back to basic conversion
-					load = s.turvoToDrumkit(&baseShipment)
-				} else {
-					// Convert detailed shipment to load
-					load = s.turvoDetailsToDrumkit(detailedShipment)
-				}
-
-				// Store result in the correct position
-				mu.Lock()
-				loads[index] = load
-				mu.Unlock()
-			}(i, shipment.ID, shipment)
+	var loads []models.Load
+	var partialFailures []models.LoadPartialFailure
+	if filters.IncludeDetails || len(filters.Expand) > 0 {
+		// Without includeDetails, only the expanded sections come from the details
+		var sections []string
+		if !filters.IncludeDetails {
+			sections = filters.Expand
 		}
-
-		// Wait for all goroutines to complete
-		wg.Wait()
+		loads, partialFailures = s.loadsWithDetails(ctx, turvoShipments, filters.BypassCache, sections)
+		span.SetAttributes(attribute.Int("load.detail_fallbacks", len(partialFailures)))
 	} else {
 		// Use basic list conversion sequentially
+		loads = make([]models.Load, len(turvoShipments))
 		for i, shipment := range turvoShipments {
 			loads[i] = s.turvoToDrumkit(&shipment)
 		}
 	}
 
+	span.SetAttributes(attribute.Int("load.count", len(loads)))
+
 	// Calculate pagination from Turvo's response
 	// Note: Turvo doesn't provide total count, only current page info
 	// totalRecordsInPage represents the number of records in the current page response
@@ -117,46 +188,152 @@ func (s *Service) GetLoads(filters models.LoadFilters) (*models.LoadListResponse
 			Page:  filters.Page,
 			Limit: filters.Limit,
 		},
+		PartialFailures: partialFailures,
 	}, nil
 }
 
+// loadsWithDetails fetches shipment details with a bounded pool of workers and converts them to loads.
+// If sections is not nil, only those top-level sections are taken from the details and the rest from
+// the list data. Shipments whose details cannot be fetched fall back to the basic list conversion and
+// are reported as partial failures, so callers can tell which loads are missing details.
+func (s *Service) loadsWithDetails(ctx context.Context, shipments []models.TurvoShipment, bypassCache bool, sections []string) ([]models.Load, []models.LoadPartialFailure) {
+	loads := make([]models.Load, len(shipments))
+	failures := make([]*models.LoadPartialFailure, len(shipments))
+
+	workers := s.detailWorkers
+	if workers > len(shipments) {
+		workers = len(shipments)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each worker writes only to the indexes it receives, so no locking is needed
+			for index := range jobs {
+				shipment := shipments[index]
+				detailedShipment, err := s.getShipmentDetails(ctx, shipment.ID, bypassCache)
+				if err != nil {
+					slog.WarnContext(ctx, "failed to fetch shipment details, using list data", "shipment_id", shipment.ID, "error", err)
+					metrics.DetailFallbacks.Inc()
+					loads[index] = s.turvoToDrumkit(&shipment)
+					failures[index] = &models.LoadPartialFailure{
+						ExternalTMSLoadID: loads[index].ExternalTMSLoadID,
+						Reason:            err.Error(),
+					}
+					continue
+				}
+				loads[index] = s.turvoDetailsToDrumkit(detailedShipment)
+				if sections != nil {
+					loads[index] = mergeSections(s.turvoToDrumkit(&shipment), loads[index], sections)
+				}
+			}
+		}()
+	}
+
+	for i := range shipments {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Keep failures in the same order as the loads
+	var partialFailures []models.LoadPartialFailure
+	for _, failure := range failures {
+		if failure != nil {
+			partialFailures = append(partialFailures, *failure)
+		}
+	}
+
+	return loads, partialFailures
+}
+
+// getShipmentDetails returns shipment details from the cache, fetching them from Turvo on a miss.
+// When bypassCache is true, Turvo is always called and the cache is refreshed with the result.
+func (s *Service) getShipmentDetails(ctx context.Context, shipmentID int, bypassCache bool) (_ *models.TurvoShipmentCreateDetails, err error) {
+	ctx, span := s.startSpan(ctx, "load.getShipmentDetails", tracing.ShipmentID.Int(shipmentID))
+	defer func() { tracing.End(span, err) }()
+
+	if !bypassCache {
+		if shipment, found := s.detailsCache.Get(shipmentID); found {
+			span.SetAttributes(attribute.Bool("cache.hit", true))
+			return shipment, nil
+		}
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+
+	shipment, err := s.turvoClient.GetShipment(ctx, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.detailsCache.Set(shipmentID, shipment)
+	return shipment, nil
+}
+
+// InvalidateShipment removes cached details for a shipment so the next read goes to Turvo
+func (s *Service) InvalidateShipment(shipmentID int) {
+	s.detailsCache.Delete(shipmentID)
+}
+
 // mapToTurvoFilters maps our API filters to Turvo's filter format
-func (s *Service) mapToTurvoFilters(filters models.LoadFilters) models.TurvoShipmentFilters {
+func (s *Service) mapToTurvoFilters(filters models.LoadFilters) (models.TurvoShipmentFilters, error) {
 	turvoFilters := models.TurvoShipmentFilters{
 		PageSize: filters.Limit,
 		Start:    (filters.Page - 1) * filters.Limit,
 	}
 
-	// Map status: convert API status to Turvo status code
-	if filters.Status != "" {
-		statusKey, _ := APIToTurvoStatus(filters.Status)
-		turvoFilters.Status = statusKey
+	// Map statuses: convert API statuses to Turvo status codes
+	for _, apiStatus := range filters.Statuses {
+		status, err := s.APIToTurvoStatus("status", apiStatus)
+		if err != nil {
+			return turvoFilters, err
+		}
+		turvoFilters.Statuses = append(turvoFilters.Statuses, status.Key)
 	}
 
-	// Map customerId
-	if filters.CustomerID != "" {
-		turvoFilters.CustomerID = filters.CustomerID
-	}
+	// Map IDs
+	turvoFilters.CustomerID = filters.CustomerID
+	turvoFilters.CarrierID = filters.CarrierID
+	turvoFilters.CustomID = filters.FreightLoadID
 
-	// Map pickup date filters: convert to RFC3339 format in UTC
-	if filters.PickupDateFrom != nil {
-		// Convert to UTC and format
-		utcDate := filters.PickupDateFrom.UTC()
-		turvoFilters.PickupDateGte = utcDate.Format(time.RFC3339)
-	}
-	if filters.PickupDateTo != nil {
-		// Convert to UTC and format
-		utcDate := filters.PickupDateTo.UTC()
-		turvoFilters.PickupDateLte = utcDate.Format(time.RFC3339)
+	// Map the sort if Turvo can apply it
+	if sort, ok := turvoSort(filters.Sort); ok {
+		turvoFilters.SortBy = sort.Field
+		turvoFilters.SortDescending = sort.Descending
 	}
 
-	return turvoFilters
+	// Map date filters: convert to RFC3339 format in UTC
+	turvoFilters.PickupDateGte = turvoDate(filters.PickupDateFrom)
+	turvoFilters.PickupDateLte = turvoDate(filters.PickupDateTo)
+	turvoFilters.DeliveryDateGte = turvoDate(filters.DeliveryDateFrom)
+	turvoFilters.DeliveryDateLte = turvoDate(filters.DeliveryDateTo)
+	turvoFilters.CreatedGte = turvoDate(filters.CreatedFrom)
+	turvoFilters.CreatedLte = turvoDate(filters.CreatedTo)
+	turvoFilters.UpdatedGte = turvoDate(filters.UpdatedFrom)
+	turvoFilters.UpdatedLte = turvoDate(filters.UpdatedTo)
+
+	return turvoFilters, nil
+}
+
+// turvoDate formats a filter date for Turvo, or returns "" if it is not set
+func turvoDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.UTC().Format(time.RFC3339)
 }
 
 // CreateLoad creates a new load in Turvo from a Drumkit load format
-func (s *Service) CreateLoad(load *models.Load) (*models.LoadCreateResponse, error) {
+func (s *Service) CreateLoad(ctx context.Context, load *models.Load) (_ *models.LoadCreateResponse, err error) {
+	ctx, span := s.startSpan(ctx, "load.CreateLoad")
+	defer func() { tracing.End(span, err) }()
+
 	// Validate the load
-	if err := ValidateLoad(load); err != nil {
+	if err := s.validateLoad(load); err != nil {
+		recordValidationFailures(err)
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
 
@@ -164,10 +341,11 @@ func (s *Service) CreateLoad(load *models.Load) (*models.LoadCreateResponse, err
 
 	// Validate required fields before creating shipment
 	if err := ValidateTurvoShipment(turvoShipment); err != nil {
+		recordValidationFailures(err)
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
 
-	response, err := s.turvoClient.CreateShipment(turvoShipment)
+	response, err := s.turvoClient.CreateShipment(ctx, turvoShipment)
 	if err != nil {
 		return nil, err
 	}
@@ -175,6 +353,8 @@ func (s *Service) CreateLoad(load *models.Load) (*models.LoadCreateResponse, err
 	// Return minimal response with only id and createdAt
 	createdAt := time.Now()
 	if response.Details.ID > 0 {
+		metrics.LoadsCreated.Inc()
+		span.SetAttributes(tracing.ShipmentID.Int(response.Details.ID))
 		return &models.LoadCreateResponse{
 			ID:        fmt.Sprintf("%d", response.Details.ID),
 			CreatedAt: createdAt,
@@ -190,20 +370,18 @@ func (s *Service) drumkitToTurvo(load *models.Load) *models.TurvoShipmentCreate
 		LtlShipment: false,
 	}
 
-	// Map status using status mapper
+	// Map status using the profile's status table (unknown statuses are rejected by validateLoad)
 	if load.Status != "" {
-		statusKey, statusValue := APIToTurvoStatus(load.Status)
-		shipment.Status = &models.TurvoCreateStatus{
-			Code: models.TurvoStatusCode{
-				Key:   statusKey,
-				Value: statusValue,
-			},
+		if statusCode, err := s.APIToTurvoStatus("status", load.Status); err == nil {
+			shipment.Status = &models.TurvoCreateStatus{
+				Code: statusCode,
+			}
 		}
 	}
 
 	// Map dates from pickup and consignee
 	// Pickup maps to startDate, Consignee maps to endDate
-	timezone := "America/New_York" // Default timezone
+	timezone := s.profile.DefaultTimezone
 	if load.Pickup.Timezone != "" {
 		timezone = load.Pickup.Timezone
 	}
@@ -284,7 +462,7 @@ func (s *Service) drumkitToTurvo(load *models.Load) *models.TurvoShipmentCreate
 				poNum = strings.TrimSpace(poNum)
 				if poNum != "" {
 					externalIds = append(externalIds, models.TurvoExternalId{
-						Type:  models.TurvoKeyValue{Key: "1400", Value: "Purchase shipment #"},
+						Type:  turvoKeyValue(s.profile.ExternalIDTypes.PurchaseOrder.Code()),
 						Value: poNum,
 					})
 				}
@@ -292,7 +470,7 @@ func (s *Service) drumkitToTurvo(load *models.Load) *models.TurvoShipmentCreate
 		}
 		if load.Customer.RefNumber != "" {
 			externalIds = append(externalIds, models.TurvoExternalId{
-				Type:  models.TurvoKeyValue{Key: "1401", Value: "Reference Number"},
+				Type:  turvoKeyValue(s.profile.ExternalIDTypes.ReferenceNumber.Code()),
 				Value: load.Customer.RefNumber,
 			})
 		}
@@ -334,23 +512,23 @@ func (s *Service) drumkitToTurvo(load *models.Load) *models.TurvoShipmentCreate
 		equipment := models.TurvoEquipment{}
 		if load.TotalWeight != nil {
 			equipment.Weight = *load.TotalWeight
-			equipment.WeightUnits = models.TurvoKeyValue{Key: "1520", Value: "lb"}
+			equipment.WeightUnits = turvoKeyValue(s.profile.Equipment.WeightUnits)
 		}
 		if load.Specifications != nil {
 			if load.Specifications.MinTempFahrenheit != nil && load.Specifications.MaxTempFahrenheit != nil {
 				// Use average temperature
 				avgTemp := (*load.Specifications.MinTempFahrenheit + *load.Specifications.MaxTempFahrenheit) / 2
 				equipment.Temp = avgTemp
-				equipment.TempUnits = models.TurvoKeyValue{Key: "1510", Value: "°F"}
+				equipment.TempUnits = turvoKeyValue(s.profile.Equipment.TempUnits)
 			} else if load.Specifications.MinTempFahrenheit != nil {
 				equipment.Temp = *load.Specifications.MinTempFahrenheit
-				equipment.TempUnits = models.TurvoKeyValue{Key: "1510", Value: "°F"}
+				equipment.TempUnits = turvoKeyValue(s.profile.Equipment.TempUnits)
 			} else if load.Specifications.MaxTempFahrenheit != nil {
 				equipment.Temp = *load.Specifications.MaxTempFahrenheit
-				equipment.TempUnits = models.TurvoKeyValue{Key: "1510", Value: "°F"}
+				equipment.TempUnits = turvoKeyValue(s.profile.Equipment.TempUnits)
 			}
 		}
-		equipment.Type = models.TurvoKeyValue{Key: "1200", Value: "Van"} // Default equipment type
+		equipment.Type = turvoKeyValue(s.profile.Equipment.DefaultType)
 		shipment.Equipment = []models.TurvoEquipment{equipment}
 	}
 
@@ -376,7 +554,7 @@ func (s *Service) turvoToDrumkit(shipment *models.TurvoShipment) models.Load {
 	load := models.Load{
 		ExternalTMSLoadID: idStr,
 		FreightLoadID:     shipment.CustomID,
-		Status:            TurvoStatusToAPI(shipment.Status.Code.Key, shipment.Status.Code.Value),
+		Status:            s.TurvoStatusToAPI(shipment.Status.Code.Key, shipment.Status.Code.Value),
 	}
 
 	// Map customer from customerOrder array
@@ -440,7 +618,7 @@ func (s *Service) turvoDetailsToDrumkit(shipment *models.TurvoShipmentCreateDeta
 
 	// Map status
 	if shipment.Status != nil && shipment.Status.Code.Value != "" {
-		apiStatus := TurvoStatusToAPI(shipment.Status.Code.Key, shipment.Status.Code.Value)
+		apiStatus := s.TurvoStatusToAPI(shipment.Status.Code.Key, shipment.Status.Code.Value)
 		if apiStatus != "" {
 			load.Status = apiStatus
 		}
@@ -517,7 +695,7 @@ func (s *Service) turvoDetailsToDrumkit(shipment *models.TurvoShipmentCreateDeta
 		}
 
 		// Determine if this is pickup or delivery based on stopType
-		isPickup := stop.StopType.Value == "Pickup" || stop.StopType.Key == "1500"
+		isPickup := s.isPickupStop(stop.StopType)
 
 		if isPickup {
 			if load.Pickup == nil {
@@ -599,7 +777,7 @@ func (s *Service) turvoDetailsToDrumkit(shipment *models.TurvoShipmentCreateDeta
 				if routeStop.Deleted {
 					continue
 				}
-				if routeStop.StopType.Value == "Pickup" || routeStop.StopType.Key == "1500" {
+				if s.isPickupStop(routeStop.StopType) {
 					if load.Pickup != nil {
 						if routeStop.Address != nil {
 							load.Pickup.AddressLine1 = routeStop.Address.Line1
@@ -643,9 +821,9 @@ func (s *Service) turvoDetailsToDrumkit(shipment *models.TurvoShipmentCreateDeta
 			poNums := []string{}
 			for _, extId := range custOrder.ExternalIds {
 				if !extId.Deleted {
-					if extId.Type.Key == "1400" || extId.Type.Value == "Purchase shipment #" {
+					if s.profile.ExternalIDTypes.PurchaseOrder.Matches(mappingKeyValue(extId.Type)) {
 						poNums = append(poNums, extId.Value)
-					} else if extId.Type.Key == "1401" || extId.Type.Value == "Reference Number" {
+					} else if s.profile.ExternalIDTypes.ReferenceNumber.Matches(mappingKeyValue(extId.Type)) {
 						if load.Customer != nil {
 							load.Customer.RefNumber = extId.Value
 						}
@@ -736,43 +914,19 @@ func (s *Service) turvoDetailsToDrumkit(shipment *models.TurvoShipmentCreateDeta
 		if stop.Deleted {
 			continue
 		}
-		isPickup := stop.StopType.Value == "Pickup" || stop.StopType.Key == "1500"
+		isPickup := s.isPickupStop(stop.StopType)
 		for _, service := range stop.Services {
-			serviceValue := strings.ToLower(service.Value)
-			if strings.Contains(serviceValue, "liftgate") {
-				if isPickup {
-					val := true
-					load.Specifications.LiftgatePickup = &val
-				} else {
-					val := true
-					load.Specifications.LiftgateDelivery = &val
+			for _, rule := range s.profile.Services {
+				if !rule.MatchesService(mappingKeyValue(service)) {
+					continue
 				}
-			}
-			if strings.Contains(serviceValue, "inside") {
+				setSpecificationFlag(load.Specifications, rule.Flag)
 				if isPickup {
-					val := true
-					load.Specifications.InsidePickup = &val
+					setSpecificationFlag(load.Specifications, rule.Pickup)
 				} else {
-					val := true
-					load.Specifications.InsideDelivery = &val
+					setSpecificationFlag(load.Specifications, rule.Delivery)
 				}
 			}
-			if strings.Contains(serviceValue, "tarp") {
-				val := true
-				load.Specifications.Tarps = &val
-			}
-			if strings.Contains(serviceValue, "hazmat") {
-				val := true
-				load.Specifications.Hazmat = &val
-			}
-			if strings.Contains(serviceValue, "strap") {
-				val := true
-				load.Specifications.Straps = &val
-			}
-			if strings.Contains(serviceValue, "seal") {
-				val := true
-				load.Specifications.Seal = &val
-			}
 		}
 	}
 
@@ -786,15 +940,16 @@ func (s *Service) turvoDetailsToDrumkit(shipment *models.TurvoShipmentCreateDeta
 				for _, extId := range carrierOrder.ExternalIds {
 					if !extId.Deleted {
 						// Map based on external ID type
-						extType := strings.ToLower(extId.Type.Value)
-						if strings.Contains(extType, "truck") || extId.Type.Key == "7605" {
+						extType := mappingKeyValue(extId.Type)
+						types := s.profile.ExternalIDTypes
+						if types.Truck.Matches(extType) {
 							load.Carrier.ExternalTMSTruckId = extId.Value
-						} else if strings.Contains(extType, "trailer") || extId.Type.Key == "7606" {
+						} else if types.Trailer.Matches(extType) {
 							load.Carrier.ExternalTMSTrailerId = extId.Value
-						} else if strings.Contains(extType, "bol") || extId.Type.Key == "7602" {
+						} else if types.BOL.Matches(extType) {
 							// BOL number - could map to sealNumber or keep separate
 							load.Carrier.SealNumber = extId.Value
-						} else if strings.Contains(extType, "pro") || extId.Type.Key == "7603" {
+						} else if types.PRO.Matches(extType) {
 							// PRO number - could map to a field if we have one
 						}
 					}
@@ -832,10 +987,59 @@ func (s *Service) turvoDetailsToDrumkit(shipment *models.TurvoShipmentCreateDeta
 
 	// Map distance from customerOrder totalMiles
 	if len(shipment.CustomerOrder) > 0 && !shipment.CustomerOrder[0].Deleted {
-		if shipment.CustomerOrder[0].TotalMiles > 0 {
-			load.RouteMiles = &shipment.CustomerOrder[0].TotalMiles
+		// Copy the miles, since shipment may be the cached details shared by every request
+		if totalMiles := shipment.CustomerOrder[0].TotalMiles; totalMiles > 0 {
+			load.RouteMiles = &totalMiles
 		}
 	}
 
 	return load
 }
+
+// isPickupStop reports whether a Turvo stop type is a pickup according to the mapping profile
+func (s *Service) isPickupStop(stopType models.TurvoKeyValue) bool {
+	return s.profile.StopTypes.Pickup.Matches(mappingKeyValue(stopType))
+}
+
+// turvoKeyValue converts a mapping profile code to Turvo's key/value type
+func turvoKeyValue(kv mapping.KeyValue) models.TurvoKeyValue {
+	return models.TurvoKeyValue{Key: kv.Key, Value: kv.Value}
+}
+
+// mappingKeyValue converts a Turvo key/value to a mapping profile code
+func mappingKeyValue(kv models.TurvoKeyValue) mapping.KeyValue {
+	return mapping.KeyValue{Key: kv.Key, Value: kv.Value}
+}
+
+// setSpecificationFlag sets the specification field named by flag (see mapping.SpecificationFlags) to true
+func setSpecificationFlag(spec *models.Specifications, flag string) {
+	val := true
+	switch flag {
+	case "liftgatePickup":
+		spec.LiftgatePickup = &val
+	case "liftgateDelivery":
+		spec.LiftgateDelivery = &val
+	case "insidePickup":
+		spec.InsidePickup = &val
+	case "insideDelivery":
+		spec.InsideDelivery = &val
+	case "tarps":
+		spec.Tarps = &val
+	case "oversized":
+		spec.Oversized = &val
+	case "hazmat":
+		spec.Hazmat = &val
+	case "straps":
+		spec.Straps = &val
+	case "permits":
+		spec.Permits = &val
+	case "escorts":
+		spec.Escorts = &val
+	case "seal":
+		spec.Seal = &val
+	case "customBonded":
+		spec.CustomBonded = &val
+	case "labor":
+		spec.Labor = &val
+	}
+}
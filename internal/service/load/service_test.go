@@ -0,0 +1,518 @@
+package load
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/mapping"
+	"github.com/lwlach/turvo-integration-backend/internal/metrics"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newTestService returns a service backed by a fresh fake Turvo API
+func newTestService(t *testing.T, cfg Config) (*Service, *turvotest.Server) {
+	t.Helper()
+	fake := turvotest.NewServer()
+	t.Cleanup(fake.Close)
+
+	client, err := turvo.NewClient(fake.Config())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return NewService(client, cfg), fake
+}
+
+// readLoad reads a load from testdata/fidelity
+func readLoad(t *testing.T, name string) models.Load {
+	t.Helper()
+	data, err := os.ReadFile("testdata/fidelity/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var load models.Load
+	if err := json.Unmarshal(data, &load); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	load.ExternalTMSLoadID = ""
+	return load
+}
+
+// createLoads creates n copies of the minimal load and returns their IDs in creation order
+func createLoads(t *testing.T, service *Service, n int) []string {
+	t.Helper()
+	ids := make([]string, n)
+	for i := range ids {
+		load := readLoad(t, "minimal.json")
+		response, err := service.CreateLoad(context.Background(), &load)
+		if err != nil {
+			t.Fatalf("CreateLoad: %v", err)
+		}
+		ids[i] = response.ID
+	}
+	return ids
+}
+
+func loadIDs(loads []models.Load) []string {
+	ids := make([]string, len(loads))
+	for i, load := range loads {
+		ids[i] = load.ExternalTMSLoadID
+	}
+	return ids
+}
+
+func TestCreateLoad(t *testing.T) {
+	service, fake := newTestService(t, Config{})
+	load := readLoad(t, "complete.json")
+
+	response, err := service.CreateLoad(context.Background(), &load)
+	if err != nil {
+		t.Fatalf("CreateLoad: %v", err)
+	}
+	if response.ID == "" || response.CreatedAt.IsZero() {
+		t.Fatalf("response = %+v, want an ID and a creation time", response)
+	}
+	if got := fake.Requests(turvotest.EndpointCreate); got != 1 {
+		t.Errorf("create requests = %d, want 1", got)
+	}
+
+	// The created shipment reads back with the load's data
+	page, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 20, IncludeDetails: true})
+	if err != nil {
+		t.Fatalf("GetLoads: %v", err)
+	}
+	if len(page.Data) != 1 || page.Data[0].ExternalTMSLoadID != response.ID {
+		t.Fatalf("loads = %v, want [%s]", loadIDs(page.Data), response.ID)
+	}
+	created := page.Data[0]
+	if created.Pickup == nil || created.Pickup.City != load.Pickup.City {
+		t.Errorf("pickup = %+v, want city %q", created.Pickup, load.Pickup.City)
+	}
+	if created.Customer == nil || created.Customer.ExternalTMSId != load.Customer.ExternalTMSId {
+		t.Errorf("customer = %+v, want ID %q", created.Customer, load.Customer.ExternalTMSId)
+	}
+}
+
+func TestCreateLoadErrors(t *testing.T) {
+	t.Run("invalid load not sent", func(t *testing.T) {
+		service, fake := newTestService(t, Config{})
+		load := readLoad(t, "minimal.json")
+		load.Status = "teleported"
+
+		_, err := service.CreateLoad(context.Background(), &load)
+		if err == nil {
+			t.Fatal("CreateLoad accepted an unknown status")
+		}
+		if got := fake.Requests(turvotest.EndpointCreate); got != 0 {
+			t.Errorf("create requests = %d, want 0", got)
+		}
+	})
+
+	t.Run("Turvo error", func(t *testing.T) {
+		service, fake := newTestService(t, Config{})
+		fake.InjectError(turvotest.EndpointCreate, http.StatusInternalServerError, 1)
+		load := readLoad(t, "minimal.json")
+
+		if _, err := service.CreateLoad(context.Background(), &load); err == nil {
+			t.Fatal("CreateLoad succeeded, want the injected error")
+		}
+	})
+}
+
+func TestGetLoadsPagination(t *testing.T) {
+	service, _ := newTestService(t, Config{})
+	ids := createLoads(t, service, 5)
+
+	var listed []string
+	for page := 1; page <= 4; page++ {
+		response, err := service.GetLoads(context.Background(), models.LoadFilters{Page: page, Limit: 2})
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		if response.Pagination.Page != page || response.Pagination.Limit != 2 {
+			t.Errorf("page %d: pagination = %+v", page, response.Pagination)
+		}
+		if want := min(2, max(0, len(ids)-(page-1)*2)); len(response.Data) != want {
+			t.Errorf("page %d: %d loads, want %d", page, len(response.Data), want)
+		}
+		listed = append(listed, loadIDs(response.Data)...)
+	}
+	if strings.Join(listed, ",") != strings.Join(ids, ",") {
+		t.Errorf("listed %v, want %v", listed, ids)
+	}
+}
+
+func TestSearchWithWrongMoreAvailable(t *testing.T) {
+	service, fake := newTestService(t, Config{})
+	ids := createLoads(t, service, 3)
+
+	// A search pages until Turvo returns an empty page, even if it claims there is more
+	fake.SetMoreAvailable(true)
+	response, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 20, OriginCity: "newark"})
+	if err != nil {
+		t.Fatalf("GetLoads: %v", err)
+	}
+	if strings.Join(loadIDs(response.Data), ",") != strings.Join(ids, ",") {
+		t.Errorf("loads = %v, want %v", loadIDs(response.Data), ids)
+	}
+	if response.SearchIncomplete {
+		t.Error("search reported incomplete after Turvo ran out of shipments")
+	}
+	if got := fake.Requests(turvotest.EndpointList); got != 2 {
+		t.Errorf("list requests = %d, want 2 (a full page and an empty one)", got)
+	}
+}
+
+func TestGetLoadsDetailErrors(t *testing.T) {
+	t.Run("transient errors retried", func(t *testing.T) {
+		service, fake := newTestService(t, Config{})
+		createLoads(t, service, 2)
+		fake.InjectError(turvotest.EndpointGet, http.StatusServiceUnavailable, 1)
+
+		response, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 20, IncludeDetails: true})
+		if err != nil {
+			t.Fatalf("GetLoads: %v", err)
+		}
+		if len(response.PartialFailures) != 0 {
+			t.Errorf("partial failures = %+v, want none", response.PartialFailures)
+		}
+	})
+
+	t.Run("failed details fall back to list data", func(t *testing.T) {
+		service, fake := newTestService(t, Config{})
+		ids := createLoads(t, service, 2)
+		fake.InjectError(turvotest.EndpointGet, http.StatusInternalServerError, -1)
+
+		response, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 20, IncludeDetails: true})
+		if err != nil {
+			t.Fatalf("GetLoads: %v", err)
+		}
+		if strings.Join(loadIDs(response.Data), ",") != strings.Join(ids, ",") {
+			t.Errorf("loads = %v, want %v", loadIDs(response.Data), ids)
+		}
+		if len(response.PartialFailures) != len(ids) {
+			t.Fatalf("partial failures = %+v, want one per load", response.PartialFailures)
+		}
+		for i, failure := range response.PartialFailures {
+			if failure.ExternalTMSLoadID != ids[i] || !strings.Contains(failure.Reason, "500") {
+				t.Errorf("failure %d = %+v, want load %s with the 500", i, failure, ids[i])
+			}
+		}
+	})
+
+	t.Run("searched loads without details left out", func(t *testing.T) {
+		service, fake := newTestService(t, Config{})
+		createLoads(t, service, 2)
+		fake.InjectError(turvotest.EndpointGet, http.StatusInternalServerError, -1)
+
+		response, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 20, OriginCity: "Newark"})
+		if err != nil {
+			t.Fatalf("GetLoads: %v", err)
+		}
+		if len(response.Data) != 0 {
+			t.Errorf("loads = %v, want none", loadIDs(response.Data))
+		}
+		for _, failure := range response.PartialFailures {
+			if !strings.HasPrefix(failure.Reason, "not searched, details unavailable: ") {
+				t.Errorf("reason = %q, want it marked as not searched", failure.Reason)
+			}
+		}
+	})
+}
+
+func TestGetLoadsCachesDetails(t *testing.T) {
+	service, fake := newTestService(t, Config{})
+	createLoads(t, service, 2)
+	filters := models.LoadFilters{Page: 1, Limit: 20, IncludeDetails: true}
+
+	for i := 0; i < 2; i++ {
+		if _, err := service.GetLoads(context.Background(), filters); err != nil {
+			t.Fatalf("GetLoads: %v", err)
+		}
+	}
+	if got := fake.Requests(turvotest.EndpointGet); got != 2 {
+		t.Errorf("get requests = %d, want 2 (second list served from the cache)", got)
+	}
+
+	filters.BypassCache = true
+	if _, err := service.GetLoads(context.Background(), filters); err != nil {
+		t.Fatalf("GetLoads: %v", err)
+	}
+	if got := fake.Requests(turvotest.EndpointGet); got != 4 {
+		t.Errorf("get requests = %d, want 4 after bypassing the cache", got)
+	}
+}
+
+func TestRouteMilesNotSharedWithCache(t *testing.T) {
+	service, fake := newTestService(t, Config{})
+	id := fake.AddShipment(models.TurvoShipmentCreateDetails{
+		CustomerOrder: []models.TurvoCustomerOrderResponse{{TotalMiles: 95}},
+	})
+
+	details, err := service.getShipmentDetails(context.Background(), id, false)
+	if err != nil {
+		t.Fatalf("getShipmentDetails: %v", err)
+	}
+	load := service.turvoDetailsToDrumkit(details)
+	if load.RouteMiles == nil || *load.RouteMiles != 95 {
+		t.Fatalf("routeMiles = %v, want 95", load.RouteMiles)
+	}
+
+	*load.RouteMiles = 1
+	cached, _ := service.getShipmentDetails(context.Background(), id, false)
+	if cached.CustomerOrder[0].TotalMiles != 95 {
+		t.Errorf("cached totalMiles = %v after changing a load, want 95", cached.CustomerOrder[0].TotalMiles)
+	}
+}
+
+func TestCarrierExternalIDLabels(t *testing.T) {
+	service := NewService(nil, Config{})
+	externalID := func(key, value, id string) models.TurvoExternalIdResponse {
+		return models.TurvoExternalIdResponse{Type: models.TurvoKeyValue{Key: key, Value: value}, Value: id}
+	}
+	shipment := &models.TurvoShipmentCreateDetails{
+		CarrierOrder: []models.TurvoCarrierOrderResponse{{ExternalIds: []models.TurvoExternalIdResponse{
+			externalID("9001", "Truck number", "T-1"),
+			externalID("7606", "Equipment", "TR-2"),
+			externalID("9003", "Master BOL", "B-3"),
+		}}},
+	}
+
+	load := service.turvoDetailsToDrumkit(shipment)
+	if load.Carrier == nil {
+		t.Fatal("carrier not mapped")
+	}
+	if load.Carrier.ExternalTMSTruckId != "T-1" || load.Carrier.ExternalTMSTrailerId != "TR-2" || load.Carrier.SealNumber != "B-3" {
+		t.Errorf("carrier = truck %q, trailer %q, seal %q; want T-1, TR-2, B-3",
+			load.Carrier.ExternalTMSTruckId, load.Carrier.ExternalTMSTrailerId, load.Carrier.SealNumber)
+	}
+}
+
+// detailsTurvo wraps a Turvo client to slow down GetShipment, fail it for some shipments and
+// record how many calls run at once
+type detailsTurvo struct {
+	TurvoAPI
+	delay   time.Duration
+	failing map[int]bool
+
+	mutex       sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (d *detailsTurvo) GetShipment(ctx context.Context, shipmentID int) (*models.TurvoShipmentCreateDetails, error) {
+	d.mutex.Lock()
+	d.inFlight++
+	d.maxInFlight = max(d.maxInFlight, d.inFlight)
+	d.mutex.Unlock()
+	defer func() {
+		d.mutex.Lock()
+		d.inFlight--
+		d.mutex.Unlock()
+	}()
+
+	time.Sleep(d.delay)
+	if d.failing[shipmentID] {
+		return nil, fmt.Errorf("turvo API error: 500 for shipment %d", shipmentID)
+	}
+	return d.TurvoAPI.GetShipment(ctx, shipmentID)
+}
+
+// newDetailsService returns a service whose GetShipment calls go through a detailsTurvo, with n
+// loads created
+func newDetailsService(t *testing.T, cfg Config, n int) (*Service, *detailsTurvo, []string) {
+	t.Helper()
+	service, _ := newTestService(t, Config{})
+	ids := createLoads(t, service, n)
+	turvo := &detailsTurvo{TurvoAPI: service.turvoClient, failing: map[int]bool{}}
+	return NewService(turvo, cfg), turvo, ids
+}
+
+func TestGetLoadsDetailWorkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		want    int // Most GetShipment calls at once
+	}{
+		{name: "one worker", workers: 1, want: 1},
+		{name: "fewer workers than loads", workers: 3, want: 3},
+		{name: "more workers than loads", workers: 20, want: 6},
+		{name: "default", want: 6},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, turvo, ids := newDetailsService(t, Config{DetailWorkers: test.workers}, 6)
+			turvo.delay = 50 * time.Millisecond
+
+			response, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 20, IncludeDetails: true})
+			if err != nil {
+				t.Fatalf("GetLoads: %v", err)
+			}
+			if strings.Join(loadIDs(response.Data), ",") != strings.Join(ids, ",") {
+				t.Errorf("loads = %v, want %v in list order", loadIDs(response.Data), ids)
+			}
+			if turvo.maxInFlight != test.want {
+				t.Errorf("GetShipment calls at once = %d, want %d", turvo.maxInFlight, test.want)
+			}
+		})
+	}
+}
+
+func TestGetLoadsPartialFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters models.LoadFilters
+	}{
+		{name: "includeDetails", filters: models.LoadFilters{Page: 1, Limit: 20, IncludeDetails: true}},
+		{name: "expand", filters: models.LoadFilters{Page: 1, Limit: 20, Expand: []string{"pickup"}}},
+		{name: "fields", filters: models.LoadFilters{Page: 1, Limit: 20, Fields: []string{"externalTMSLoadID", "pickup.city"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, turvo, ids := newDetailsService(t, Config{DetailWorkers: 2}, 5)
+			failed := []string{ids[1], ids[3]}
+			for _, id := range failed {
+				shipmentID, _ := strconv.Atoi(id)
+				turvo.failing[shipmentID] = true
+			}
+
+			response, err := service.GetLoads(context.Background(), test.filters)
+			if err != nil {
+				t.Fatalf("GetLoads: %v", err)
+			}
+			if strings.Join(loadIDs(response.Data), ",") != strings.Join(ids, ",") {
+				t.Errorf("loads = %v, want all of %v in list order", loadIDs(response.Data), ids)
+			}
+			if len(response.PartialFailures) != len(failed) {
+				t.Fatalf("partial failures = %+v, want %v", response.PartialFailures, failed)
+			}
+			for i, failure := range response.PartialFailures {
+				if failure.ExternalTMSLoadID != failed[i] || !strings.Contains(failure.Reason, "500 for shipment "+failed[i]) {
+					t.Errorf("failure %d = %+v, want load %s with its error", i, failure, failed[i])
+				}
+			}
+
+			// Loads with details have the pickup city, the others only list data
+			for i, load := range response.Data {
+				hasDetails := fieldValue(t, load, "pickup.city") != nil
+				if want := !slices.Contains(failed, ids[i]); hasDetails != want {
+					t.Errorf("load %s has details = %t, want %t", ids[i], hasDetails, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusesFollowProfile(t *testing.T) {
+	set, err := mapping.Parse([]byte(`
+default:
+  statuses:
+    - { api: covered, key: "3102", value: "Carrier assigned" }
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	service, fake := newTestService(t, Config{Profile: set.Default})
+
+	statuses := service.Statuses()
+	covered := slices.IndexFunc(statuses, func(status models.LoadStatus) bool { return status.Status == "covered" })
+	if covered < 0 || statuses[covered].TurvoCode != "3102" {
+		t.Fatalf("statuses = %+v, want covered as 3102", statuses)
+	}
+	draft := slices.IndexFunc(statuses, func(status models.LoadStatus) bool { return status.Status == "draft" })
+	if draft < 0 || !slices.Equal(statuses[draft].Aliases, []string{"pending"}) {
+		t.Errorf("draft = %+v, want the pending alias", statuses[draft])
+	}
+
+	tests := []struct {
+		name   string
+		status string
+		want   string // Status read back, empty if rejected
+	}{
+		{name: "overridden status", status: "covered", want: "covered"},
+		{name: "pending alias", status: "pending", want: "draft"},
+		{name: "Turvo value", status: "Carrier assigned", want: "covered"},
+		{name: "unknown status", status: "teleported"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			load := readLoad(t, "minimal.json")
+			load.Status = test.status
+			before := fake.Requests(turvotest.EndpointCreate)
+
+			response, err := service.CreateLoad(context.Background(), &load)
+			if test.want == "" {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) || !strings.Contains(err.Error(), `status "teleported" is not a supported status`) {
+					t.Errorf("error = %v, want the unknown status rejected", err)
+				}
+				if fake.Requests(turvotest.EndpointCreate) != before {
+					t.Error("load with an unknown status sent to Turvo")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateLoad: %v", err)
+			}
+			id, _ := strconv.Atoi(response.ID)
+			details, err := service.getShipmentDetails(context.Background(), id, true)
+			if err != nil {
+				t.Fatalf("getShipmentDetails: %v", err)
+			}
+			if got := service.turvoDetailsToDrumkit(details).Status; got != test.want {
+				t.Errorf("status = %s, want %s", got, test.want)
+			}
+		})
+	}
+
+	// Filters take the same statuses
+	if _, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 20, Statuses: []string{"teleported"}}); err == nil {
+		t.Error("GetLoads accepted an unknown status filter")
+	}
+}
+
+func TestLoadMetrics(t *testing.T) {
+	service, fake := newTestService(t, Config{})
+	created := testutil.ToFloat64(metrics.LoadsCreated)
+	fallbacks := testutil.ToFloat64(metrics.DetailFallbacks)
+	statusFailures := testutil.ToFloat64(metrics.ValidationFailures.WithLabelValues("status"))
+
+	ids := createLoads(t, service, 2)
+	invalid := readLoad(t, "minimal.json")
+	invalid.Status = "teleported"
+	if _, err := service.CreateLoad(context.Background(), &invalid); err == nil {
+		t.Fatal("CreateLoad accepted an unknown status")
+	}
+	fake.InjectError(turvotest.EndpointCreate, http.StatusInternalServerError, 1)
+	failed := readLoad(t, "minimal.json")
+	if _, err := service.CreateLoad(context.Background(), &failed); err == nil {
+		t.Fatal("CreateLoad succeeded, want the injected error")
+	}
+
+	fake.InjectError(turvotest.EndpointGet, http.StatusNotFound, 1)
+	if _, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 20, IncludeDetails: true}); err != nil {
+		t.Fatalf("GetLoads: %v", err)
+	}
+
+	if got := testutil.ToFloat64(metrics.LoadsCreated) - created; got != float64(len(ids)) {
+		t.Errorf("loads created = %v, want %d (not the rejected or failed loads)", got, len(ids))
+	}
+	if got := testutil.ToFloat64(metrics.ValidationFailures.WithLabelValues("status")) - statusFailures; got != 1 {
+		t.Errorf("status validation failures = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.DetailFallbacks) - fallbacks; got != 1 {
+		t.Errorf("detail fallbacks = %v, want 1", got)
+	}
+}
@@ -0,0 +1,148 @@
+package load
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// Fields loads can be sorted by
+const (
+	SortPickupDate   = "pickupDate"   // Pickup appointment, or ready time without one
+	SortDeliveryDate = "deliveryDate" // Consignee appointment
+	SortCreated      = "created"      // Creation of the shipment in Turvo
+	SortUpdated      = "updated"      // Last update of the shipment in Turvo
+	SortStatus       = "status"       // Status in the order of GET /statuses
+	SortCustomerName = "customerName" // Customer name, ignoring case
+)
+
+// SortFields lists the fields loads can be sorted by
+var SortFields = []string{SortPickupDate, SortDeliveryDate, SortCreated, SortUpdated, SortStatus, SortCustomerName}
+
+// turvoSortFields are the fields Turvo's list endpoint can sort by, one at a time
+var turvoSortFields = map[string]bool{
+	SortPickupDate:   true,
+	SortDeliveryDate: true,
+	SortCreated:      true,
+	SortUpdated:      true,
+}
+
+// turvoSort returns the sort key if Turvo can apply the sort keys itself
+func turvoSort(keys []models.LoadSort) (models.LoadSort, bool) {
+	if len(keys) != 1 || !turvoSortFields[keys[0].Field] {
+		return models.LoadSort{}, false
+	}
+	return keys[0], true
+}
+
+// sortNeedsDetails reports whether sorting by keys needs shipment details; list summaries
+// have no stop dates
+func sortNeedsDetails(keys []models.LoadSort) bool {
+	for _, key := range keys {
+		if key.Field == SortPickupDate || key.Field == SortDeliveryDate {
+			return true
+		}
+	}
+	return false
+}
+
+// scannedLoad is a load with the list summary of its shipment, which has the created and
+// updated times our load format lacks
+type scannedLoad struct {
+	load     models.Load
+	shipment models.TurvoShipment
+}
+
+// sortLoads sorts loads stably by keys, so ties keep Turvo's order. Loads without a value for
+// a key go last in either direction.
+func (s *Service) sortLoads(loads []scannedLoad, keys []models.LoadSort) {
+	statusRank := make(map[string]int, len(s.profile.Statuses))
+	for i, status := range s.profile.Statuses {
+		statusRank[status.API] = i
+	}
+
+	sort.SliceStable(loads, func(i, j int) bool {
+		for _, key := range keys {
+			if c := compareSortKey(loads[i], loads[j], key, statusRank); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+func compareSortKey(a, b scannedLoad, key models.LoadSort, statusRank map[string]int) int {
+	var c int
+	var aMissing, bMissing bool
+	switch key.Field {
+	case SortStatus:
+		x, xFound := statusRank[a.load.Status]
+		y, yFound := statusRank[b.load.Status]
+		aMissing, bMissing, c = !xFound, !yFound, x-y
+	case SortCustomerName:
+		x, y := customerName(a.load), customerName(b.load)
+		aMissing, bMissing, c = x == "", y == "", strings.Compare(x, y)
+	default:
+		x, y := sortTime(a, key.Field), sortTime(b, key.Field)
+		aMissing, bMissing = x == nil, y == nil
+		if !aMissing && !bMissing {
+			c = x.Compare(*y)
+		}
+	}
+
+	switch {
+	case aMissing && bMissing:
+		return 0
+	case aMissing:
+		return 1
+	case bMissing:
+		return -1
+	case key.Descending:
+		return -c
+	default:
+		return c
+	}
+}
+
+func customerName(load models.Load) string {
+	if load.Customer == nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(load.Customer.Name))
+}
+
+// sortTime returns the time of a date sort field, or nil if the load has none
+func sortTime(l scannedLoad, field string) *time.Time {
+	switch field {
+	case SortPickupDate:
+		if l.load.Pickup == nil {
+			return nil
+		}
+		if l.load.Pickup.ApptTime != nil {
+			return l.load.Pickup.ApptTime
+		}
+		return l.load.Pickup.ReadyTime
+	case SortDeliveryDate:
+		if l.load.Consignee == nil {
+			return nil
+		}
+		return l.load.Consignee.ApptTime
+	case SortCreated:
+		return parseTurvoTime(l.shipment.Created, l.shipment.CreatedDate)
+	case SortUpdated:
+		return parseTurvoTime(l.shipment.Updated, l.shipment.LastUpdatedOn)
+	}
+	return nil
+}
+
+// parseTurvoTime parses the first RFC3339 time among values
+func parseTurvoTime(values ...string) *time.Time {
+	for _, value := range values {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
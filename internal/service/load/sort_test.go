@@ -0,0 +1,229 @@
+package load
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+// listTurvo wraps a Turvo client to record the filters of list requests
+type listTurvo struct {
+	TurvoAPI
+
+	mutex   sync.Mutex
+	filters []models.TurvoShipmentFilters
+}
+
+func (l *listTurvo) ListShipmentsWithFiltersAndPagination(ctx context.Context, filters models.TurvoShipmentFilters) ([]models.TurvoShipment, models.TurvoPagination, error) {
+	l.mutex.Lock()
+	l.filters = append(l.filters, filters)
+	l.mutex.Unlock()
+	return l.TurvoAPI.ListShipmentsWithFiltersAndPagination(ctx, filters)
+}
+
+// sortLoad describes a load created for the sort tests
+type sortLoad struct {
+	customer string
+	status   string
+	pickup   string // Pickup appointment, or empty for a ready time of 2025-01-20 only
+	delivery string // Consignee appointment
+}
+
+// newSortService creates the loads and returns a service recording its list requests
+func newSortService(t *testing.T, cfg Config, loads []sortLoad) (*Service, *listTurvo, *turvotest.Server) {
+	t.Helper()
+	service, fake := newTestService(t, Config{})
+	for _, spec := range loads {
+		load := readLoad(t, "minimal.json")
+		load.Customer.Name = spec.customer
+		load.Status = spec.status
+		load.Pickup.ApptTime = parseTime(t, spec.pickup)
+		load.Pickup.ReadyTime = nil
+		if spec.pickup == "" {
+			readyTime := time.Date(2025, 1, 20, 8, 0, 0, 0, time.UTC)
+			load.Pickup.ReadyTime = &readyTime
+		}
+		load.Consignee.ApptTime = parseTime(t, spec.delivery)
+		if _, err := service.CreateLoad(context.Background(), &load); err != nil {
+			t.Fatalf("CreateLoad: %v", err)
+		}
+	}
+	turvo := &listTurvo{TurvoAPI: service.turvoClient}
+	return NewService(turvo, cfg), turvo, fake
+}
+
+func parseTime(t *testing.T, value string) *time.Time {
+	t.Helper()
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &parsed
+}
+
+// customers returns the customer names of the loads, in order
+func customers(t *testing.T, response *models.LoadListResponse) string {
+	t.Helper()
+	names := make([]string, len(response.Data))
+	for i, load := range response.Data {
+		if load.Customer == nil {
+			t.Fatalf("load %s has no customer", load.ExternalTMSLoadID)
+		}
+		names[i] = load.Customer.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// sortTestLoads in creation order
+var sortTestLoads = []sortLoad{
+	{customer: "Bravo", status: "delivered", pickup: "2025-01-27T08:00:00Z", delivery: "2025-01-29T08:00:00Z"},
+	{customer: "alpha", status: "covered", pickup: "2025-01-25T08:00:00Z", delivery: "2025-01-30T08:00:00Z"},
+	{customer: "Delta", status: "tendered", delivery: "2025-01-28T08:00:00Z"},
+	{customer: "Charlie", status: "covered", pickup: "2025-01-26T08:00:00Z", delivery: "2025-01-31T08:00:00Z"},
+}
+
+func TestSortByTurvo(t *testing.T) {
+	tests := []struct {
+		name       string
+		sort       []models.LoadSort
+		sortBy     string
+		descending bool
+		want       string
+	}{
+		{name: "pickup date", sort: []models.LoadSort{{Field: SortPickupDate}}, sortBy: "pickupDate", want: "Delta,alpha,Charlie,Bravo"},
+		{name: "pickup date descending", sort: []models.LoadSort{{Field: SortPickupDate, Descending: true}}, sortBy: "pickupDate", descending: true, want: "Bravo,Charlie,alpha,Delta"},
+		{name: "delivery date", sort: []models.LoadSort{{Field: SortDeliveryDate}}, sortBy: "deliveryDate", want: "Delta,Bravo,alpha,Charlie"},
+		{name: "created descending", sort: []models.LoadSort{{Field: SortCreated, Descending: true}}, sortBy: "created", descending: true, want: "Charlie,Delta,alpha,Bravo"},
+		{name: "no sort", want: "Bravo,alpha,Delta,Charlie"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, turvo, _ := newSortService(t, Config{}, sortTestLoads)
+
+			response, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 2, Limit: 2, Sort: test.sort, IncludeDetails: true})
+			if err != nil {
+				t.Fatalf("GetLoads: %v", err)
+			}
+			// One request for the page, sorted by Turvo
+			if len(turvo.filters) != 1 {
+				t.Fatalf("%d list requests, want 1", len(turvo.filters))
+			}
+			filters := turvo.filters[0]
+			if filters.SortBy != test.sortBy || filters.SortDescending != test.descending || filters.Start != 2 || filters.PageSize != 2 {
+				t.Errorf("Turvo filters = sortBy %q, descending %t, start %d, pageSize %d; want %q, %t, 2, 2",
+					filters.SortBy, filters.SortDescending, filters.Start, filters.PageSize, test.sortBy, test.descending)
+			}
+			want := strings.Join(strings.Split(test.want, ",")[2:], ",")
+			if got := customers(t, response); got != want {
+				t.Errorf("page 2 = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSortInMemory(t *testing.T) {
+	tests := []struct {
+		name string
+		sort []models.LoadSort
+		want string
+	}{
+		{name: "status in table order", sort: []models.LoadSort{{Field: SortStatus}}, want: "Delta,alpha,Charlie,Bravo"},
+		{name: "status descending", sort: []models.LoadSort{{Field: SortStatus, Descending: true}}, want: "Bravo,alpha,Charlie,Delta"},
+		{name: "customer name ignoring case", sort: []models.LoadSort{{Field: SortCustomerName}}, want: "alpha,Bravo,Charlie,Delta"},
+		{name: "customer name descending", sort: []models.LoadSort{{Field: SortCustomerName, Descending: true}}, want: "Delta,Charlie,Bravo,alpha"},
+		{name: "status then pickup date", sort: []models.LoadSort{{Field: SortStatus}, {Field: SortPickupDate, Descending: true}}, want: "Delta,Charlie,alpha,Bravo"},
+		{name: "two date keys", sort: []models.LoadSort{{Field: SortDeliveryDate}, {Field: SortPickupDate}}, want: "Delta,Bravo,alpha,Charlie"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, turvo, _ := newSortService(t, Config{}, sortTestLoads)
+
+			var pages []string
+			for page := 1; page <= 2; page++ {
+				response, err := service.GetLoads(context.Background(), models.LoadFilters{Page: page, Limit: 2, Sort: test.sort})
+				if err != nil {
+					t.Fatalf("GetLoads: %v", err)
+				}
+				if response.Pagination.Total != 4 || response.Pagination.Pages != 2 || response.SearchIncomplete {
+					t.Errorf("pagination = %+v, incomplete %t; want 4 loads on 2 pages", response.Pagination, response.SearchIncomplete)
+				}
+				pages = append(pages, customers(t, response))
+			}
+			if got := strings.Join(pages, ","); got != test.want {
+				t.Errorf("sorted = %s, want %s", got, test.want)
+			}
+			// Every shipment is scanned from the start, unsorted by Turvo
+			for _, filters := range turvo.filters {
+				if filters.SortBy != "" || filters.Start != 0 {
+					t.Errorf("Turvo filters = sortBy %q, start %d; want Turvo's order from the start", filters.SortBy, filters.Start)
+				}
+			}
+		})
+	}
+}
+
+func TestSortInMemoryMissingValues(t *testing.T) {
+	service, _, fake := newSortService(t, Config{}, nil)
+	// Shipments without dates, which loads created through the API always have
+	ids := make([]string, 4)
+	for i, endDate := range []string{"2025-01-29T08:00:00Z", "", "2025-01-28T08:00:00Z", ""} {
+		ids[i] = strconv.Itoa(fake.AddShipment(models.TurvoShipmentCreateDetails{
+			EndDate: models.TurvoDateWithTimezoneAndFlex{Date: endDate},
+		}))
+	}
+
+	tests := []struct {
+		name string
+		sort []models.LoadSort
+		want []string
+	}{
+		// Loads without a delivery date go last either way, and those without a pickup date tie
+		{name: "ascending", sort: []models.LoadSort{{Field: SortDeliveryDate}, {Field: SortPickupDate}}, want: []string{ids[2], ids[0], ids[1], ids[3]}},
+		{name: "descending", sort: []models.LoadSort{{Field: SortDeliveryDate, Descending: true}, {Field: SortPickupDate}}, want: []string{ids[0], ids[2], ids[1], ids[3]}},
+		{name: "ties keep Turvo's order", sort: []models.LoadSort{{Field: SortPickupDate}, {Field: SortStatus, Descending: true}}, want: ids},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 10, Sort: test.sort})
+			if err != nil {
+				t.Fatalf("GetLoads: %v", err)
+			}
+			if got := loadIDs(response.Data); !slices.Equal(got, test.want) {
+				t.Errorf("sorted = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSortInMemoryScanLimit(t *testing.T) {
+	service, turvo, _ := newSortService(t, Config{SearchScanLimit: 3}, sortTestLoads)
+
+	response, err := service.GetLoads(context.Background(), models.LoadFilters{Page: 1, Limit: 10, Sort: []models.LoadSort{{Field: SortCustomerName}}})
+	if err != nil {
+		t.Fatalf("GetLoads: %v", err)
+	}
+	// Only the scanned loads are sorted, and the response says so
+	if got := customers(t, response); got != "alpha,Bravo,Delta" {
+		t.Errorf("sorted = %s, want the first 3 shipments sorted", got)
+	}
+	if !response.SearchIncomplete {
+		t.Error("search not reported incomplete at the scan limit")
+	}
+	scanned := 0
+	for _, filters := range turvo.filters {
+		scanned += filters.PageSize
+	}
+	if scanned != 3 {
+		t.Errorf("requested %d shipments, want 3", scanned)
+	}
+}
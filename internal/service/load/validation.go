@@ -1,12 +1,24 @@
 package load
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 
+	"github.com/lwlach/turvo-integration-backend/internal/metrics"
 	"github.com/lwlach/turvo-integration-backend/internal/models"
 )
 
+// ValidationError reports a request that cannot be sent to Turvo as given.
+// Handlers answer it with 400 Bad Request instead of 500.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("validation failed: %v", e.Problems)
+}
+
 // ValidateTurvoShipment validates required fields for Turvo shipment creation
 func ValidateTurvoShipment(shipment *models.TurvoShipmentCreate) error {
 	var errors []string
@@ -47,12 +59,14 @@ func ValidateTurvoShipment(shipment *models.TurvoShipmentCreate) error {
 	}
 
 	if len(errors) > 0 {
-		return fmt.Errorf("validation failed: %v", errors)
+		return &ValidationError{Problems: errors}
 	}
 
 	return nil
 }
 
+// ValidateLoad validates the fields a load needs for mapping to a Turvo shipment.
+// Statuses are checked by the service, since the status table depends on the mapping profile.
 func ValidateLoad(load *models.Load) error {
 	var errors []string
 
@@ -114,8 +128,34 @@ func ValidateLoad(load *models.Load) error {
 	}
 
 	if len(errors) > 0 {
-		return fmt.Errorf("validation failed: %v", errors)
+		return &ValidationError{Problems: errors}
+	}
+
+	return nil
+}
+
+// validateLoad runs ValidateLoad and the profile-dependent checks, reporting all problems together
+func (s *Service) validateLoad(load *models.Load) error {
+	var problems []string
+	if err := ValidateLoad(load); err != nil {
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			return err
+		}
+		problems = append(problems, validationErr.Problems...)
 	}
+	problems = append(problems, s.validateStatus(load)...)
 
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
 	return nil
 }
+
+// recordValidationFailures counts the invalid fields of a rejected load
+func recordValidationFailures(err error) {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		metrics.RecordValidationFailures(validationErr.Problems)
+	}
+}
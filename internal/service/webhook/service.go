@@ -0,0 +1,242 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/notify"
+	"github.com/lwlach/turvo-integration-backend/internal/service/load"
+)
+
+// How long processed event IDs are remembered for duplicate detection.
+// Turvo retries failed deliveries for up to a day.
+const seenEventTTL = 24 * time.Hour
+
+// Result tells what HandleEvent did with an event
+type Result int
+
+const (
+	Applied   Result = iota
+	Duplicate        // Already processed, nothing done
+	Outdated         // Occurred before an event already applied to the shipment, nothing done
+)
+
+// Service handles webhook events for every tenant; the caller passes the tenant's load service
+type Service struct {
+	notifier notify.Notifier
+
+	// Processed event IDs and when they were processed
+	seenEvents map[string]time.Time
+	lastSweep  time.Time
+	seenMutex  sync.Mutex
+
+	// When the last event applied to a shipment occurred, by tenant, shipment and kind of
+	// event. Held while an event is applied, so events for a shipment apply in order.
+	applied      map[string]appliedEvent
+	appliedSweep time.Time
+	appliedMutex sync.Mutex
+}
+
+type appliedEvent struct {
+	occurredAt time.Time
+	appliedAt  time.Time
+}
+
+func NewService(notifier notify.Notifier) *Service {
+	return &Service{
+		notifier:   notifier,
+		seenEvents: make(map[string]time.Time),
+		applied:    make(map[string]appliedEvent),
+	}
+}
+
+// ParseEvent parses a raw Turvo webhook body into a typed event
+func ParseEvent(body []byte) (*models.TurvoWebhookEvent, error) {
+	var envelope models.TurvoWebhookEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid event body: %w", err)
+	}
+
+	if envelope.EventID == "" {
+		return nil, fmt.Errorf("eventId is required")
+	}
+	if envelope.ShipmentID == 0 {
+		return nil, fmt.Errorf("shipmentId is required")
+	}
+
+	event := &models.TurvoWebhookEvent{
+		EventID:    envelope.EventID,
+		EventType:  envelope.EventType,
+		OccurredAt: envelope.OccurredAt,
+		ShipmentID: envelope.ShipmentID,
+	}
+
+	switch envelope.EventType {
+	case models.TurvoEventShipmentCreated, models.TurvoEventShipmentUpdated,
+		models.TurvoEventShipmentCanceled, models.TurvoEventShipmentDeleted:
+		var payload models.TurvoShipmentEvent
+		if len(envelope.Payload) > 0 {
+			if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("invalid shipment event payload: %w", err)
+			}
+		}
+		event.Shipment = &payload
+	case models.TurvoEventStatusChanged:
+		var payload models.TurvoStatusEvent
+		if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("invalid status event payload: %w", err)
+		}
+		if payload.Status.Code.Key == "" && payload.Status.Code.Value == "" {
+			return nil, fmt.Errorf("status event is missing status.code")
+		}
+		event.Status = &payload
+	case models.TurvoEventLocationUpdated:
+		var payload models.TurvoLocationEvent
+		if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("invalid location event payload: %w", err)
+		}
+		event.Location = &payload
+	default:
+		return nil, fmt.Errorf("unsupported event type: %q", envelope.EventType)
+	}
+
+	return event, nil
+}
+
+// HandleEvent routes a parsed event to the load service and notifies our consumers.
+// Event IDs are only unique within a Turvo account, so duplicates are detected per tenant.
+//
+// Turvo retries failed deliveries, so events can arrive out of order. An event that occurred
+// before the last one applied to the shipment is dropped, so it cannot overwrite newer state.
+// Location events are ordered on their own, since they do not change the load. Events without
+// a valid occurredAt are applied but do not count as the last one.
+func (s *Service) HandleEvent(ctx context.Context, tenantID string, loadService *load.Service, event *models.TurvoWebhookEvent) (Result, error) {
+	seenKey := tenantID + "/" + event.EventID
+	if !s.markSeen(seenKey) {
+		return Duplicate, nil
+	}
+
+	occurredAt, timed := parseOccurredAt(ctx, event.OccurredAt)
+	notification := notify.Notification{
+		TenantID:   tenantID,
+		EventID:    event.EventID,
+		LoadID:     fmt.Sprintf("%d", event.ShipmentID),
+		OccurredAt: occurredAt,
+	}
+
+	kind := "state"
+	if event.Location != nil {
+		kind = "location"
+	}
+	appliedKey := fmt.Sprintf("%s/%d/%s", tenantID, event.ShipmentID, kind)
+	s.appliedMutex.Lock()
+	if last, found := s.applied[appliedKey]; timed && found && occurredAt.Before(last.occurredAt) {
+		s.appliedMutex.Unlock()
+		slog.InfoContext(ctx, "dropped outdated webhook event", "tenant_id", tenantID, "event_id", event.EventID,
+			"shipment_id", event.ShipmentID, "occurred_at", occurredAt, "last_applied_occurred_at", last.occurredAt)
+		return Outdated, nil
+	}
+
+	switch {
+	case event.Shipment != nil:
+		updatedLoad := loadService.ApplyShipmentEvent(event)
+		notification.Type = notify.TypeLoadUpdated
+		if event.EventType == models.TurvoEventShipmentDeleted {
+			notification.Type = notify.TypeLoadRemoved
+		}
+		notification.Load = updatedLoad
+		if updatedLoad != nil {
+			notification.Status = updatedLoad.Status
+		}
+	case event.Status != nil:
+		updatedLoad, previousStatus := loadService.ApplyStatusEvent(event)
+		notification.Type = notify.TypeStatusChanged
+		notification.Load = updatedLoad
+		notification.Status = loadService.TurvoStatusToAPI(event.Status.Status.Code.Key, event.Status.Status.Code.Value)
+		notification.PreviousStatus = previousStatus
+	case event.Location != nil:
+		notification.Type = notify.TypeLocation
+		notification.Load = loadService.ApplyLocationEvent(event)
+		notification.Location = &event.Location.Location
+	}
+	if timed {
+		s.markApplied(appliedKey, occurredAt)
+	}
+	s.appliedMutex.Unlock()
+
+	loadService.RecordEvent(notification.OccurredAt)
+
+	if err := s.notifier.Notify(ctx, notification); err != nil {
+		// Forget the event so Turvo's retry gets another chance to notify
+		s.forget(seenKey)
+		return Applied, fmt.Errorf("failed to notify: %w", err)
+	}
+
+	return Applied, nil
+}
+
+// markApplied records when the last event applied under key occurred. The caller holds
+// appliedMutex.
+func (s *Service) markApplied(key string, occurredAt time.Time) {
+	now := time.Now()
+
+	// Shipments without events for as long as Turvo retries cannot get outdated ones anymore
+	if now.Sub(s.appliedSweep) > time.Minute {
+		for key, applied := range s.applied {
+			if now.Sub(applied.appliedAt) > seenEventTTL {
+				delete(s.applied, key)
+			}
+		}
+		s.appliedSweep = now
+	}
+
+	s.applied[key] = appliedEvent{occurredAt: occurredAt, appliedAt: now}
+}
+
+// markSeen records the event key and returns false if it had already been recorded
+func (s *Service) markSeen(key string) bool {
+	s.seenMutex.Lock()
+	defer s.seenMutex.Unlock()
+
+	now := time.Now()
+
+	// Drop expired entries so the map does not grow without bound (at most once a minute)
+	if now.Sub(s.lastSweep) > time.Minute {
+		for id, seenAt := range s.seenEvents {
+			if now.Sub(seenAt) > seenEventTTL {
+				delete(s.seenEvents, id)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if _, found := s.seenEvents[key]; found {
+		return false
+	}
+	s.seenEvents[key] = now
+	return true
+}
+
+// forget removes an event key so that a redelivery is processed again
+func (s *Service) forget(key string) {
+	s.seenMutex.Lock()
+	delete(s.seenEvents, key)
+	s.seenMutex.Unlock()
+}
+
+// parseOccurredAt parses the event time, falling back to now when missing or invalid; ok
+// reports whether the event had a valid time
+func parseOccurredAt(ctx context.Context, occurredAt string) (t time.Time, ok bool) {
+	if occurredAt != "" {
+		if t, err := time.Parse(time.RFC3339, occurredAt); err == nil {
+			return t, true
+		}
+		slog.WarnContext(ctx, "invalid webhook occurredAt, using current time", "occurred_at", occurredAt)
+	}
+	return time.Now().UTC(), false
+}
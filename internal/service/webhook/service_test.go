@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/lwlach/turvo-integration-backend/internal/notify"
+	"github.com/lwlach/turvo-integration-backend/internal/service/load"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+const shipmentID = 1000000001
+
+// recordingNotifier records notifications and fails the next failures calls
+type recordingNotifier struct {
+	mutex         sync.Mutex
+	notifications []notify.Notification
+	failures      int
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification notify.Notification) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.failures > 0 {
+		n.failures--
+		return errors.New("consumer unavailable")
+	}
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func newTestService(t *testing.T) (*Service, *load.Service, *recordingNotifier) {
+	t.Helper()
+	fake := turvotest.NewServer()
+	t.Cleanup(fake.Close)
+	client, err := turvo.NewClient(fake.Config())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	notifier := &recordingNotifier{}
+	return NewService(notifier), load.NewService(client, load.Config{}), notifier
+}
+
+// handle parses a raw event and handles it for tenant "acme"
+func handle(t *testing.T, service *Service, loads *load.Service, body string) Result {
+	t.Helper()
+	event, err := ParseEvent([]byte(body))
+	if err != nil {
+		t.Fatalf("ParseEvent: %v", err)
+	}
+	result, err := service.HandleEvent(context.Background(), "acme", loads, event)
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	return result
+}
+
+func shipmentEvent(eventID, occurredAt, statusKey, statusValue string) string {
+	return fmt.Sprintf(`{"eventId":%q,"eventType":"SHIPMENT_UPDATED","occurredAt":%q,"shipmentId":%d,
+		"payload":{"shipment":{"id":%d,"status":{"code":{"key":%q,"value":%q}}}}}`,
+		eventID, occurredAt, shipmentID, shipmentID, statusKey, statusValue)
+}
+
+func statusEvent(eventID, occurredAt, statusKey, statusValue string) string {
+	return fmt.Sprintf(`{"eventId":%q,"eventType":"SHIPMENT_STATUS_CHANGED","occurredAt":%q,"shipmentId":%d,
+		"payload":{"status":{"code":{"key":%q,"value":%q}}}}`,
+		eventID, occurredAt, shipmentID, statusKey, statusValue)
+}
+
+func locationEvent(eventID, occurredAt, city string) string {
+	return fmt.Sprintf(`{"eventId":%q,"eventType":"SHIPMENT_LOCATION_UPDATED","occurredAt":%q,"shipmentId":%d,
+		"payload":{"location":{"lat":40.7,"lon":-74.2,"city":%q}}}`,
+		eventID, occurredAt, shipmentID, city)
+}
+
+func cachedStatus(t *testing.T, loads *load.Service) string {
+	t.Helper()
+	cached, found := loads.GetCachedLoad(shipmentID)
+	if !found {
+		t.Fatal("shipment not cached")
+	}
+	return cached.Status
+}
+
+func TestHandleEventDuplicate(t *testing.T) {
+	service, loads, notifier := newTestService(t)
+	body := shipmentEvent("evt-1", "2025-01-27T09:00:00Z", "2102", "Covered")
+
+	if got := handle(t, service, loads, body); got != Applied {
+		t.Fatalf("first delivery = %v, want Applied", got)
+	}
+	if got := handle(t, service, loads, body); got != Duplicate {
+		t.Errorf("redelivery = %v, want Duplicate", got)
+	}
+	if len(notifier.notifications) != 1 {
+		t.Errorf("%d notifications, want 1", len(notifier.notifications))
+	}
+
+	// Event IDs are unique per Turvo account only
+	event, _ := ParseEvent([]byte(body))
+	if got, err := service.HandleEvent(context.Background(), "globex", loads, event); err != nil || got != Applied {
+		t.Errorf("same event ID for another tenant = %v, %v, want Applied", got, err)
+	}
+}
+
+func TestHandleEventNotifyFailure(t *testing.T) {
+	service, loads, notifier := newTestService(t)
+	notifier.failures = 1
+	event, err := ParseEvent([]byte(shipmentEvent("evt-1", "2025-01-27T09:00:00Z", "2102", "Covered")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.HandleEvent(context.Background(), "acme", loads, event); err == nil {
+		t.Fatal("HandleEvent succeeded, want the notify error")
+	}
+	// The event is forgotten, so Turvo's retry notifies
+	result, err := service.HandleEvent(context.Background(), "acme", loads, event)
+	if err != nil || result != Applied {
+		t.Fatalf("retry = %v, %v, want Applied", result, err)
+	}
+	if len(notifier.notifications) != 1 || notifier.notifications[0].EventID != "evt-1" {
+		t.Errorf("notifications = %+v, want evt-1 once", notifier.notifications)
+	}
+}
+
+func TestHandleEventOutOfOrder(t *testing.T) {
+	service, loads, notifier := newTestService(t)
+	handle(t, service, loads, shipmentEvent("evt-1", "2025-01-27T09:00:00Z", "2102", "Covered"))
+	handle(t, service, loads, statusEvent("evt-3", "2025-01-27T11:00:00Z", "2107", "Delivered"))
+
+	tests := []struct {
+		name   string
+		body   string
+		result Result
+		status string // Cached status afterwards
+	}{
+		{name: "older status event", body: statusEvent("evt-2", "2025-01-27T10:00:00Z", "2103", "Dispatched"), result: Outdated, status: "delivered"},
+		{name: "older shipment event", body: shipmentEvent("evt-0", "2025-01-27T08:00:00Z", "2101", "Tendered"), result: Outdated, status: "delivered"},
+		{name: "location event older than the status", body: locationEvent("evt-4", "2025-01-27T10:30:00Z", "Newark"), result: Applied, status: "delivered"},
+		{name: "older location event", body: locationEvent("evt-5", "2025-01-27T10:15:00Z", "Edison"), result: Outdated, status: "delivered"},
+		{name: "event at the same time", body: statusEvent("evt-6", "2025-01-27T11:00:00Z", "2106", "At delivery"), result: Applied, status: "at_delivery"},
+		{name: "event without occurredAt", body: statusEvent("evt-7", "", "2107", "Delivered"), result: Applied, status: "delivered"},
+		{name: "newer event after one without occurredAt", body: statusEvent("evt-8", "2025-01-27T11:30:00Z", "2106", "At delivery"), result: Applied, status: "at_delivery"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := len(notifier.notifications)
+			if got := handle(t, service, loads, test.body); got != test.result {
+				t.Fatalf("result = %v, want %v", got, test.result)
+			}
+			if got := cachedStatus(t, loads); got != test.status {
+				t.Errorf("cached status = %s, want %s", got, test.status)
+			}
+			notified := len(notifier.notifications) > before
+			if notified != (test.result == Applied) {
+				t.Errorf("notified = %t, want %t", notified, test.result == Applied)
+			}
+		})
+	}
+}
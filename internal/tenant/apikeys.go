@@ -0,0 +1,167 @@
+package tenant
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/auth"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// APIKey is a tenant API key. Only its hash is kept; the key itself is shown once when issued.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Hash      string    `json:"hash"` // auth.HashAPIKey of the key
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// IssueAPIKey creates a new API key for a tenant and returns it with the key in plain text.
+// Without scopes the key gets all tenant scopes. With revokeExisting, the tenant's other keys
+// stop working.
+func (r *Registry) IssueAPIKey(tenantID string, scopes []string, revokeExisting bool) (APIKey, string, error) {
+	if len(scopes) == 0 {
+		scopes = auth.TenantScopes
+	}
+	if err := validateScopes(scopes); err != nil {
+		return APIKey{}, "", err
+	}
+
+	key, apiKey, err := newAPIKey(scopes, time.Now().UTC())
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tenant, found := r.tenants[tenantID]
+	if !found {
+		return APIKey{}, "", ErrNotFound
+	}
+
+	previous := tenant.apiKeys
+	var keys []APIKey
+	if !revokeExisting {
+		keys = append(keys, previous...)
+	}
+	r.setAPIKeys(tenant, append(keys, key))
+
+	if err := r.save(); err != nil {
+		r.setAPIKeys(tenant, previous)
+		return APIKey{}, "", err
+	}
+	return key, apiKey, nil
+}
+
+// RevokeAPIKey removes one API key of a tenant
+func (r *Registry) RevokeAPIKey(tenantID, keyID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tenant, found := r.tenants[tenantID]
+	if !found {
+		return ErrNotFound
+	}
+
+	previous := tenant.apiKeys
+	var keys []APIKey
+	for _, key := range previous {
+		if key.ID != keyID {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == len(previous) {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, keyID)
+	}
+	r.setAPIKeys(tenant, keys)
+
+	if err := r.save(); err != nil {
+		r.setAPIKeys(tenant, previous)
+		return err
+	}
+	return nil
+}
+
+// LookupAPIKey implements auth.KeyStore: tenant keys authenticate as bound to their tenant
+func (r *Registry) LookupAPIKey(hash string) (*auth.Principal, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tenant, found := r.keyIndex[hash]
+	if !found {
+		return nil, false
+	}
+	for _, key := range tenant.apiKeys {
+		if key.Hash == hash {
+			return &auth.Principal{
+				Method:   auth.MethodAPIKey,
+				ID:       key.ID,
+				TenantID: tenant.ID,
+				Scopes:   key.Scopes,
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// setAPIKeys replaces a tenant's keys and updates the index; the caller must hold the mutex
+func (r *Registry) setAPIKeys(tenant *Tenant, keys []APIKey) {
+	for _, key := range tenant.apiKeys {
+		delete(r.keyIndex, key.Hash)
+	}
+	tenant.apiKeys = keys
+	for _, key := range keys {
+		r.keyIndex[key.Hash] = tenant
+	}
+}
+
+func apiKeyInfos(keys []APIKey) []models.APIKeyInfo {
+	infos := make([]models.APIKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		infos = append(infos, models.APIKeyInfo{
+			ID:        key.ID,
+			Scopes:    key.Scopes,
+			CreatedAt: key.CreatedAt,
+		})
+	}
+	return infos
+}
+
+// validateScopes only allows the scopes of tenant-bound credentials
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		allowed := false
+		for _, tenantScope := range auth.TenantScopes {
+			allowed = allowed || scope == tenantScope
+		}
+		if !allowed {
+			return fmt.Errorf("%w: scope %q cannot be granted to a tenant API key (allowed: %s)",
+				ErrInvalid, scope, strings.Join(auth.TenantScopes, ", "))
+		}
+	}
+	return nil
+}
+
+// newAPIKey returns a random API key and its record
+func newAPIKey(scopes []string, createdAt time.Time) (APIKey, string, error) {
+	secret := make([]byte, 32)
+	id := make([]byte, 4)
+	if _, err := rand.Read(secret); err != nil {
+		return APIKey{}, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	if _, err := rand.Read(id); err != nil {
+		return APIKey{}, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	apiKey := "tk_" + hex.EncodeToString(secret)
+	return APIKey{
+		ID:        "key_" + hex.EncodeToString(id),
+		Hash:      auth.HashAPIKey(apiKey),
+		Scopes:    scopes,
+		CreatedAt: createdAt,
+	}, apiKey, nil
+}
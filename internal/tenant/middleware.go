@@ -0,0 +1,69 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lwlach/turvo-integration-backend/internal/auth"
+	"github.com/lwlach/turvo-integration-backend/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IDHeader selects a tenant by ID
+const IDHeader = "X-Tenant-ID"
+
+type contextKey struct{}
+
+// WithTenant returns a context carrying the tenant
+func WithTenant(ctx context.Context, tenant *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant selected for the request
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	tenant, ok := ctx.Value(contextKey{}).(*Tenant)
+	return tenant, ok
+}
+
+// Middleware selects the tenant of each request from its authenticated principal (see
+// auth.Middleware) and stores it in the request context:
+//   - credentials bound to a tenant select that tenant; an X-Tenant-ID header, if sent,
+//     must name the same tenant
+//   - credentials not bound to a tenant need auth.ScopeTenantsAny and select the tenant in
+//     X-Tenant-ID, or the default tenant without it
+func Middleware(registry *Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.FromContext(r.Context())
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			tenantID := r.Header.Get(IDHeader)
+			switch {
+			case principal.TenantID != "":
+				if tenantID != "" && tenantID != principal.TenantID {
+					auth.Audit(r, auth.AuditForbidden, principal, "credentials are not valid for tenant "+tenantID)
+					http.Error(w, "forbidden: credentials are not valid for tenant "+tenantID, http.StatusForbidden)
+					return
+				}
+				tenantID = principal.TenantID
+			case !principal.HasScope(auth.ScopeTenantsAny):
+				auth.Audit(r, auth.AuditForbidden, principal, "credentials are not bound to a tenant")
+				http.Error(w, "forbidden: credentials are not bound to a tenant", http.StatusForbidden)
+				return
+			case tenantID == "":
+				tenantID = registry.DefaultID()
+			}
+
+			tenant, found := registry.Get(tenantID)
+			if !found {
+				http.Error(w, "unknown tenant: "+tenantID, http.StatusNotFound)
+				return
+			}
+			trace.SpanFromContext(r.Context()).SetAttributes(tracing.TenantID.String(tenant.ID))
+			next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), tenant)))
+		})
+	}
+}
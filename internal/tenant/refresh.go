@@ -0,0 +1,35 @@
+package tenant
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RefreshTokens refreshes the Turvo token of every tenant that expires within margin, checking
+// every interval until ctx is done
+func (r *Registry) RefreshTokens(ctx context.Context, interval, margin time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, tenant := range r.List() {
+			if ctx.Err() != nil {
+				return
+			}
+			refreshed, err := tenant.client.RefreshToken(ctx, margin)
+			switch {
+			case err != nil:
+				slog.WarnContext(ctx, "failed to refresh Turvo token", "tenant_id", tenant.ID, "error", err)
+			case refreshed:
+				slog.InfoContext(ctx, "refreshed Turvo token", "tenant_id", tenant.ID)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package tenant
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+// Run with -race: tokens are swapped by the refresh worker while requests are in flight
+func TestRefreshTokensDuringRequests(t *testing.T) {
+	registry, fake := newTestRegistry(t)
+	if _, _, err := registry.Register(context.Background(), Spec{ID: "acme", Credentials: testCredentials()}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	acme, _ := registry.Get("acme")
+	shipmentID := fake.AddShipment(models.TurvoShipmentCreateDetails{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	// A margin beyond the token lifetime refreshes on every tick
+	wg.Go(func() { registry.RefreshTokens(ctx, time.Millisecond, 24*time.Hour) })
+	for range 4 {
+		wg.Go(func() {
+			for ctx.Err() == nil {
+				if _, err := acme.client.GetShipment(ctx, shipmentID); err != nil && ctx.Err() == nil {
+					t.Errorf("GetShipment: %v", err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := fake.Requests(turvotest.EndpointToken); got < 3 {
+		t.Errorf("token requests = %d, want the worker to refresh repeatedly", got)
+	}
+}
@@ -0,0 +1,476 @@
+// Package tenant keeps one Turvo account per tenant (brokerage) served by the backend.
+// Every tenant has its own Turvo client (and so its own token), load service with details
+// cache, and mapping profile.
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lwlach/turvo-integration-backend/internal/auth"
+	"github.com/lwlach/turvo-integration-backend/internal/mapping"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	loadservice "github.com/lwlach/turvo-integration-backend/internal/service/load"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo"
+)
+
+var (
+	ErrNotFound           = errors.New("tenant not found")
+	ErrExists             = errors.New("tenant already exists")
+	ErrInvalid            = errors.New("invalid tenant")
+	ErrCredentialsInvalid = errors.New("Turvo credentials could not be verified")
+	ErrAPIKeyNotFound     = errors.New("API key not found")
+	ErrStaticCredentials  = errors.New("tenant credentials come from the TURVO_* environment variables")
+)
+
+// Tenant IDs appear in URLs and file names, so they are restricted to a safe alphabet
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Config holds the settings shared by all tenants
+type Config struct {
+	// Turvo is the client configuration every tenant starts from; its credentials are ignored.
+	// In record/replay mode each tenant other than the default one gets its own cassette
+	// next to CassettePath (e.g. sandbox.acme.json for tenant "acme").
+	Turvo turvo.Config
+
+	// Loads is the load service configuration; the profile comes from Profiles
+	Loads loadservice.Config
+
+	// Profiles holds the mapping profile of each tenant
+	Profiles *mapping.ProfileSet
+
+	// StorePath is the JSON file tenants registered at runtime are saved to and loaded from.
+	// Without it, registered tenants and rotated credentials are lost on restart.
+	StorePath string
+
+	// DefaultTenantID is the tenant used when a request does not select one
+	DefaultTenantID string
+}
+
+// Tenant is a registered Turvo account
+type Tenant struct {
+	ID    string
+	Name  string
+	Loads *loadservice.Service
+
+	client        *turvo.Client
+	webhookSecret string
+	static        bool // Configured from the environment and never written to the store
+
+	// Guarded by the registry mutex
+	credentials          models.TurvoCredentials
+	apiKeys              []APIKey
+	createdAt            time.Time
+	credentialsRotatedAt time.Time
+}
+
+// Client returns the tenant's Turvo client
+func (t *Tenant) Client() *turvo.Client {
+	return t.client
+}
+
+// WebhookSecret returns the secret Turvo signs this tenant's webhooks with, if it has its own
+func (t *Tenant) WebhookSecret() string {
+	return t.webhookSecret
+}
+
+// Spec describes a tenant to register
+type Spec struct {
+	ID            string                  `json:"id"`
+	Name          string                  `json:"name"`
+	Credentials   models.TurvoCredentials `json:"credentials"`
+	WebhookSecret string                  `json:"webhookSecret,omitempty"`
+
+	APIKeys              []APIKey  `json:"apiKeys,omitempty"`
+	CreatedAt            time.Time `json:"createdAt"`
+	CredentialsRotatedAt time.Time `json:"credentialsRotatedAt"`
+}
+
+// storeFile is the layout of the tenant store
+type storeFile struct {
+	Tenants []Spec `json:"tenants"`
+}
+
+// Registry holds the registered tenants
+type Registry struct {
+	cfg Config
+
+	mutex    sync.RWMutex
+	tenants  map[string]*Tenant
+	keyIndex map[string]*Tenant // Tenant of each API key, by hash
+}
+
+func NewRegistry(cfg Config) *Registry {
+	if cfg.Profiles == nil {
+		cfg.Profiles = mapping.DefaultSet()
+	}
+	if cfg.DefaultTenantID == "" {
+		cfg.DefaultTenantID = "default"
+	}
+	return &Registry{
+		cfg:      cfg,
+		tenants:  make(map[string]*Tenant),
+		keyIndex: make(map[string]*Tenant),
+	}
+}
+
+// RegisterStatic registers a tenant configured outside the store (e.g. from environment
+// variables). It is not saved to the store. apiKeys are given in plain text and get all
+// tenant scopes.
+func (r *Registry) RegisterStatic(spec Spec, apiKeys []string) (*Tenant, error) {
+	if spec.CreatedAt.IsZero() {
+		spec.CreatedAt = time.Now().UTC()
+		spec.CredentialsRotatedAt = spec.CreatedAt
+	}
+	for i, key := range apiKeys {
+		spec.APIKeys = append(spec.APIKeys, APIKey{
+			ID:        fmt.Sprintf("env-%d", i+1),
+			Hash:      auth.HashAPIKey(key),
+			Scopes:    auth.TenantScopes,
+			CreatedAt: spec.CreatedAt,
+		})
+	}
+
+	tenant, err := r.newTenant(spec)
+	if err != nil {
+		return nil, err
+	}
+	tenant.static = true
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.add(tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// LoadStore registers every tenant in the store file. A missing file is not an error.
+func (r *Registry) LoadStore() error {
+	if r.cfg.StorePath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(r.cfg.StorePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read tenant store: %w", err)
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid tenant store %s: %w", r.cfg.StorePath, err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var errs []error
+	for _, spec := range file.Tenants {
+		tenant, err := r.newTenant(spec)
+		if err == nil {
+			err = r.add(tenant)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tenant %q: %w", spec.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Register adds a tenant and issues its first API key (with all tenant scopes), which is
+// returned in plain text. The credentials are checked against Turvo before the tenant is saved.
+func (r *Registry) Register(ctx context.Context, spec Spec) (*Tenant, string, error) {
+	now := time.Now().UTC()
+	key, apiKey, err := newAPIKey(auth.TenantScopes, now)
+	if err != nil {
+		return nil, "", err
+	}
+	spec.APIKeys = []APIKey{key}
+	spec.CreatedAt = now
+	spec.CredentialsRotatedAt = now
+
+	tenant, err := r.newTenant(spec)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.mutex.RLock()
+	_, exists := r.tenants[spec.ID]
+	r.mutex.RUnlock()
+	if exists {
+		return nil, "", ErrExists
+	}
+
+	// Check the credentials by fetching a token, outside the lock since this calls Turvo
+	if err := tenant.client.RotateCredentials(ctx, spec.Credentials); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrCredentialsInvalid, err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.add(tenant); err != nil {
+		return nil, "", err
+	}
+	if err := r.save(); err != nil {
+		r.remove(tenant)
+		return nil, "", err
+	}
+	return tenant, apiKey, nil
+}
+
+// RotateCredentials switches a tenant to new Turvo credentials. The new credentials are
+// checked against Turvo first; if they are rejected the tenant keeps the old ones.
+// Static tenants are rejected, since the environment would bring the old credentials back
+// on restart.
+func (r *Registry) RotateCredentials(ctx context.Context, tenantID string, credentials models.TurvoCredentials) error {
+	tenant, found := r.Get(tenantID)
+	if !found {
+		return ErrNotFound
+	}
+	if tenant.static {
+		return ErrStaticCredentials
+	}
+
+	r.mutex.RLock()
+	previous := tenant.credentials
+	r.mutex.RUnlock()
+
+	if err := validateCredentials(credentials); err != nil {
+		return err
+	}
+	if err := tenant.client.RotateCredentials(ctx, credentials); err != nil {
+		return fmt.Errorf("%w: %v", ErrCredentialsInvalid, err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	previousRotatedAt := tenant.credentialsRotatedAt
+	tenant.credentials = credentials
+	tenant.credentialsRotatedAt = time.Now().UTC()
+	if err := r.save(); err != nil {
+		// Keep memory and store consistent: the old credentials are still valid at Turvo
+		tenant.credentials = previous
+		tenant.credentialsRotatedAt = previousRotatedAt
+		tenant.client.RotateCredentials(ctx, previous)
+		return err
+	}
+	return nil
+}
+
+// Close closes the Turvo client of every tenant, which writes recorded traffic in record mode
+func (r *Registry) Close() error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var errs []error
+	for _, tenant := range r.tenants {
+		if err := tenant.client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %q: %w", tenant.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Get returns a tenant by ID
+func (r *Registry) Get(tenantID string) (*Tenant, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tenant, found := r.tenants[tenantID]
+	return tenant, found
+}
+
+// Default returns the tenant used when a request does not select one
+func (r *Registry) Default() (*Tenant, bool) {
+	return r.Get(r.cfg.DefaultTenantID)
+}
+
+// DefaultID returns the ID of the default tenant
+func (r *Registry) DefaultID() string {
+	return r.cfg.DefaultTenantID
+}
+
+// List returns all tenants sorted by ID
+func (r *Registry) List() []*Tenant {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tenants := make([]*Tenant, 0, len(r.tenants))
+	for _, tenant := range r.tenants {
+		tenants = append(tenants, tenant)
+	}
+	sort.Slice(tenants, func(i, j int) bool {
+		return tenants[i].ID < tenants[j].ID
+	})
+	return tenants
+}
+
+// Info describes a tenant without its credentials or API key hashes
+func (r *Registry) Info(tenant *Tenant) models.Tenant {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return models.Tenant{
+		ID:                   tenant.ID,
+		Name:                 tenant.Name,
+		Default:              tenant.ID == r.cfg.DefaultTenantID,
+		APIKeys:              apiKeyInfos(tenant.apiKeys),
+		CreatedAt:            tenant.createdAt,
+		CredentialsRotatedAt: tenant.credentialsRotatedAt,
+	}
+}
+
+// newTenant validates a spec and builds the tenant's Turvo client and load service
+func (r *Registry) newTenant(spec Spec) (*Tenant, error) {
+	if err := validateSpec(spec); err != nil {
+		return nil, err
+	}
+
+	turvoConfig := r.cfg.Turvo
+	turvoConfig.ClientName = spec.Credentials.ClientName
+	turvoConfig.ClientSecret = spec.Credentials.ClientSecret
+	turvoConfig.Username = spec.Credentials.Username
+	turvoConfig.Password = spec.Credentials.Password
+	turvoConfig.TenantID = spec.ID
+	if turvoConfig.Mode != "" && turvoConfig.Mode != turvo.ModeLive && spec.ID != r.cfg.DefaultTenantID {
+		turvoConfig.CassettePath = tenantCassettePath(turvoConfig.CassettePath, spec.ID)
+	}
+
+	client, err := turvo.NewClient(turvoConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Turvo client: %w", err)
+	}
+
+	loadConfig := r.cfg.Loads
+	loadConfig.Profile = r.cfg.Profiles.ForTenant(spec.ID)
+	loadConfig.TenantID = spec.ID
+
+	return &Tenant{
+		ID:                   spec.ID,
+		Name:                 spec.Name,
+		Loads:                loadservice.NewService(client, loadConfig),
+		client:               client,
+		webhookSecret:        spec.WebhookSecret,
+		credentials:          spec.Credentials,
+		apiKeys:              spec.APIKeys,
+		createdAt:            spec.CreatedAt,
+		credentialsRotatedAt: spec.CredentialsRotatedAt,
+	}, nil
+}
+
+// add makes a tenant and its API keys visible; the caller must hold the mutex
+func (r *Registry) add(tenant *Tenant) error {
+	if _, exists := r.tenants[tenant.ID]; exists {
+		return ErrExists
+	}
+	for _, key := range tenant.apiKeys {
+		if _, taken := r.keyIndex[key.Hash]; taken {
+			return fmt.Errorf("API key %s of tenant %q is already used by another tenant", key.ID, tenant.ID)
+		}
+	}
+
+	r.tenants[tenant.ID] = tenant
+	for _, key := range tenant.apiKeys {
+		r.keyIndex[key.Hash] = tenant
+	}
+	return nil
+}
+
+// remove undoes add; the caller must hold the mutex
+func (r *Registry) remove(tenant *Tenant) {
+	delete(r.tenants, tenant.ID)
+	for _, key := range tenant.apiKeys {
+		delete(r.keyIndex, key.Hash)
+	}
+}
+
+// save writes all non-static tenants to the store; the caller must hold the mutex.
+// The file holds Turvo credentials, so it is only readable by the owner.
+func (r *Registry) save() error {
+	if r.cfg.StorePath == "" {
+		return nil
+	}
+
+	file := storeFile{Tenants: []Spec{}}
+	for _, tenant := range r.tenants {
+		if tenant.static {
+			continue
+		}
+		file.Tenants = append(file.Tenants, Spec{
+			ID:                   tenant.ID,
+			Name:                 tenant.Name,
+			Credentials:          tenant.credentials,
+			WebhookSecret:        tenant.webhookSecret,
+			APIKeys:              tenant.apiKeys,
+			CreatedAt:            tenant.createdAt,
+			CredentialsRotatedAt: tenant.credentialsRotatedAt,
+		})
+	}
+	sort.Slice(file.Tenants, func(i, j int) bool {
+		return file.Tenants[i].ID < file.Tenants[j].ID
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tenant store: %w", err)
+	}
+
+	// Write to a temporary file and rename, so a crash never leaves a truncated store
+	tmp := r.cfg.StorePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write tenant store: %w", err)
+	}
+	if err := os.Rename(tmp, r.cfg.StorePath); err != nil {
+		return fmt.Errorf("failed to write tenant store: %w", err)
+	}
+	return nil
+}
+
+func validateSpec(spec Spec) error {
+	var problems []string
+	if !tenantIDPattern.MatchString(spec.ID) {
+		problems = append(problems, "id must be 1-64 lowercase letters, digits, '-' or '_'")
+	}
+	problems = append(problems, credentialProblems(spec.Credentials)...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func validateCredentials(credentials models.TurvoCredentials) error {
+	if problems := credentialProblems(credentials); len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func credentialProblems(credentials models.TurvoCredentials) []string {
+	var problems []string
+	if credentials.ClientName == "" || credentials.ClientSecret == "" {
+		problems = append(problems, "credentials.clientName and credentials.clientSecret are required")
+	}
+	if credentials.Username == "" || credentials.Password == "" {
+		problems = append(problems, "credentials.username and credentials.password are required")
+	}
+	return problems
+}
+
+// tenantCassettePath derives a tenant's cassette from the shared one: sandbox.json becomes sandbox.<tenant>.json
+func tenantCassettePath(path, tenantID string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + tenantID + ext
+}
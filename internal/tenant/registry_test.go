@@ -0,0 +1,90 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+// newTestRegistry returns a registry backed by a fresh fake Turvo API and a store in a temp dir
+func newTestRegistry(t *testing.T) (*Registry, *turvotest.Server) {
+	t.Helper()
+	fake := turvotest.NewServer()
+	t.Cleanup(fake.Close)
+
+	registry := NewRegistry(Config{
+		Turvo:     fake.Config(),
+		StorePath: filepath.Join(t.TempDir(), "tenants.json"),
+	})
+	return registry, fake
+}
+
+func testCredentials() models.TurvoCredentials {
+	return models.TurvoCredentials{
+		ClientName:   turvotest.ClientName,
+		ClientSecret: turvotest.ClientSecret,
+		Username:     turvotest.Username,
+		Password:     turvotest.Password,
+	}
+}
+
+func TestRotateCredentialsOfStaticTenant(t *testing.T) {
+	registry, fake := newTestRegistry(t)
+	if _, err := registry.RegisterStatic(Spec{ID: "default", Credentials: testCredentials()}, nil); err != nil {
+		t.Fatalf("RegisterStatic: %v", err)
+	}
+
+	err := registry.RotateCredentials(context.Background(), "default", testCredentials())
+	if !errors.Is(err, ErrStaticCredentials) {
+		t.Fatalf("error = %v, want ErrStaticCredentials", err)
+	}
+	if got := fake.Requests(turvotest.EndpointToken); got != 0 {
+		t.Errorf("token requests = %d, want none", got)
+	}
+	if _, err := os.Stat(registry.cfg.StorePath); !os.IsNotExist(err) {
+		t.Errorf("store written for a static tenant: %v", err)
+	}
+}
+
+func TestRotateCredentials(t *testing.T) {
+	registry, fake := newTestRegistry(t)
+	if _, _, err := registry.Register(context.Background(), Spec{ID: "acme", Credentials: testCredentials()}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	t.Run("rejected by Turvo", func(t *testing.T) {
+		credentials := testCredentials()
+		credentials.Password = "wrong"
+		err := registry.RotateCredentials(context.Background(), "acme", credentials)
+		if !errors.Is(err, ErrCredentialsInvalid) {
+			t.Fatalf("error = %v, want ErrCredentialsInvalid", err)
+		}
+		data, _ := os.ReadFile(registry.cfg.StorePath)
+		if strings.Contains(string(data), `"wrong"`) {
+			t.Error("rejected credentials saved to the store")
+		}
+	})
+
+	t.Run("accepted", func(t *testing.T) {
+		before := fake.Requests(turvotest.EndpointToken)
+		if err := registry.RotateCredentials(context.Background(), "acme", testCredentials()); err != nil {
+			t.Fatalf("RotateCredentials: %v", err)
+		}
+		if got := fake.Requests(turvotest.EndpointToken); got != before+1 {
+			t.Errorf("token requests = %d, want %d (credentials checked)", got, before+1)
+		}
+	})
+
+	t.Run("unknown tenant", func(t *testing.T) {
+		err := registry.RotateCredentials(context.Background(), "nobody", testCredentials())
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("error = %v, want ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+// Package tracing sets up OpenTelemetry tracing.
+//
+// Spans are exported with OTLP over HTTP, or dropped by a no-op exporter. With the no-op
+// exporter spans are still created and trace IDs still propagated, so traceparent headers
+// and the trace IDs in our logs work the same offline.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lwlach/turvo-integration-backend/internal/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters
+const (
+	ExporterNone = "none" // Spans are created but dropped
+	ExporterOTLP = "otlp" // Spans are sent to an OTLP/HTTP collector
+)
+
+// Attributes we add to spans
+const (
+	TenantID   = attribute.Key("tenant.id")
+	ShipmentID = attribute.Key("turvo.shipment_id")
+)
+
+const instrumentationName = "github.com/lwlach/turvo-integration-backend"
+
+// Config holds tracing options
+type Config struct {
+	Exporter    string  // ExporterNone (default) or ExporterOTLP
+	Endpoint    string  // OTLP/HTTP endpoint URL; the OTEL_EXPORTER_OTLP_* environment variables apply if empty
+	ServiceName string  // service.name of our spans
+	SampleRatio float64 // Share of new traces that are recorded; traces started by callers follow their decision
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator. The
+// returned function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		exporter = noopExporter{}
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		otlpExporter, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		exporter = otlpExporter
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer our spans are created with
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span, see trace.Tracer
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End records err (if any) on the span and ends it. Error messages are redacted like our
+// logs, since Turvo error bodies can contain customer data.
+func End(span trace.Span, err error) {
+	if err != nil {
+		message := logging.Redact(err.Error())
+		span.AddEvent("exception", trace.WithAttributes(
+			attribute.String("exception.type", fmt.Sprintf("%T", err)),
+			attribute.String("exception.message", message),
+		))
+		span.SetStatus(codes.Error, message)
+	}
+	span.End()
+}
+
+// noopExporter drops every span
+type noopExporter struct{}
+
+func (noopExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error { return nil }
+func (noopExporter) Shutdown(context.Context) error                             { return nil }
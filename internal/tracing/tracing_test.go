@@ -0,0 +1,142 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// restoreGlobals puts back the global tracer provider and propagator after the test
+func restoreGlobals(t *testing.T) {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string // Error, empty if none
+	}{
+		{name: "default exporter", cfg: Config{ServiceName: "test", SampleRatio: 1}},
+		{name: "no-op exporter", cfg: Config{Exporter: ExporterNone, ServiceName: "test", SampleRatio: 1}},
+		{name: "OTLP exporter", cfg: Config{Exporter: ExporterOTLP, Endpoint: "http://localhost:4318", ServiceName: "test", SampleRatio: 1}},
+		{name: "unknown exporter", cfg: Config{Exporter: "zipkin"}, want: `unknown trace exporter "zipkin"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restoreGlobals(t)
+			shutdown, err := Setup(context.Background(), test.cfg)
+			if test.want != "" {
+				if err == nil || !strings.Contains(err.Error(), test.want) {
+					t.Errorf("error = %v, want %q", err, test.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Setup: %v", err)
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("shutdown: %v", err)
+			}
+		})
+	}
+}
+
+// With the no-op exporter spans are dropped, but trace IDs are still created and propagated
+func TestNoopExporterPropagates(t *testing.T) {
+	restoreGlobals(t)
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone, ServiceName: "test", SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	ctx, span := Start(context.Background(), "request")
+	if !span.SpanContext().IsValid() || !span.SpanContext().IsSampled() {
+		t.Fatalf("span context = %+v, want a valid sampled span", span.SpanContext())
+	}
+	header := http.Header{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	want := "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"
+	if got := header.Get("traceparent"); got != want {
+		t.Errorf("traceparent = %q, want %q", got, want)
+	}
+	End(span, nil)
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+}
+
+func TestSampleRatio(t *testing.T) {
+	restoreGlobals(t)
+	shutdown, err := Setup(context.Background(), Config{ServiceName: "test", SampleRatio: 0})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	_, root := Start(context.Background(), "new trace")
+	if !root.SpanContext().IsValid() || root.SpanContext().IsSampled() {
+		t.Errorf("new trace = %+v, want a valid trace that is not sampled", root.SpanContext())
+	}
+
+	// Traces started by callers follow their sampling decision
+	header := http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+	_, child := Start(ctx, "continued trace")
+	if !child.SpanContext().IsSampled() || child.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("continued trace = %+v, want the caller's sampled trace", child.SpanContext())
+	}
+}
+
+func TestEnd(t *testing.T) {
+	restoreGlobals(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	_, span := Start(context.Background(), "ok", trace.WithAttributes(TenantID.String("acme")))
+	End(span, nil)
+	_, span = Start(context.Background(), "failed")
+	End(span, errors.New(`turvo API error: 400 {"email":"ops@acme.example","access_token":"abc123"}`))
+
+	ended := recorder.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("%d spans ended, want 2", len(ended))
+	}
+	if ok := ended[0]; ok.Status().Code != codes.Unset || len(ok.Events()) != 0 {
+		t.Errorf("span without error = %+v with events %v, want no status or events", ok.Status(), ok.Events())
+	}
+
+	failed := ended[1]
+	if failed.Status().Code != codes.Error {
+		t.Errorf("status = %+v, want an error", failed.Status())
+	}
+	if len(failed.Events()) != 1 || failed.Events()[0].Name != "exception" {
+		t.Fatalf("events = %+v, want one exception", failed.Events())
+	}
+	recorded := failed.Status().Description
+	for _, attr := range failed.Events()[0].Attributes {
+		recorded += " " + attr.Value.Emit()
+	}
+	for _, secret := range []string{"ops@acme.example", "abc123"} {
+		if strings.Contains(recorded, secret) {
+			t.Errorf("span records %q: %s", secret, recorded)
+		}
+	}
+	if !strings.Contains(recorded, "turvo API error: 400") || !strings.Contains(recorded, "*errors.errorString") {
+		t.Errorf("span records %s, want the redacted message and the error type", recorded)
+	}
+}
@@ -0,0 +1,382 @@
+package turvo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client modes
+const (
+	ModeLive   = "live"   // Talk to Turvo
+	ModeRecord = "record" // Talk to Turvo and save every request/response to the cassette
+	ModeReplay = "replay" // Serve responses from the cassette, no network
+)
+
+// redactedValue replaces secrets in recorded traffic
+const redactedValue = "REDACTED"
+
+// Headers, query parameters and JSON body fields that are never written to a cassette
+var (
+	redactedHeaders = []string{"Authorization", "X-Api-Key", "Cookie", "Set-Cookie"}
+	// Trace context differs on every run, so it is left out rather than redacted
+	droppedHeaders = []string{"Traceparent", "Tracestate", "Baggage"}
+	redactedParams = []string{"client_id", "client_secret", "password", "username"}
+	redactedFields = map[string]bool{
+		"password":      true,
+		"client_secret": true,
+		"client_id":     true,
+		"username":      true,
+		"access_token":  true,
+		"refresh_token": true,
+		"email":         true,
+	}
+)
+
+// Cassette is a recorded sequence of Turvo HTTP interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recordedAt"`
+}
+
+// RecordedRequest is a request with secrets removed
+type RecordedRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"` // Path and query, without host
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response with secrets removed
+type RecordedResponse struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       json.RawMessage   `json:"body,omitempty"`
+	BodyIsText bool              `json:"bodyIsText,omitempty"` // Body is a JSON string holding a non-JSON body
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return &cassette, nil
+}
+
+// cassetteTransport records traffic to, or replays traffic from, a cassette file
+type cassetteTransport struct {
+	mode string
+	path string
+	next http.RoundTripper // Used when recording
+
+	mutex    sync.Mutex
+	cassette *Cassette
+	replayed map[string]int // Interactions already replayed per request key
+	recorded bool           // Interactions were recorded since the cassette was loaded
+}
+
+// newCassetteTransport creates the transport for record or replay mode
+func newCassetteTransport(mode, path string) (*cassetteTransport, error) {
+	if path == "" {
+		return nil, fmt.Errorf("cassette path is required in %s mode", mode)
+	}
+
+	transport := &cassetteTransport{
+		mode:     mode,
+		path:     path,
+		next:     http.DefaultTransport,
+		cassette: &Cassette{},
+		replayed: make(map[string]int),
+	}
+
+	switch mode {
+	case ModeReplay:
+		cassette, err := LoadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		transport.cassette = cassette
+	case ModeRecord:
+		// Keep earlier recordings so one cassette can be built up over several runs
+		if cassette, err := LoadCassette(path); err == nil {
+			transport.cassette = cassette
+		}
+	default:
+		return nil, fmt.Errorf("unknown client mode: %q", mode)
+	}
+
+	return transport, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.mode == ModeReplay {
+		return t.replay(req)
+	}
+	return t.record(req)
+}
+
+// record sends the request to Turvo and appends the redacted interaction to the cassette
+func (t *cassetteTransport) record(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	respRecordedBody, isText := redactBody(respBody)
+	reqRecordedBody, _ := redactBody(reqBody)
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method:  req.Method,
+			URL:     redactedURL(req.URL),
+			Headers: redactHeaders(req.Header),
+			Body:    reqRecordedBody,
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    redactHeaders(resp.Header),
+			Body:       respRecordedBody,
+			BodyIsText: isText,
+		},
+		RecordedAt: time.Now().UTC(),
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, interaction)
+	t.recorded = true
+
+	return resp, nil
+}
+
+// Close writes the recorded interactions to the cassette file. Recordings are kept in
+// memory until then, so the file is written once however long the recording runs.
+func (t *cassetteTransport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.mode != ModeRecord || !t.recorded {
+		return nil
+	}
+	if err := t.save(); err != nil {
+		return err
+	}
+	t.recorded = false
+	return nil
+}
+
+// replay answers the request from the cassette. Requests are matched on method, path and
+// query; repeated requests get the recorded responses in order, and the last one once exhausted.
+func (t *cassetteTransport) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	key := req.Method + " " + redactedURL(req.URL)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var matches []Interaction
+	for _, interaction := range t.cassette.Interactions {
+		if interaction.Request.Method+" "+interaction.Request.URL == key {
+			matches = append(matches, interaction)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no recorded interaction for %s in cassette %s", key, t.path)
+	}
+
+	index := t.replayed[key]
+	if index >= len(matches) {
+		index = len(matches) - 1
+	}
+	t.replayed[key]++
+	recorded := matches[index].Response
+
+	body := []byte(recorded.Body)
+	if recorded.BodyIsText {
+		var text string
+		if err := json.Unmarshal(recorded.Body, &text); err != nil {
+			return nil, fmt.Errorf("invalid text body in cassette %s: %w", t.path, err)
+		}
+		body = []byte(text)
+	}
+
+	header := make(http.Header)
+	for name, value := range recorded.Headers {
+		header.Set(name, value)
+	}
+
+	return &http.Response{
+		StatusCode:    recorded.StatusCode,
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// save writes the cassette to disk; the caller must hold the mutex
+func (t *cassetteTransport) save() error {
+	data, err := json.MarshalIndent(t.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(t.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// readBody reads a body and replaces it with a fresh reader so it can still be consumed
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// redactedURL returns the path and query of u with secret parameters redacted and parameters sorted
+func redactedURL(u *url.URL) string {
+	query := u.Query()
+	for _, param := range redactedParams {
+		if query.Has(param) {
+			query.Set(param, redactedValue)
+		}
+	}
+	if len(query) == 0 {
+		return u.Path
+	}
+	// url.Values.Encode sorts by key
+	return u.Path + "?" + query.Encode()
+}
+
+// redactHeaders flattens headers and redacts secret ones
+func redactHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		if !contains(droppedHeaders, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	flattened := make(map[string]string, len(names))
+	for _, name := range names {
+		flattened[name] = strings.Join(header.Values(name), ", ")
+	}
+	for _, name := range redactedHeaders {
+		if _, found := flattened[http.CanonicalHeaderKey(name)]; found {
+			flattened[http.CanonicalHeaderKey(name)] = redactedValue
+		}
+	}
+	return flattened
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactJSON returns a JSON body with secret fields replaced, as it is written to a cassette
+func RedactJSON(body []byte) json.RawMessage {
+	redacted, _ := redactBody(body)
+	return redacted
+}
+
+// redactBody redacts secret fields of a JSON body. Non-JSON bodies are stored as a JSON string,
+// reported by the second return value.
+func redactBody(body []byte) (json.RawMessage, bool) {
+	if len(body) == 0 {
+		return nil, false
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		text, _ := json.Marshal(string(body))
+		return text, true
+	}
+
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		text, _ := json.Marshal(string(body))
+		return text, true
+	}
+	return redacted, false
+}
+
+// redactValue walks a decoded JSON value and replaces secret fields
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if redactedFields[strings.ToLower(key)] {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redactValue(field)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
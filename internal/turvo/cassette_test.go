@@ -0,0 +1,153 @@
+package turvo_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+)
+
+func TestRecordRedactsSecrets(t *testing.T) {
+	fake := turvotest.NewServer()
+	defer fake.Close()
+	id := fake.AddShipment(models.TurvoShipmentCreateDetails{
+		CustomerOrder: []models.TurvoCustomerOrderResponse{{
+			Contacts: []models.TurvoShipContact{{Name: "Dispatch", Email: []models.TurvoEmail{{Email: "dispatch@example.com"}}}},
+		}},
+	})
+
+	config := fake.Config()
+	config.Mode = turvo.ModeRecord
+	config.CassettePath = filepath.Join(t.TempDir(), "cassettes", "record.json")
+	config.APIKey = "turvotest-api-key"
+	client, err := turvo.NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, err := client.GetShipment(context.Background(), id); err != nil {
+		t.Fatalf("GetShipment: %v", err)
+	}
+
+	// Interactions are kept in memory until the client is closed
+	if _, err := os.Stat(config.CassettePath); !os.IsNotExist(err) {
+		t.Errorf("cassette written before Close: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	data, err := os.ReadFile(config.CassettePath)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+
+	recorded := string(data)
+	for _, secret := range []string{
+		turvotest.ClientName, turvotest.ClientSecret, turvotest.Username, turvotest.Password,
+		config.APIKey, "dispatch@example.com", "Bearer ",
+	} {
+		if strings.Contains(recorded, secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+
+	cassette, err := turvo.LoadCassette(config.CassettePath)
+	if err != nil {
+		t.Fatalf("LoadCassette: %v", err)
+	}
+	if len(cassette.Interactions) != 2 {
+		t.Fatalf("recorded %d interactions, want token and get", len(cassette.Interactions))
+	}
+	token, get := cassette.Interactions[0], cassette.Interactions[1]
+	if !strings.Contains(token.Request.URL, "client_id=REDACTED") {
+		t.Errorf("token URL = %q, want client_id redacted", token.Request.URL)
+	}
+	var tokenResponse models.TurvoAuthResponse
+	if err := json.Unmarshal(token.Response.Body, &tokenResponse); err != nil {
+		t.Fatalf("decode token response: %v", err)
+	}
+	if tokenResponse.AccessToken != "REDACTED" {
+		t.Errorf("access token = %q, want REDACTED", tokenResponse.AccessToken)
+	}
+	if got := get.Request.Headers["Authorization"]; got != "REDACTED" {
+		t.Errorf("Authorization header = %q, want REDACTED", got)
+	}
+	if got := get.Request.Headers["X-Api-Key"]; got != "REDACTED" {
+		t.Errorf("X-Api-Key header = %q, want REDACTED", got)
+	}
+	if _, found := get.Request.Headers["Traceparent"]; found {
+		t.Error("trace context recorded")
+	}
+}
+
+func TestRecordAppendsToCassette(t *testing.T) {
+	fake := turvotest.NewServer()
+	defer fake.Close()
+	id := fake.AddShipment(models.TurvoShipmentCreateDetails{})
+
+	config := fake.Config()
+	config.Mode = turvo.ModeRecord
+	config.CassettePath = filepath.Join(t.TempDir(), "record.json")
+	for run := 1; run <= 2; run++ {
+		client, err := turvo.NewClient(config)
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		if _, err := client.GetShipment(context.Background(), id); err != nil {
+			t.Fatalf("GetShipment: %v", err)
+		}
+		if err := client.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	cassette, err := turvo.LoadCassette(config.CassettePath)
+	if err != nil {
+		t.Fatalf("LoadCassette: %v", err)
+	}
+	if len(cassette.Interactions) != 4 {
+		t.Errorf("recorded %d interactions over two runs, want 4", len(cassette.Interactions))
+	}
+}
+
+func TestReplayServesRecordedResponses(t *testing.T) {
+	fake := turvotest.NewServer()
+	id := fake.AddShipment(models.TurvoShipmentCreateDetails{CustomID: "FREIGHT-1"})
+
+	config := fake.Config()
+	config.Mode = turvo.ModeRecord
+	config.CassettePath = filepath.Join(t.TempDir(), "replay.json")
+	client, err := turvo.NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, err := client.GetShipment(context.Background(), id); err != nil {
+		t.Fatalf("GetShipment: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	fake.Close()
+
+	// Replay never reaches the (now closed) fake
+	config.Mode = turvo.ModeReplay
+	replay, err := turvo.NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	shipment, err := replay.GetShipment(context.Background(), id)
+	if err != nil {
+		t.Fatalf("replayed GetShipment: %v", err)
+	}
+	if shipment.CustomID != "FREIGHT-1" {
+		t.Errorf("customId = %q, want FREIGHT-1", shipment.CustomID)
+	}
+	if _, err := replay.GetShipment(context.Background(), id+1); err == nil {
+		t.Error("replayed a request that was never recorded")
+	}
+}
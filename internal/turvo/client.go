@@ -1,40 +1,73 @@
 package turvo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/lwlach/turvo-integration-backend/internal/metrics"
 	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
 type Config struct {
 	BaseURL      string
+	AuthURL      string // Base URL of the OAuth token endpoint; defaults to the sandbox public API
 	APIKey       string // Deprecated: Use ClientName and ClientSecret instead
 	ClientName   string
 	ClientSecret string
 	Username     string
 	Password     string
+	MaxRetries   int           // Retries for transient failures (network errors, 429, 5xx); 0 disables them
+	RetryBackoff time.Duration // Wait before the first retry, doubled for each further retry; default 500ms
+	RateLimit    float64       // Maximum requests per second sent to Turvo, including retries; 0 means unlimited
+	RateBurst    int           // Requests that may be sent at once before RateLimit applies; default 1
+	TenantID     string        // Added to the spans of the client's requests
+
+	// Mode is ModeLive (default), ModeRecord or ModeReplay. Record and replay use the cassette
+	// file at CassettePath; tokens, passwords and client secrets are never written to it.
+	Mode         string
+	CassettePath string
 }
 
 type Client struct {
-	baseURL      string
-	httpClient   *resty.Client
-	apiKey       string
-	clientName   string
-	clientSecret string
-	username     string
-	password     string
+	baseURL     string
+	authURL     string
+	httpClient  *resty.Client
+	apiKey      string
+	tenantID    string
+	credentials models.TurvoCredentials // Guarded by authMutex
 
 	// Token management
 	token       string
 	tokenExpiry time.Time
 	tokenMutex  sync.RWMutex
+	authMutex   sync.Mutex // Serializes authentication so concurrent requests share one token refresh
+
+	// When Turvo last answered a request successfully, token requests included
+	lastSuccess time.Time
+	statusMutex sync.Mutex
+
+	// Transport for record/replay mode; nil in live mode
+	transport *cassetteTransport
+
+	// Request pipeline every endpoint goes through
+	pipeline     handlerFunc
+	maxRetries   int
+	retryBackoff time.Duration
+	limiter      *rate.Limiter // nil without a rate limit
 }
 
-func NewClient(cfg Config) *Client {
+func NewClient(cfg Config) (*Client, error) {
 	client := resty.New().
 		SetBaseURL(cfg.BaseURL).
 		SetTimeout(30*time.Second).
@@ -42,34 +75,100 @@ func NewClient(cfg Config) *Client {
 		SetHeader("Accept", "application/json").
 		SetHeader("x-api-key", cfg.APIKey)
 
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.AuthURL == "" {
+		cfg.AuthURL = "https://my-sandbox-publicapi.turvo.com"
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 500 * time.Millisecond
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = ModeLive
+	}
+
 	turvoClient := &Client{
-		baseURL:      cfg.BaseURL,
-		httpClient:   client,
-		apiKey:       cfg.APIKey,
-		clientName:   cfg.ClientName,
-		clientSecret: cfg.ClientSecret,
-		username:     cfg.Username,
-		password:     cfg.Password,
+		baseURL:    cfg.BaseURL,
+		authURL:    cfg.AuthURL,
+		httpClient: client,
+		apiKey:     cfg.APIKey,
+		tenantID:   cfg.TenantID,
+		credentials: models.TurvoCredentials{
+			ClientName:   cfg.ClientName,
+			ClientSecret: cfg.ClientSecret,
+			Username:     cfg.Username,
+			Password:     cfg.Password,
+		},
+		maxRetries:   cfg.MaxRetries,
+		retryBackoff: cfg.RetryBackoff,
+	}
+	if cfg.Mode != ModeLive {
+		transport, err := newCassetteTransport(cfg.Mode, cfg.CassettePath)
+		if err != nil {
+			return nil, err
+		}
+		turvoClient.transport = transport
+		client.SetTransport(transport)
+	}
+
+	if cfg.RateLimit > 0 {
+		if cfg.RateBurst <= 0 {
+			cfg.RateBurst = 1
+		}
+		turvoClient.limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), cfg.RateBurst)
 	}
 
-	return turvoClient
+	turvoClient.pipeline = turvoClient.buildPipeline()
+
+	return turvoClient, nil
 }
 
-// authenticate retrieves an access token from Turvo's authentication API
-func (c *Client) authenticate() error {
-	if c.clientName == "" || c.clientSecret == "" {
-		return fmt.Errorf("clientName and clientSecret are required for authentication")
+// authenticate retrieves an access token from Turvo's authentication API; the caller must hold authMutex
+func (c *Client) authenticate(ctx context.Context) error {
+	authResp, err := c.requestToken(ctx, c.credentials)
+	if err != nil {
+		return err
+	}
+	c.storeToken(authResp)
+	c.recordSuccess()
+	return nil
+}
+
+// RotateCredentials switches the client to new credentials. A token is fetched with the new
+// credentials first, so the client keeps working with the old ones if they are rejected.
+func (c *Client) RotateCredentials(ctx context.Context, credentials models.TurvoCredentials) error {
+	c.authMutex.Lock()
+	defer c.authMutex.Unlock()
+
+	authResp, err := c.requestToken(ctx, credentials)
+	if err != nil {
+		return err
+	}
+	c.credentials = credentials
+	c.storeToken(authResp)
+	return nil
+}
+
+// requestToken exchanges credentials for an access token
+func (c *Client) requestToken(ctx context.Context, credentials models.TurvoCredentials) (_ *models.TurvoAuthResponse, err error) {
+	ctx, span := tracing.Start(ctx, "turvo authenticate", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(tracing.TenantID.String(c.tenantID)))
+	defer func() { tracing.End(span, err) }()
+
+	if credentials.ClientName == "" || credentials.ClientSecret == "" {
+		return nil, fmt.Errorf("clientName and clientSecret are required for authentication")
 	}
-	if c.username == "" || c.password == "" {
-		return fmt.Errorf("username and password are required for authentication")
+	if credentials.Username == "" || credentials.Password == "" {
+		return nil, fmt.Errorf("username and password are required for authentication")
 	}
 
 	authReq := models.TurvoAuthRequest{
 		GrantType:    "password",
-		ClientID:     c.clientName,
-		ClientSecret: c.clientSecret,
-		Username:     c.username,
-		Password:     c.password,
+		ClientID:     credentials.ClientName,
+		ClientSecret: credentials.ClientSecret,
+		Username:     credentials.Username,
+		Password:     credentials.Password,
 		Scope:        "read+trust+write",
 		Type:         "business",
 	}
@@ -79,29 +178,46 @@ func (c *Client) authenticate() error {
 	// Use a separate client for auth to avoid circular auth issues
 	authClient := resty.New().
 		SetHeader("Content-Type", "application/json").
-		SetBaseURL("https://my-sandbox-publicapi.turvo.com").
+		SetBaseURL(c.authURL).
 		SetTimeout(30*time.Second).
 		SetHeader("x-api-key", c.apiKey).
-		SetQueryParam("client_id", c.clientName).
-		SetQueryParam("client_secret", c.clientSecret)
+		SetQueryParam("client_id", credentials.ClientName).
+		SetQueryParam("client_secret", credentials.ClientSecret)
+	if c.transport != nil {
+		authClient.SetTransport(c.transport)
+	}
 
+	// Bodies are never logged here: the request holds the password, the response the token
+	slog.DebugContext(ctx, "turvo token request", "client_name", credentials.ClientName)
 	resp, err := authClient.R().
+		SetContext(ctx).
 		SetBody(authReq).
 		SetResult(&authResp).
 		Post("/v1/oauth/token")
 
-	if err != nil {
-		return fmt.Errorf("failed to authenticate: %w", err)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("failed to authenticate: %w", err)
+	case resp.IsError():
+		err = fmt.Errorf("authentication failed: %s - %s", resp.Status(), string(resp.Body()))
+	case authResp.AccessToken == "":
+		err = fmt.Errorf("authentication response missing access token")
 	}
-
-	if resp.IsError() {
-		return fmt.Errorf("authentication failed: %s - %s", resp.Status(), string(resp.Body()))
+	if err != nil {
+		metrics.TurvoTokenRefreshes.WithLabelValues("failure").Inc()
+		slog.WarnContext(ctx, "turvo token request failed", "client_name", credentials.ClientName, "error", err)
+		return nil, err
 	}
+	metrics.TurvoTokenRefreshes.WithLabelValues("success").Inc()
+	slog.DebugContext(ctx, "turvo token issued", "client_name", credentials.ClientName, "expires_in", authResp.ExpiresIn)
 
-	if authResp.AccessToken == "" {
-		return fmt.Errorf("authentication response missing access token")
-	}
+	return &authResp, nil
+}
 
+// storeToken makes a newly issued token the one used for requests. Requests read it under
+// tokenMutex and set it on themselves; the resty client is shared by in-flight requests, so
+// its headers are never changed after NewClient.
+func (c *Client) storeToken(authResp *models.TurvoAuthResponse) {
 	// Store token and calculate expiry
 	c.tokenMutex.Lock()
 	c.token = authResp.AccessToken
@@ -112,260 +228,203 @@ func (c *Client) authenticate() error {
 	}
 	c.tokenExpiry = time.Now().Add(time.Duration(expiresIn-300) * time.Second) // 5 min buffer
 	c.tokenMutex.Unlock()
-
-	// Update the HTTP client with the new token
-	c.httpClient.SetHeader("Authorization", fmt.Sprintf("%s %s", authResp.TokenType, authResp.AccessToken))
-
-	return nil
 }
 
 // ensureAuthenticated ensures we have a valid token, refreshing if necessary
-func (c *Client) ensureAuthenticated() error {
-	c.tokenMutex.RLock()
-	tokenValid := c.token != "" && time.Now().Before(c.tokenExpiry)
-	c.tokenMutex.RUnlock()
+func (c *Client) ensureAuthenticated(ctx context.Context) error {
+	if c.tokenValid() {
+		return nil
+	}
 
-	if tokenValid {
+	// Only one request refreshes the token, the others wait and reuse it
+	c.authMutex.Lock()
+	defer c.authMutex.Unlock()
+	if c.tokenValid() {
 		return nil
 	}
 
 	// Need to authenticate
-	return c.authenticate()
+	return c.authenticate(ctx)
 }
 
-// getAuthenticatedRequest returns a resty request with authentication applied
-func (c *Client) getAuthenticatedRequest() (*resty.Request, error) {
-	if err := c.ensureAuthenticated(); err != nil {
-		return nil, err
+// TokenExpiry returns when the current token stops being used (5 minutes before Turvo expires
+// it); zero if the client has not authenticated yet
+func (c *Client) TokenExpiry() time.Time {
+	c.tokenMutex.RLock()
+	defer c.tokenMutex.RUnlock()
+	if c.token == "" {
+		return time.Time{}
 	}
+	return c.tokenExpiry
+}
 
-	c.tokenMutex.RLock()
-	token := c.token
-	c.tokenMutex.RUnlock()
+// RefreshToken fetches a new token if the current one expires within margin, so requests do
+// not wait for authentication. Clients that have not authenticated yet are left alone, so
+// tenants that are not in use do not call Turvo. It reports whether a token was fetched.
+func (c *Client) RefreshToken(ctx context.Context, margin time.Duration) (bool, error) {
+	expiring := func() bool {
+		expiry := c.TokenExpiry()
+		return !expiry.IsZero() && time.Until(expiry) < margin
+	}
+	if !expiring() {
+		return false, nil
+	}
 
-	return c.httpClient.R().SetAuthToken(token), nil
+	c.authMutex.Lock()
+	defer c.authMutex.Unlock()
+	if !expiring() {
+		return false, nil
+	}
+	if err := c.authenticate(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-// GetShipments fetches all shipments from Turvo (alias for ListShipments for backward compatibility)
-func (c *Client) GetShipments() ([]models.TurvoShipment, error) {
-	return c.ListShipments()
+// Close writes the traffic recorded in record mode to the cassette. The client must not be
+// used afterwards.
+func (c *Client) Close() error {
+	if c.transport == nil {
+		return nil
+	}
+	return c.transport.Close()
 }
 
-// ListShipments fetches all shipments from Turvo
-func (c *Client) ListShipments() ([]models.TurvoShipment, error) {
-	return c.ListShipmentsWithFilters(models.TurvoShipmentFilters{})
+// EnsureToken fetches a token unless the client holds a valid one
+func (c *Client) EnsureToken(ctx context.Context) error {
+	return c.ensureAuthenticated(ctx)
 }
 
-// ListShipmentsWithFilters fetches shipments from Turvo with filters and pagination
-func (c *Client) ListShipmentsWithFilters(filters models.TurvoShipmentFilters) ([]models.TurvoShipment, error) {
-	var response models.TurvoShipmentsListResponse
+// Ping checks that Turvo answers authenticated requests by listing a single shipment
+func (c *Client) Ping(ctx context.Context) error {
+	_, _, err := c.ListShipmentsWithFiltersAndPagination(ctx, models.TurvoShipmentFilters{PageSize: 1})
+	return err
+}
 
-	req, err := c.getAuthenticatedRequest()
-	if err != nil {
-		return nil, err
-	}
+// LastSuccess returns when Turvo last answered a request successfully; zero if it never did
+func (c *Client) LastSuccess() time.Time {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	return c.lastSuccess
+}
 
-	// Build query parameters
-	if filters.Status != "" {
-		req.SetQueryParam("status[eq]", filters.Status)
-	}
-	if filters.CustomerID != "" {
-		req.SetQueryParam("customerId[eq]", filters.CustomerID)
-	}
-	if filters.PickupDateGte != "" {
-		req.SetQueryParam("pickupDate[gte]", filters.PickupDateGte)
-	}
-	if filters.PickupDateLte != "" {
-		req.SetQueryParam("pickupDate[lte]", filters.PickupDateLte)
-	}
-	if filters.Start > 0 {
-		req.SetQueryParam("start", fmt.Sprintf("%d", filters.Start))
-	}
-	if filters.PageSize > 0 {
-		req.SetQueryParam("pageSize", fmt.Sprintf("%d", filters.PageSize))
-	}
+func (c *Client) recordSuccess() {
+	c.statusMutex.Lock()
+	c.lastSuccess = time.Now()
+	c.statusMutex.Unlock()
+}
 
-	resp, err := req.
-		SetResult(&response).
-		Get("/v1/shipments/list")
+// tokenValid reports whether we hold a token that has not expired
+func (c *Client) tokenValid() bool {
+	c.tokenMutex.RLock()
+	defer c.tokenMutex.RUnlock()
+	return c.token != "" && time.Now().Before(c.tokenExpiry)
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to list shipments: %w", err)
-	}
-
-	if resp.IsError() {
-		// If unauthorized, try to re-authenticate once
-		if resp.StatusCode() == 401 {
-			if authErr := c.authenticate(); authErr != nil {
-				return nil, fmt.Errorf("authentication failed: %w", authErr)
-			}
-			// Retry the request
-			req, err := c.getAuthenticatedRequest()
-			if err != nil {
-				return nil, err
-			}
-			// Re-apply query parameters
-			if filters.Status != "" {
-				req.SetQueryParam("status[eq]", filters.Status)
-			}
-			if filters.CustomerID != "" {
-				req.SetQueryParam("customerId[eq]", filters.CustomerID)
-			}
-			if filters.PickupDateGte != "" {
-				req.SetQueryParam("pickupDate[gte]", filters.PickupDateGte)
-			}
-			if filters.PickupDateLte != "" {
-				req.SetQueryParam("pickupDate[lte]", filters.PickupDateLte)
-			}
-			if filters.Start > 0 {
-				req.SetQueryParam("start", fmt.Sprintf("%d", filters.Start))
-			}
-			if filters.PageSize > 0 {
-				req.SetQueryParam("pageSize", fmt.Sprintf("%d", filters.PageSize))
-			}
-			resp, err = req.SetResult(&response).Get("/v1/shipments/list")
-			if err != nil {
-				return nil, fmt.Errorf("failed to list shipments after re-auth: %w", err)
-			}
-			if resp.IsError() {
-				return nil, fmt.Errorf("turvo API error: %s - %s", resp.Status(), string(resp.Body()))
-			}
-		} else {
-			return nil, fmt.Errorf("turvo API error: %s - %s", resp.Status(), string(resp.Body()))
-		}
-	}
+// GetShipments fetches all shipments from Turvo (alias for ListShipments for backward compatibility)
+func (c *Client) GetShipments(ctx context.Context) ([]models.TurvoShipment, error) {
+	return c.ListShipments(ctx)
+}
 
-	return response.Details.Shipments, nil
+// ListShipments fetches all shipments from Turvo
+func (c *Client) ListShipments(ctx context.Context) ([]models.TurvoShipment, error) {
+	return c.ListShipmentsWithFilters(ctx, models.TurvoShipmentFilters{})
+}
+
+// ListShipmentsWithFilters fetches shipments from Turvo with filters and pagination
+func (c *Client) ListShipmentsWithFilters(ctx context.Context, filters models.TurvoShipmentFilters) ([]models.TurvoShipment, error) {
+	shipments, _, err := c.ListShipmentsWithFiltersAndPagination(ctx, filters)
+	return shipments, err
 }
 
 // ListShipmentsWithFiltersAndPagination fetches shipments with filters and returns pagination info
-func (c *Client) ListShipmentsWithFiltersAndPagination(filters models.TurvoShipmentFilters) ([]models.TurvoShipment, models.TurvoPagination, error) {
+func (c *Client) ListShipmentsWithFiltersAndPagination(ctx context.Context, filters models.TurvoShipmentFilters) ([]models.TurvoShipment, models.TurvoPagination, error) {
 	var response models.TurvoShipmentsListResponse
 
-	req, err := c.getAuthenticatedRequest()
+	_, err := c.execute(ctx, &request{
+		Name:   "list shipments",
+		Method: http.MethodGet,
+		Path:   "/v1/shipments/list",
+		Query:  shipmentFilterParams(filters),
+		Result: &response,
+	})
 	if err != nil {
 		return nil, models.TurvoPagination{}, err
 	}
 
-	// Build query parameters
-	if filters.Status != "" {
-		req.SetQueryParam("status[eq]", filters.Status)
+	return response.Details.Shipments, response.Details.Pagination, nil
+}
+
+// shipmentFilterParams builds the query parameters for the list shipments endpoint
+func shipmentFilterParams(filters models.TurvoShipmentFilters) map[string]string {
+	params := map[string]string{}
+	switch len(filters.Statuses) {
+	case 0:
+	case 1:
+		params["status[eq]"] = filters.Statuses[0]
+	default:
+		params["status[in]"] = strings.Join(filters.Statuses, ",")
 	}
 	if filters.CustomerID != "" {
-		req.SetQueryParam("customerId[eq]", filters.CustomerID)
-	}
-	if filters.PickupDateGte != "" {
-		req.SetQueryParam("pickupDate[gte]", filters.PickupDateGte)
+		params["customerId[eq]"] = filters.CustomerID
+	}
+	if filters.CarrierID != "" {
+		params["carrierId[eq]"] = filters.CarrierID
+	}
+	if filters.CustomID != "" {
+		params["customId[eq]"] = filters.CustomID
+	}
+
+	// Date ranges
+	for param, value := range map[string]string{
+		"pickupDate[gte]":   filters.PickupDateGte,
+		"pickupDate[lte]":   filters.PickupDateLte,
+		"deliveryDate[gte]": filters.DeliveryDateGte,
+		"deliveryDate[lte]": filters.DeliveryDateLte,
+		"created[gte]":      filters.CreatedGte,
+		"created[lte]":      filters.CreatedLte,
+		"updated[gte]":      filters.UpdatedGte,
+		"updated[lte]":      filters.UpdatedLte,
+	} {
+		if value != "" {
+			params[param] = value
+		}
 	}
-	if filters.PickupDateLte != "" {
-		req.SetQueryParam("pickupDate[lte]", filters.PickupDateLte)
+	if filters.SortBy != "" {
+		params["sortBy"] = filters.SortBy
+		params["sortDirection"] = "asc"
+		if filters.SortDescending {
+			params["sortDirection"] = "desc"
+		}
 	}
 	if filters.Start > 0 {
-		req.SetQueryParam("start", fmt.Sprintf("%d", filters.Start))
+		params["start"] = fmt.Sprintf("%d", filters.Start)
 	}
 	if filters.PageSize > 0 {
-		req.SetQueryParam("pageSize", fmt.Sprintf("%d", filters.PageSize))
+		params["pageSize"] = fmt.Sprintf("%d", filters.PageSize)
 	}
-
-	resp, err := req.
-		SetResult(&response).
-		Get("/v1/shipments/list")
-
-	if err != nil {
-		return nil, models.TurvoPagination{}, fmt.Errorf("failed to list shipments: %w", err)
-	}
-
-	if resp.IsError() {
-		// If unauthorized, try to re-authenticate once
-		if resp.StatusCode() == 401 {
-			if authErr := c.authenticate(); authErr != nil {
-				return nil, models.TurvoPagination{}, fmt.Errorf("authentication failed: %w", authErr)
-			}
-			// Retry the request
-			req, err := c.getAuthenticatedRequest()
-			if err != nil {
-				return nil, models.TurvoPagination{}, err
-			}
-			// Re-apply query parameters
-			if filters.Status != "" {
-				req.SetQueryParam("status[eq]", filters.Status)
-			}
-			if filters.CustomerID != "" {
-				req.SetQueryParam("customerId[eq]", filters.CustomerID)
-			}
-			if filters.PickupDateGte != "" {
-				req.SetQueryParam("pickupDate[gte]", filters.PickupDateGte)
-			}
-			if filters.PickupDateLte != "" {
-				req.SetQueryParam("pickupDate[lte]", filters.PickupDateLte)
-			}
-			if filters.Start > 0 {
-				req.SetQueryParam("start", fmt.Sprintf("%d", filters.Start))
-			}
-			if filters.PageSize > 0 {
-				req.SetQueryParam("pageSize", fmt.Sprintf("%d", filters.PageSize))
-			}
-			resp, err = req.SetResult(&response).Get("/v1/shipments/list")
-			if err != nil {
-				return nil, models.TurvoPagination{}, fmt.Errorf("failed to list shipments after re-auth: %w", err)
-			}
-			if resp.IsError() {
-				return nil, models.TurvoPagination{}, fmt.Errorf("turvo API error: %s - %s", resp.Status(), string(resp.Body()))
-			}
-		} else {
-			return nil, models.TurvoPagination{}, fmt.Errorf("turvo API error: %s - %s", resp.Status(), string(resp.Body()))
-		}
-	}
-
-	return response.Details.Shipments, response.Details.Pagination, nil
+	return params
 }
 
 // CreateShipment creates a new shipment in Turvo
-func (c *Client) CreateShipment(shipment *models.TurvoShipmentCreate) (*models.TurvoShipmentCreateResponse, error) {
+func (c *Client) CreateShipment(ctx context.Context, shipment *models.TurvoShipmentCreate) (*models.TurvoShipmentCreateResponse, error) {
 	var response models.TurvoShipmentCreateResponse
-	var errorResponse models.TurvoShipmentCreateErrorResponse
 
-	req, err := c.getAuthenticatedRequest()
+	resp, err := c.execute(ctx, &request{
+		Name:   "create shipment",
+		Method: http.MethodPost,
+		Path:   "/v1/shipments",
+		Body:   shipment,
+		Result: &response,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := req.
-		SetBody(shipment).
-		SetResult(&response).
-		SetError(&errorResponse).
-		Post("/v1/shipments")
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to create shipment: %w", err)
-	}
-
-	if resp.IsError() {
-		// If unauthorized, try to re-authenticate once
-		if resp.StatusCode() == 401 {
-			if authErr := c.authenticate(); authErr != nil {
-				return nil, fmt.Errorf("authentication failed: %w", authErr)
-			}
-			// Retry the request
-			req, err := c.getAuthenticatedRequest()
-			if err != nil {
-				return nil, err
-			}
-			resp, err = req.SetBody(shipment).SetResult(&response).Post("/v1/shipments")
-			if err != nil {
-				return nil, fmt.Errorf("failed to create shipment after re-auth: %w", err)
-			}
-			if resp.IsError() {
-				return nil, fmt.Errorf("turvo API error: %s - %s", errorResponse.Status, errorResponse.Details.ErrorMessage)
-			}
-		} else {
-			return nil, fmt.Errorf("turvo API error: %s - %s", resp.Status(), string(resp.Body()))
-		}
-	}
-
+	// Turvo reports some failures with a 200 and a non-SUCCESS status
 	if response.Status != "SUCCESS" {
-		err := json.Unmarshal(resp.Body(), &errorResponse)
-		if err != nil {
+		var errorResponse models.TurvoShipmentCreateErrorResponse
+		if err := json.Unmarshal(resp.Body(), &errorResponse); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal error response: %w", err)
 		}
 		return nil, fmt.Errorf("turvo API error: %s - %s", errorResponse.Status, errorResponse.Details.ErrorMessage)
@@ -375,45 +434,20 @@ func (c *Client) CreateShipment(shipment *models.TurvoShipmentCreate) (*models.T
 }
 
 // GetShipment fetches a single shipment by ID from Turvo
-func (c *Client) GetShipment(shipmentID int) (*models.TurvoShipmentCreateDetails, error) {
+func (c *Client) GetShipment(ctx context.Context, shipmentID int) (*models.TurvoShipmentCreateDetails, error) {
 	var response models.TurvoShipmentResponse
 
-	req, err := c.getAuthenticatedRequest()
+	_, err := c.execute(ctx, &request{
+		Name:       "get shipment",
+		Method:     http.MethodGet,
+		Path:       fmt.Sprintf("/v1/shipments/%d", shipmentID),
+		Result:     &response,
+		Attributes: []attribute.KeyValue{tracing.ShipmentID.Int(shipmentID)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := req.
-		SetResult(&response).
-		Get(fmt.Sprintf("/v1/shipments/%d", shipmentID))
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to get shipment: %w", err)
-	}
-
-	if resp.IsError() {
-		// If unauthorized, try to re-authenticate once
-		if resp.StatusCode() == 401 {
-			if authErr := c.authenticate(); authErr != nil {
-				return nil, fmt.Errorf("authentication failed: %w", authErr)
-			}
-			// Retry the request
-			req, err := c.getAuthenticatedRequest()
-			if err != nil {
-				return nil, err
-			}
-			resp, err = req.SetResult(&response).Get(fmt.Sprintf("/v1/shipments/%d", shipmentID))
-			if err != nil {
-				return nil, fmt.Errorf("failed to get shipment after re-auth: %w", err)
-			}
-			if resp.IsError() {
-				return nil, fmt.Errorf("turvo API error: %s - %s", resp.Status(), string(resp.Body()))
-			}
-		} else {
-			return nil, fmt.Errorf("turvo API error: %s - %s", resp.Status(), string(resp.Body()))
-		}
-	}
-
 	if response.Status != "SUCCESS" {
 		return nil, fmt.Errorf("turvo API error: %s", response.Status)
 	}
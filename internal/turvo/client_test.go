@@ -0,0 +1,435 @@
+package turvo_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lwlach/turvo-integration-backend/internal/metrics"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo/turvotest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newClient(t *testing.T) (*turvo.Client, *turvotest.Server) {
+	t.Helper()
+	fake := turvotest.NewServer()
+	t.Cleanup(fake.Close)
+
+	client, err := turvo.NewClient(fake.Config())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client, fake
+}
+
+func addShipments(fake *turvotest.Server, n int) []int {
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = fake.AddShipment(models.TurvoShipmentCreateDetails{
+			Status: &models.TurvoCreateStatus{Code: models.TurvoStatusCode{Key: "2102", Value: "Covered"}},
+		})
+	}
+	return ids
+}
+
+func TestListShipmentsPagination(t *testing.T) {
+	client, fake := newClient(t)
+	ids := addShipments(fake, 5)
+
+	var listed []int
+	for start := 0; ; {
+		shipments, pagination, err := client.ListShipmentsWithFiltersAndPagination(context.Background(),
+			models.TurvoShipmentFilters{Start: start, PageSize: 2})
+		if err != nil {
+			t.Fatalf("list at start %d: %v", start, err)
+		}
+		if pagination.TotalRecordsInPage != len(shipments) {
+			t.Errorf("start %d: totalRecordsInPage = %d, want %d", start, pagination.TotalRecordsInPage, len(shipments))
+		}
+		for _, shipment := range shipments {
+			listed = append(listed, shipment.ID)
+		}
+		start += len(shipments)
+		if !pagination.MoreAvailable {
+			break
+		}
+	}
+
+	if len(listed) != len(ids) {
+		t.Fatalf("listed %v, want %v", listed, ids)
+	}
+	for i := range ids {
+		if listed[i] != ids[i] {
+			t.Errorf("shipment %d = %d, want %d", i, listed[i], ids[i])
+		}
+	}
+}
+
+func TestListShipmentsWrongMoreAvailable(t *testing.T) {
+	client, fake := newClient(t)
+	addShipments(fake, 3)
+
+	// The client reports Turvo's flag as is; callers must not trust it alone
+	fake.SetMoreAvailable(true)
+	shipments, pagination, err := client.ListShipmentsWithFiltersAndPagination(context.Background(),
+		models.TurvoShipmentFilters{Start: 3, PageSize: 2})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(shipments) != 0 || !pagination.MoreAvailable {
+		t.Errorf("got %d shipments with moreAvailable %v, want 0 with true", len(shipments), pagination.MoreAvailable)
+	}
+
+	fake.SetMoreAvailable(false)
+	shipments, pagination, err = client.ListShipmentsWithFiltersAndPagination(context.Background(),
+		models.TurvoShipmentFilters{Start: 0, PageSize: 2})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(shipments) != 2 || pagination.MoreAvailable {
+		t.Errorf("got %d shipments with moreAvailable %v, want 2 with false", len(shipments), pagination.MoreAvailable)
+	}
+}
+
+func TestGetShipmentRetriesTransientErrors(t *testing.T) {
+	client, fake := newClient(t)
+	id := addShipments(fake, 1)[0]
+
+	fake.InjectError(turvotest.EndpointGet, http.StatusServiceUnavailable, 2)
+	shipment, err := client.GetShipment(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetShipment: %v", err)
+	}
+	if shipment.ID != id {
+		t.Errorf("ID = %d, want %d", shipment.ID, id)
+	}
+	if got := fake.Requests(turvotest.EndpointGet); got != 3 {
+		t.Errorf("get requests = %d, want 3 (two retries)", got)
+	}
+}
+
+func TestGetShipmentErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		requests int // Attempts expected with two retries
+	}{
+		{name: "server error retried until retries run out", status: http.StatusInternalServerError, requests: 3},
+		{name: "rate limit retried", status: http.StatusTooManyRequests, requests: 3},
+		{name: "not found not retried", status: http.StatusNotFound, requests: 1},
+		{name: "bad request not retried", status: http.StatusBadRequest, requests: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, fake := newClient(t)
+			id := addShipments(fake, 1)[0]
+			fake.InjectError(turvotest.EndpointGet, test.status, -1)
+
+			_, err := client.GetShipment(context.Background(), id)
+			var apiErr *turvo.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want an *APIError", err)
+			}
+			if apiErr.StatusCode != test.status {
+				t.Errorf("status = %d, want %d", apiErr.StatusCode, test.status)
+			}
+			if apiErr.ErrorCode != "INJECTED" {
+				t.Errorf("error code = %q, want Turvo's error code INJECTED", apiErr.ErrorCode)
+			}
+			if got := fake.Requests(turvotest.EndpointGet); got != test.requests {
+				t.Errorf("get requests = %d, want %d", got, test.requests)
+			}
+		})
+	}
+}
+
+func TestNoRetriesWithZeroMaxRetries(t *testing.T) {
+	fake := turvotest.NewServer()
+	defer fake.Close()
+	config := fake.Config()
+	config.MaxRetries = 0
+	client, err := turvo.NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	id := addShipments(fake, 1)[0]
+
+	fake.InjectError(turvotest.EndpointGet, http.StatusServiceUnavailable, 1)
+	if _, err := client.GetShipment(context.Background(), id); err == nil {
+		t.Fatal("GetShipment succeeded, want the injected error")
+	}
+	if got := fake.Requests(turvotest.EndpointGet); got != 1 {
+		t.Errorf("get requests = %d, want 1", got)
+	}
+}
+
+func TestReauthenticatesAfter401(t *testing.T) {
+	client, fake := newClient(t)
+	id := addShipments(fake, 1)[0]
+
+	if _, err := client.GetShipment(context.Background(), id); err != nil {
+		t.Fatalf("first GetShipment: %v", err)
+	}
+	fake.ExpireTokens()
+
+	if _, err := client.GetShipment(context.Background(), id); err != nil {
+		t.Fatalf("GetShipment after token expiry: %v", err)
+	}
+	if got := fake.Requests(turvotest.EndpointToken); got != 2 {
+		t.Errorf("token requests = %d, want 2 (initial and re-auth)", got)
+	}
+	if got := fake.Requests(turvotest.EndpointGet); got != 3 {
+		t.Errorf("get requests = %d, want 3 (ok, 401, retried)", got)
+	}
+}
+
+func TestReauthFailsWithRejectedCredentials(t *testing.T) {
+	fake := turvotest.NewServer()
+	defer fake.Close()
+	config := fake.Config()
+	config.Password = "wrong"
+	client, err := turvo.NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	if _, err := client.GetShipment(context.Background(), 1); err == nil {
+		t.Fatal("GetShipment succeeded with rejected credentials")
+	}
+	if got := fake.Requests(turvotest.EndpointGet); got != 0 {
+		t.Errorf("get requests = %d, want none without a token", got)
+	}
+}
+
+func TestCreateShipment(t *testing.T) {
+	client, fake := newClient(t)
+
+	create := &models.TurvoShipmentCreate{
+		CustomerOrder: []models.TurvoCreateCustomerOrder{{Customer: models.TurvoAccount{ID: 834099}}},
+	}
+	response, err := client.CreateShipment(context.Background(), create)
+	if err != nil {
+		t.Fatalf("CreateShipment: %v", err)
+	}
+	if response.Details.ID == 0 {
+		t.Fatal("created shipment has no ID")
+	}
+	stored, found := fake.Shipment(response.Details.ID)
+	if !found {
+		t.Fatalf("shipment %d not stored", response.Details.ID)
+	}
+	if len(stored.CustomerOrder) == 0 || stored.CustomerOrder[0].Customer.ID != 834099 {
+		t.Errorf("stored customer orders = %+v, want customer 834099", stored.CustomerOrder)
+	}
+}
+
+func TestCreateShipmentErrors(t *testing.T) {
+	t.Run("validation error with 200", func(t *testing.T) {
+		client, _ := newClient(t)
+		_, err := client.CreateShipment(context.Background(), &models.TurvoShipmentCreate{})
+		if err == nil {
+			t.Fatal("CreateShipment succeeded without a customer")
+		}
+	})
+
+	t.Run("server error not retried", func(t *testing.T) {
+		client, fake := newClient(t)
+		fake.InjectError(turvotest.EndpointCreate, http.StatusBadGateway, -1)
+		create := &models.TurvoShipmentCreate{
+			CustomerOrder: []models.TurvoCreateCustomerOrder{{Customer: models.TurvoAccount{ID: 834099}}},
+		}
+		if _, err := client.CreateShipment(context.Background(), create); err == nil {
+			t.Fatal("CreateShipment succeeded, want the injected error")
+		}
+		// Turvo may have created the shipment before failing, so POSTs are sent once
+		if got := fake.Requests(turvotest.EndpointCreate); got != 1 {
+			t.Errorf("create requests = %d, want 1", got)
+		}
+	})
+}
+
+// histogramCount returns the number of observations of a histogram
+func histogramCount(t *testing.T, histogram *prometheus.HistogramVec, labels ...string) uint64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := histogram.WithLabelValues(labels...).(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestClientMetrics(t *testing.T) {
+	counters := map[string]prometheus.Counter{
+		"token success": metrics.TurvoTokenRefreshes.WithLabelValues("success"),
+		"token failure": metrics.TurvoTokenRefreshes.WithLabelValues("failure"),
+		"get 200":       metrics.TurvoRequests.WithLabelValues("get shipment", "200"),
+		"get 503":       metrics.TurvoRequests.WithLabelValues("get shipment", "503"),
+		"get 401":       metrics.TurvoRequests.WithLabelValues("get shipment", "401"),
+		"get error":     metrics.TurvoRequests.WithLabelValues("get shipment", "error"),
+		"get retries":   metrics.TurvoRetries.WithLabelValues("get shipment"),
+		"get reauths":   metrics.TurvoReauths.WithLabelValues("get shipment"),
+		"list 200":      metrics.TurvoRequests.WithLabelValues("list shipments", "200"),
+		"list retries":  metrics.TurvoRetries.WithLabelValues("list shipments"),
+	}
+	before := make(map[string]float64)
+	for name, counter := range counters {
+		before[name] = testutil.ToFloat64(counter)
+	}
+	getLatencies := histogramCount(t, metrics.TurvoRequestDuration, "get shipment")
+
+	// A token, a retried 503, a 401 with re-authentication and a list request
+	client, fake := newClient(t)
+	id := addShipments(fake, 1)[0]
+	fake.InjectError(turvotest.EndpointGet, http.StatusServiceUnavailable, 1)
+	if _, err := client.GetShipment(context.Background(), id); err != nil {
+		t.Fatalf("GetShipment: %v", err)
+	}
+	fake.ExpireTokens()
+	if _, err := client.GetShipment(context.Background(), id); err != nil {
+		t.Fatalf("GetShipment after token expiry: %v", err)
+	}
+	if _, _, err := client.ListShipmentsWithFiltersAndPagination(context.Background(), models.TurvoShipmentFilters{PageSize: 10}); err != nil {
+		t.Fatalf("ListShipments: %v", err)
+	}
+
+	// Rejected credentials and no response at all
+	config := fake.Config()
+	config.Password = "wrong"
+	rejected, err := turvo.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected.GetShipment(context.Background(), id)
+	config = fake.Config()
+	config.MaxRetries = 0
+	unreachable, err := turvo.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unreachable.GetShipment(context.Background(), id); err != nil {
+		t.Fatalf("GetShipment: %v", err)
+	}
+	fake.Close()
+	unreachable.GetShipment(context.Background(), id)
+
+	want := map[string]float64{
+		"token success": 3, // Initial, re-authentication, unreachable client
+		"token failure": 3, // Failed token requests are retried like the request needing them
+		"get 200":       3,
+		"get 503":       1,
+		"get 401":       1,
+		"get error":     1,
+		"get retries":   3, // The 503 and the rejected credentials twice
+		"get reauths":   1,
+		"list 200":      1,
+	}
+	for name, counter := range counters {
+		if got := testutil.ToFloat64(counter) - before[name]; got != want[name] {
+			t.Errorf("%s = %v, want %v", name, got, want[name])
+		}
+	}
+	// Every attempt is timed, the failed ones too
+	if got := histogramCount(t, metrics.TurvoRequestDuration, "get shipment") - getLatencies; got != 6 {
+		t.Errorf("get shipment latencies = %d, want 6", got)
+	}
+}
+
+func TestClientTracing(t *testing.T) {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// Requests reach the fake Turvo API through a proxy recording their traceparent headers
+	fake := turvotest.NewServer()
+	defer fake.Close()
+	target, _ := url.Parse(fake.URL)
+	var mutex sync.Mutex
+	var traceparents []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		mutex.Unlock()
+		httputil.NewSingleHostReverseProxy(target).ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+	config := fake.Config()
+	config.BaseURL = proxy.URL
+	client, err := turvo.NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	id := addShipments(fake, 1)[0]
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "GET /api/v1/loads/{id}")
+	fake.InjectError(turvotest.EndpointGet, http.StatusServiceUnavailable, 1)
+	if _, err := client.GetShipment(ctx, id); err != nil {
+		t.Fatalf("GetShipment: %v", err)
+	}
+	fake.InjectErrorBody(turvotest.EndpointGet, http.StatusNotFound, `{"details":{"errorMessage":"no access for ops@acme.example"}}`, 1)
+	if _, err := client.GetShipment(ctx, id); err == nil {
+		t.Fatal("GetShipment succeeded, want the injected error")
+	}
+	parent.End()
+
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "turvo get shipment" {
+			spans = append(spans, span)
+		}
+	}
+	if len(spans) != 2 {
+		t.Fatalf("%d get shipment spans, want 2", len(spans))
+	}
+	for i, span := range spans {
+		if span.SpanKind() != trace.SpanKindClient || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %d = %s span with parent %s, want a client span of the request", i, span.SpanKind(), span.Parent().SpanID())
+		}
+	}
+	attempts := func(span sdktrace.ReadOnlySpan) int64 {
+		for _, attr := range span.Attributes() {
+			if attr.Key == "turvo.attempts" {
+				return attr.Value.AsInt64()
+			}
+		}
+		return 0
+	}
+	if got := attempts(spans[0]); got != 2 {
+		t.Errorf("attempts = %d, want 2 (one retry) in one span", got)
+	}
+	if spans[1].Status().Code != codes.Error || strings.Contains(spans[1].Status().Description, "ops@acme.example") {
+		t.Errorf("failed span status = %+v, want a redacted error", spans[1].Status())
+	}
+
+	// Every attempt carries the trace of the request and the span of the Turvo call
+	if len(traceparents) != 3 {
+		t.Fatalf("%d requests, want 3", len(traceparents))
+	}
+	for i, traceparent := range traceparents {
+		span := spans[min(i/2, 1)]
+		want := "00-" + parent.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"
+		if traceparent != want {
+			t.Errorf("request %d traceparent = %q, want %q", i, traceparent, want)
+		}
+	}
+}
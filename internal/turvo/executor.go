@@ -0,0 +1,337 @@
+package turvo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/lwlach/turvo-integration-backend/internal/metrics"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// request describes a single call to the Turvo API. Every endpoint builds one of these
+// and hands it to the client's pipeline, which applies auth, retries, logging and error decoding.
+type request struct {
+	Name   string            // Endpoint name used in logs and errors (e.g. "list shipments")
+	Method string            // HTTP method
+	Path   string            // Path relative to the base URL
+	Query  map[string]string // Query parameters
+	Body   interface{}       // Request body, encoded as JSON
+	Result interface{}       // Decoded on success
+
+	Attributes []attribute.KeyValue // Added to the request's span (e.g. the shipment ID)
+
+	// Set by the pipeline
+	ctx      context.Context // Context of the caller, for cancellation and the request ID in logs
+	token    string          // Access token to send
+	attempts int             // Number of times the request was sent
+}
+
+// handlerFunc sends a request and returns Turvo's response
+type handlerFunc func(req *request) (*resty.Response, error)
+
+// middleware wraps a handlerFunc with additional behaviour
+type middleware func(next handlerFunc) handlerFunc
+
+// APIError is returned when Turvo answers with an error status
+type APIError struct {
+	Endpoint   string
+	StatusCode int
+	Status     string
+	ErrorCode  string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("turvo API error: %s - %s", e.Status, e.Message)
+}
+
+// buildPipeline composes the middleware every Turvo request goes through.
+// The first middleware is the outermost one.
+func (c *Client) buildPipeline() handlerFunc {
+	middlewares := []middleware{
+		c.tracingMiddleware,
+		c.loggingMiddleware,
+		c.statusMiddleware,
+		c.errorDecodingMiddleware,
+		c.reauthMiddleware,
+		c.retryMiddleware,
+		c.authMiddleware,
+		c.rateLimitMiddleware,
+		c.metricsMiddleware,
+	}
+
+	handler := c.send
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// execute runs a request through the pipeline
+func (c *Client) execute(ctx context.Context, req *request) (*resty.Response, error) {
+	req.ctx = ctx
+	return c.pipeline(req)
+}
+
+// send builds a fresh resty request and sends it
+func (c *Client) send(req *request) (*resty.Response, error) {
+	req.attempts++
+
+	r := c.httpClient.R().SetContext(req.ctx).SetAuthToken(req.token)
+	otel.GetTextMapPropagator().Inject(req.ctx, propagation.HeaderCarrier(r.Header))
+	if len(req.Query) > 0 {
+		r.SetQueryParams(req.Query)
+	}
+	if req.Body != nil {
+		r.SetBody(req.Body)
+	}
+	if req.Result != nil {
+		r.SetResult(req.Result)
+	}
+
+	debug := slog.Default().Enabled(req.ctx, slog.LevelDebug)
+	if debug {
+		attrs := []any{"endpoint", req.Name, "method", req.Method, "path", req.Path, "attempt", req.attempts}
+		if len(req.Query) > 0 {
+			attrs = append(attrs, "query", req.Query)
+		}
+		if req.Body != nil {
+			body, _ := json.Marshal(req.Body)
+			attrs = append(attrs, "body", string(body))
+		}
+		slog.DebugContext(req.ctx, "turvo request", attrs...)
+	}
+
+	resp, err := r.Execute(req.Method, req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", req.Name, err)
+	}
+
+	if debug {
+		slog.DebugContext(req.ctx, "turvo response",
+			"endpoint", req.Name,
+			"status", resp.StatusCode(),
+			"duration_ms", resp.Time().Milliseconds(),
+			"body", string(resp.Body()),
+		)
+	}
+	return resp, nil
+}
+
+// tracingMiddleware wraps the request, retries and re-authentication included, in a client
+// span. Later middleware log with the span's context, and send propagates it to Turvo.
+func (c *Client) tracingMiddleware(next handlerFunc) handlerFunc {
+	return func(req *request) (*resty.Response, error) {
+		attrs := append([]attribute.KeyValue{
+			attribute.String("turvo.endpoint", req.Name),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.path", req.Path),
+			tracing.TenantID.String(c.tenantID),
+		}, req.Attributes...)
+		ctx, span := tracing.Start(req.ctx, "turvo "+req.Name,
+			trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+		req.ctx = ctx
+
+		resp, err := next(req)
+		span.SetAttributes(attribute.Int("turvo.attempts", req.attempts))
+		if resp != nil {
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode()))
+		}
+		tracing.End(span, err)
+		return resp, err
+	}
+}
+
+// loggingMiddleware logs every request with its outcome and duration. Request and response
+// bodies are logged per attempt at debug level (see send).
+func (c *Client) loggingMiddleware(next handlerFunc) handlerFunc {
+	return func(req *request) (*resty.Response, error) {
+		start := time.Now()
+		resp, err := next(req)
+		attrs := []any{
+			"endpoint", req.Name,
+			"method", req.Method,
+			"path", req.Path,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"attempts", req.attempts,
+		}
+
+		level := slog.LevelInfo
+		if resp != nil {
+			attrs = append(attrs, "status", resp.StatusCode())
+		}
+		if err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, "error", err)
+		}
+		slog.Log(req.ctx, level, "turvo call", attrs...)
+		return resp, err
+	}
+}
+
+// statusMiddleware records successful requests for LastSuccess
+func (c *Client) statusMiddleware(next handlerFunc) handlerFunc {
+	return func(req *request) (*resty.Response, error) {
+		resp, err := next(req)
+		if err == nil {
+			c.recordSuccess()
+		}
+		return resp, err
+	}
+}
+
+// errorDecodingMiddleware turns error responses into an *APIError
+func (c *Client) errorDecodingMiddleware(next handlerFunc) handlerFunc {
+	return func(req *request) (*resty.Response, error) {
+		resp, err := next(req)
+		if err != nil || !resp.IsError() {
+			return resp, err
+		}
+		return resp, decodeAPIError(req.Name, resp)
+	}
+}
+
+// reauthMiddleware re-authenticates once and retries when Turvo rejects the token
+func (c *Client) reauthMiddleware(next handlerFunc) handlerFunc {
+	return func(req *request) (*resty.Response, error) {
+		resp, err := next(req)
+		if err != nil || resp.StatusCode() != http.StatusUnauthorized {
+			return resp, err
+		}
+		metrics.TurvoReauths.WithLabelValues(req.Name).Inc()
+		trace.SpanFromContext(req.ctx).AddEvent("reauthenticate")
+
+		if authErr := c.reauthenticate(req.ctx, req.token); authErr != nil {
+			return nil, fmt.Errorf("authentication failed: %w", authErr)
+		}
+
+		resp, err = next(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to %s after re-auth: %w", req.Name, err)
+		}
+		return resp, nil
+	}
+}
+
+// reauthenticate fetches a new token unless another request already replaced the rejected one
+func (c *Client) reauthenticate(ctx context.Context, rejectedToken string) error {
+	c.authMutex.Lock()
+	defer c.authMutex.Unlock()
+
+	c.tokenMutex.RLock()
+	refreshed := c.token != rejectedToken
+	c.tokenMutex.RUnlock()
+	if refreshed {
+		return nil
+	}
+
+	return c.authenticate(ctx)
+}
+
+// retryMiddleware retries transient failures (network errors, 429 and 5xx) with exponential backoff
+func (c *Client) retryMiddleware(next handlerFunc) handlerFunc {
+	return func(req *request) (*resty.Response, error) {
+		backoff := c.retryBackoff
+		for retry := 0; ; retry++ {
+			resp, err := next(req)
+			if retry >= c.maxRetries || !isRetryable(req, resp, err) {
+				return resp, err
+			}
+			select {
+			case <-time.After(backoff):
+			case <-req.ctx.Done():
+				return resp, err
+			}
+			metrics.TurvoRetries.WithLabelValues(req.Name).Inc()
+			trace.SpanFromContext(req.ctx).AddEvent("retry", trace.WithAttributes(attribute.Int("turvo.retry", retry+1)))
+			backoff *= 2
+		}
+	}
+}
+
+// authMiddleware makes sure a valid token is attached to the request
+func (c *Client) authMiddleware(next handlerFunc) handlerFunc {
+	return func(req *request) (*resty.Response, error) {
+		if err := c.ensureAuthenticated(req.ctx); err != nil {
+			return nil, err
+		}
+
+		c.tokenMutex.RLock()
+		req.token = c.token
+		c.tokenMutex.RUnlock()
+
+		return next(req)
+	}
+}
+
+// rateLimitMiddleware waits until the rate limit allows another request. It runs for every
+// attempt, so retries count against the limit too.
+func (c *Client) rateLimitMiddleware(next handlerFunc) handlerFunc {
+	return func(req *request) (*resty.Response, error) {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(req.ctx); err != nil {
+				return nil, fmt.Errorf("failed to %s: %w", req.Name, err)
+			}
+		}
+		return next(req)
+	}
+}
+
+// metricsMiddleware records the latency and status of every attempt. It is the innermost
+// middleware, so time spent waiting for the rate limit or between retries is not included.
+func (c *Client) metricsMiddleware(next handlerFunc) handlerFunc {
+	return func(req *request) (*resty.Response, error) {
+		start := time.Now()
+		resp, err := next(req)
+		metrics.TurvoRequestDuration.WithLabelValues(req.Name).Observe(time.Since(start).Seconds())
+
+		status := "error"
+		if resp != nil {
+			status = strconv.Itoa(resp.StatusCode())
+		}
+		metrics.TurvoRequests.WithLabelValues(req.Name, status).Inc()
+		return resp, err
+	}
+}
+
+// isRetryable reports whether a request failed in a way that may succeed when sent again.
+// Only GET requests are retried on network errors and 5xx, since Turvo may already have
+// processed a POST (e.g. created the shipment) before failing.
+func isRetryable(req *request, resp *resty.Response, err error) bool {
+	if resp != nil && resp.StatusCode() == http.StatusTooManyRequests {
+		return true
+	}
+	if req.Method != http.MethodGet {
+		return false
+	}
+	return err != nil || resp.StatusCode() >= 500
+}
+
+// decodeAPIError builds an *APIError from an error response, using Turvo's error body when present
+func decodeAPIError(endpoint string, resp *resty.Response) error {
+	apiErr := &APIError{
+		Endpoint:   endpoint,
+		StatusCode: resp.StatusCode(),
+		Status:     resp.Status(),
+		Message:    string(resp.Body()),
+	}
+
+	var errorResponse models.TurvoShipmentCreateErrorResponse
+	if err := json.Unmarshal(resp.Body(), &errorResponse); err == nil && errorResponse.Details.ErrorMessage != "" {
+		apiErr.ErrorCode = errorResponse.Details.ErrorCode
+		apiErr.Message = errorResponse.Details.ErrorMessage
+	}
+
+	return apiErr
+}
@@ -0,0 +1,113 @@
+package turvo
+
+import (
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+)
+
+// SimulateCreatedShipment builds the shipment Turvo returns for a create request, as the GET
+// shipment endpoint would show it. Only the fields the create request carries are filled in.
+// It is used by the fake Turvo server and the mapping fidelity report.
+func SimulateCreatedShipment(create *models.TurvoShipmentCreate, id int) models.TurvoShipmentCreateDetails {
+	shipment := models.TurvoShipmentCreateDetails{
+		ID:              id,
+		LtlShipment:     create.LtlShipment,
+		StartDate:       create.StartDate,
+		EndDate:         models.TurvoDateWithTimezoneAndFlex{Date: create.EndDate.Date, TimeZone: create.EndDate.TimeZone},
+		Lane:            create.Lane,
+		UseRoutingGuide: create.UseRoutingGuide,
+	}
+
+	// Turvo creates shipments as drafts unless a status is given
+	status := models.TurvoCreateStatus{Code: models.TurvoStatusCode{Key: "2120", Value: "Draft"}}
+	if create.Status != nil {
+		status = *create.Status
+	}
+	shipment.Status = &status
+
+	for _, equipment := range create.Equipment {
+		shipment.Equipment = append(shipment.Equipment, models.TurvoEquipmentResponse{
+			Type:           equipment.Type,
+			Size:           equipment.Size,
+			Weight:         equipment.Weight,
+			Temp:           equipment.Temp,
+			ShipmentLength: equipment.ShipmentLength,
+		})
+	}
+
+	for _, order := range create.CustomerOrder {
+		response := models.TurvoCustomerOrderResponse{
+			ID: order.CustomerOrderSourceID,
+			Customer: models.TurvoAccountResponse{
+				ID:   order.Customer.ID,
+				Name: order.Customer.Name,
+			},
+		}
+		for _, externalID := range order.ExternalIds {
+			response.ExternalIds = append(response.ExternalIds, models.TurvoExternalIdResponse{
+				Type:               externalID.Type,
+				Value:              externalID.Value,
+				CopyToCarrierOrder: externalID.CopyToCarrierOrder,
+			})
+		}
+		if order.Costs != nil {
+			response.Costs = &models.TurvoOrderCostsResponse{TotalAmount: order.Costs.TotalAmount}
+		}
+		shipment.CustomerOrder = append(shipment.CustomerOrder, response)
+	}
+
+	for _, order := range create.CarrierOrder {
+		shipment.CarrierOrder = append(shipment.CarrierOrder, models.TurvoCarrierOrderResponse{
+			ID: order.CarrierOrderSourceID,
+			Carrier: models.TurvoAccountResponse{
+				ID:   order.Carrier.ID,
+				Name: order.Carrier.Name,
+			},
+		})
+	}
+
+	for _, party := range create.Party {
+		shipment.Party = append(shipment.Party, models.TurvoPartyResponse{
+			Account: models.TurvoAccountResponse{
+				ID:   party.Account.ID,
+				Name: party.Account.Name,
+			},
+		})
+	}
+
+	return shipment
+}
+
+// SimulateListShipment builds the summary the list endpoint returns for a shipment
+func SimulateListShipment(shipment models.TurvoShipmentCreateDetails) models.TurvoShipment {
+	summary := models.TurvoShipment{
+		ID:       shipment.ID,
+		CustomID: shipment.CustomID,
+	}
+	if shipment.Status != nil {
+		summary.Status = models.TurvoShipmentStatus{Code: shipment.Status.Code}
+	}
+
+	for _, order := range shipment.CustomerOrder {
+		summary.CustomerOrder = append(summary.CustomerOrder, models.TurvoCustomerOrder{
+			ID:       order.ID,
+			Customer: models.TurvoAccount{ID: order.Customer.ID, Name: order.Customer.Name},
+			Deleted:  order.Deleted,
+		})
+	}
+	for _, order := range shipment.CarrierOrder {
+		summary.CarrierOrder = append(summary.CarrierOrder, models.TurvoCarrierOrder{
+			ID:      order.ID,
+			Carrier: models.TurvoAccount{ID: order.Carrier.ID, Name: order.Carrier.Name},
+			Deleted: order.Deleted,
+		})
+	}
+	for _, party := range shipment.Party {
+		summary.Party = append(summary.Party, models.TurvoPartyOrder{
+			ID:      party.ID,
+			Account: models.TurvoAccount{ID: party.Account.ID, Name: party.Account.Name},
+			Deleted: party.Deleted,
+		})
+	}
+
+	return summary
+}
@@ -0,0 +1,469 @@
+// Package turvotest provides an in-process fake of the Turvo public API for tests.
+//
+// The fake serves the OAuth token, shipment list, get and create endpoints from memory,
+// so a real *turvo.Client (and everything built on it) can run offline:
+//
+//	fake := turvotest.NewServer()
+//	defer fake.Close()
+//
+//	client, err := turvo.NewClient(fake.Config())
+//	fake.AddShipment(models.TurvoShipmentCreateDetails{CustomID: "FREIGHT-1"})
+//	fake.InjectError(turvotest.EndpointGet, http.StatusServiceUnavailable, 1)
+//	fake.SetLatency(turvotest.EndpointList, 50*time.Millisecond)
+package turvotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/turvo"
+)
+
+// Endpoint identifies one of the faked Turvo endpoints
+type Endpoint string
+
+const (
+	EndpointToken  Endpoint = "token"
+	EndpointList   Endpoint = "list"
+	EndpointGet    Endpoint = "get"
+	EndpointCreate Endpoint = "create"
+)
+
+// Credentials accepted by the fake OAuth endpoint
+const (
+	ClientName   = "turvotest-client"
+	ClientSecret = "turvotest-secret"
+	Username     = "turvotest-user"
+	Password     = "turvotest-password"
+)
+
+// fault is an injected error returned instead of the normal response
+type fault struct {
+	status    int
+	body      string
+	remaining int // Number of requests still affected; negative means forever
+}
+
+// Server is a fake Turvo API backed by an httptest.Server
+type Server struct {
+	*httptest.Server
+
+	mutex     sync.Mutex
+	shipments []models.TurvoShipmentCreateDetails // In creation order
+	nextID    int
+	tokens    map[string]bool
+	faults    map[Endpoint]*fault
+	latency   map[Endpoint]time.Duration
+	requests  map[Endpoint]int
+
+	moreAvailable *bool // Reported by every list page instead of the real value, if set
+	ignoreStart   bool  // List always returns the first page
+}
+
+// NewServer starts a fake Turvo API. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		nextID:   1000000001,
+		tokens:   make(map[string]bool),
+		faults:   make(map[Endpoint]*fault),
+		latency:  make(map[Endpoint]time.Duration),
+		requests: make(map[Endpoint]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/oauth/token", s.handle(EndpointToken, false, s.token))
+	mux.HandleFunc("GET /v1/shipments/list", s.handle(EndpointList, true, s.list))
+	mux.HandleFunc("GET /v1/shipments/{id}", s.handle(EndpointGet, true, s.get))
+	mux.HandleFunc("POST /v1/shipments", s.handle(EndpointCreate, true, s.create))
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Config returns a turvo.Config that points a client at the fake with valid credentials.
+// Retries wait only a millisecond so injected errors do not slow tests down.
+func (s *Server) Config() turvo.Config {
+	return turvo.Config{
+		BaseURL:      s.URL,
+		AuthURL:      s.URL,
+		ClientName:   ClientName,
+		ClientSecret: ClientSecret,
+		Username:     Username,
+		Password:     Password,
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	}
+}
+
+// AddShipment stores a shipment and returns its ID. A zero ID is assigned automatically.
+func (s *Server) AddShipment(shipment models.TurvoShipmentCreateDetails) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.addShipment(shipment)
+}
+
+// Shipment returns a stored shipment by ID
+func (s *Server) Shipment(id int) (models.TurvoShipmentCreateDetails, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, shipment := range s.shipments {
+		if shipment.ID == id {
+			return shipment, true
+		}
+	}
+	return models.TurvoShipmentCreateDetails{}, false
+}
+
+// InjectError makes the next times requests to endpoint fail with status.
+// A negative times makes every request fail until ClearErrors is called.
+func (s *Server) InjectError(endpoint Endpoint, status int, times int) {
+	s.InjectErrorBody(endpoint, status, "", times)
+}
+
+// InjectErrorBody is like InjectError but also sets the response body.
+// An empty body is replaced by a Turvo-style error response.
+func (s *Server) InjectErrorBody(endpoint Endpoint, status int, body string, times int) {
+	if body == "" {
+		body = errorBody("INJECTED", fmt.Sprintf("injected %d error", status))
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults[endpoint] = &fault{
+		status:    status,
+		body:      body,
+		remaining: times,
+	}
+}
+
+// ClearErrors removes all injected errors
+func (s *Server) ClearErrors() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = make(map[Endpoint]*fault)
+}
+
+// SetLatency delays every response from endpoint by d
+func (s *Server) SetLatency(endpoint Endpoint, d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.latency[endpoint] = d
+}
+
+// SetMoreAvailable makes every list page report moreAvailable as value, whatever the data,
+// like Turvo sometimes does
+func (s *Server) SetMoreAvailable(value bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.moreAvailable = &value
+}
+
+// SetIgnoreStart makes the list endpoint ignore the start parameter and always return the
+// first page, like a Turvo filter that does not support paging
+func (s *Server) SetIgnoreStart(ignore bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.ignoreStart = ignore
+}
+
+// ExpireTokens invalidates all issued tokens, so the next request gets a 401
+func (s *Server) ExpireTokens() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tokens = make(map[string]bool)
+}
+
+// Requests returns how many requests endpoint has received
+func (s *Server) Requests(endpoint Endpoint) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.requests[endpoint]
+}
+
+// handle wraps an endpoint with request counting, latency, injected errors and token checks
+func (s *Server) handle(endpoint Endpoint, requireToken bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		s.requests[endpoint]++
+		delay := s.latency[endpoint]
+		var injected *fault
+		if f, found := s.faults[endpoint]; found && f.remaining != 0 {
+			injected = f
+			if f.remaining > 0 {
+				f.remaining--
+			}
+		}
+		authorized := !requireToken || s.tokens[bearerToken(r)]
+		s.mutex.Unlock()
+
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+
+		if injected != nil {
+			writeRaw(w, injected.status, injected.body)
+			return
+		}
+
+		if !authorized {
+			writeRaw(w, http.StatusUnauthorized, errorBody("UNAUTHORIZED", "invalid or expired token"))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// token handles POST /v1/oauth/token
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	var authReq models.TurvoAuthRequest
+	if err := json.NewDecoder(r.Body).Decode(&authReq); err != nil {
+		writeRaw(w, http.StatusBadRequest, errorBody("BAD_REQUEST", err.Error()))
+		return
+	}
+
+	if r.URL.Query().Get("client_id") != ClientName || authReq.ClientSecret != ClientSecret ||
+		authReq.Username != Username || authReq.Password != Password {
+		writeRaw(w, http.StatusUnauthorized, errorBody("INVALID_CREDENTIALS", "bad credentials"))
+		return
+	}
+
+	token := uuid.NewString()
+	s.mutex.Lock()
+	s.tokens[token] = true
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, models.TurvoAuthResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   3600,
+		Scope:       authReq.Scope,
+	})
+}
+
+// list handles GET /v1/shipments/list with status, customer, carrier and customId filters,
+// sortBy/sortDirection and start/pageSize pagination
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	start, _ := strconv.Atoi(query.Get("start"))
+	pageSize, _ := strconv.Atoi(query.Get("pageSize"))
+	if pageSize <= 0 {
+		pageSize = 24
+	}
+
+	s.mutex.Lock()
+	moreAvailableOverride := s.moreAvailable
+	if s.ignoreStart {
+		start = 0
+	}
+	var matching []models.TurvoShipmentCreateDetails
+	for _, shipment := range s.shipments {
+		if status := query.Get("status[eq]"); status != "" && !hasStatus(shipment, status) {
+			continue
+		}
+		if statuses := query.Get("status[in]"); statuses != "" && !hasStatus(shipment, strings.Split(statuses, ",")...) {
+			continue
+		}
+		if customerID := query.Get("customerId[eq]"); customerID != "" && !hasCustomer(shipment, customerID) {
+			continue
+		}
+		if carrierID := query.Get("carrierId[eq]"); carrierID != "" && !hasCarrier(shipment, carrierID) {
+			continue
+		}
+		if customID := query.Get("customId[eq]"); customID != "" && shipment.CustomID != customID {
+			continue
+		}
+		matching = append(matching, shipment)
+	}
+	s.mutex.Unlock()
+	sortShipments(matching, query.Get("sortBy"), query.Get("sortDirection") == "desc")
+
+	page := []models.TurvoShipment{}
+	for i := start; i < len(matching) && i < start+pageSize; i++ {
+		page = append(page, turvo.SimulateListShipment(matching[i]))
+	}
+
+	moreAvailable := start+len(page) < len(matching)
+	if moreAvailableOverride != nil {
+		moreAvailable = *moreAvailableOverride
+	}
+
+	writeJSON(w, http.StatusOK, models.TurvoShipmentsListResponse{
+		Status: "SUCCESS",
+		Details: models.TurvoShipmentsDetails{
+			Pagination: models.TurvoPagination{
+				Start:              start,
+				PageSize:           pageSize,
+				TotalRecordsInPage: len(page),
+				MoreAvailable:      moreAvailable,
+			},
+			Shipments: page,
+		},
+	})
+}
+
+// get handles GET /v1/shipments/{id}
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeRaw(w, http.StatusBadRequest, errorBody("BAD_REQUEST", "invalid shipment id"))
+		return
+	}
+
+	shipment, found := s.Shipment(id)
+	if !found {
+		writeRaw(w, http.StatusNotFound, errorBody("NOT_FOUND", fmt.Sprintf("shipment %d not found", id)))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.TurvoShipmentResponse{
+		Status:  "SUCCESS",
+		Details: shipment,
+	})
+}
+
+// create handles POST /v1/shipments
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var create models.TurvoShipmentCreate
+	if err := json.NewDecoder(r.Body).Decode(&create); err != nil {
+		writeRaw(w, http.StatusBadRequest, errorBody("BAD_REQUEST", err.Error()))
+		return
+	}
+
+	if len(create.CustomerOrder) == 0 || create.CustomerOrder[0].Customer.ID == 0 {
+		// Turvo reports validation failures with a 200 and an ERROR status
+		writeRaw(w, http.StatusOK, errorBody("VALIDATION_ERROR", "customerOrder.customer.id is required"))
+		return
+	}
+
+	s.mutex.Lock()
+	id := s.addShipment(turvo.SimulateCreatedShipment(&create, 0))
+	s.mutex.Unlock()
+
+	shipment, _ := s.Shipment(id)
+	writeJSON(w, http.StatusOK, models.TurvoShipmentCreateResponse{
+		Status:  "SUCCESS",
+		Details: shipment,
+	})
+}
+
+// addShipment stores a shipment; the caller must hold the mutex
+func (s *Server) addShipment(shipment models.TurvoShipmentCreateDetails) int {
+	if shipment.ID == 0 {
+		shipment.ID = s.nextID
+		s.nextID++
+	}
+	if shipment.CustomID == "" {
+		shipment.CustomID = fmt.Sprintf("%d", shipment.ID)
+	}
+	s.shipments = append(s.shipments, shipment)
+	return shipment.ID
+}
+
+// sortShipments sorts by pickupDate, deliveryDate or created (creation order). Other fields
+// keep creation order, as the fake has no update times.
+func sortShipments(shipments []models.TurvoShipmentCreateDetails, sortBy string, descending bool) {
+	var date func(models.TurvoShipmentCreateDetails) string
+	switch sortBy {
+	case "pickupDate":
+		date = func(shipment models.TurvoShipmentCreateDetails) string { return shipment.StartDate.Date }
+	case "deliveryDate":
+		date = func(shipment models.TurvoShipmentCreateDetails) string { return shipment.EndDate.Date }
+	case "created":
+		if descending {
+			slices.Reverse(shipments)
+		}
+		return
+	default:
+		return
+	}
+	sort.SliceStable(shipments, func(i, j int) bool {
+		a, _ := time.Parse(time.RFC3339, date(shipments[i]))
+		b, _ := time.Parse(time.RFC3339, date(shipments[j]))
+		if descending {
+			return a.After(b)
+		}
+		return a.Before(b)
+	})
+}
+
+func hasStatus(shipment models.TurvoShipmentCreateDetails, keys ...string) bool {
+	if shipment.Status == nil {
+		return false
+	}
+	for _, key := range keys {
+		if shipment.Status.Code.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCarrier(shipment models.TurvoShipmentCreateDetails, carrierID string) bool {
+	for _, order := range shipment.CarrierOrder {
+		if !order.Deleted && fmt.Sprintf("%d", order.Carrier.ID) == carrierID {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCustomer(shipment models.TurvoShipmentCreateDetails, customerID string) bool {
+	for _, order := range shipment.CustomerOrder {
+		if !order.Deleted && fmt.Sprintf("%d", order.Customer.ID) == customerID {
+			return true
+		}
+	}
+	return false
+}
+
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) > len(prefix) && header[:len(prefix)] == prefix {
+		return header[len(prefix):]
+	}
+	return ""
+}
+
+func errorBody(code, message string) string {
+	body, _ := json.Marshal(models.TurvoShipmentCreateErrorResponse{
+		Status: "ERROR",
+		Details: models.TurvoShipmentCreateErrorResponseDetails{
+			ErrorCode:    code,
+			ErrorMessage: message,
+		},
+	})
+	return string(body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeRaw(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
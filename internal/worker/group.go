@@ -0,0 +1,62 @@
+// Package worker runs background goroutines and stops them in order on shutdown.
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Group is a set of background workers. The zero value is ready to use.
+type Group struct {
+	mutex   sync.Mutex
+	workers []*worker
+}
+
+type worker struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start runs fn in its own goroutine. Its context is cancelled by Stop, and fn must return then.
+func (g *Group) Start(name string, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &worker{name: name, cancel: cancel, done: make(chan struct{})}
+
+	g.mutex.Lock()
+	g.workers = append(g.workers, w)
+	g.mutex.Unlock()
+
+	go func() {
+		defer close(w.done)
+		fn(ctx)
+	}()
+	slog.Info("started worker", "worker", name)
+}
+
+// Stop stops the workers in reverse start order, so workers started later (which may rely on
+// earlier ones) stop first. Each worker has finished before the next one is stopped. If ctx
+// ends first, the remaining workers are cancelled without waiting and an error is returned.
+func (g *Group) Stop(ctx context.Context) error {
+	g.mutex.Lock()
+	workers := g.workers
+	g.workers = nil
+	g.mutex.Unlock()
+
+	for i := len(workers) - 1; i >= 0; i-- {
+		w := workers[i]
+		w.cancel()
+		select {
+		case <-w.done:
+			slog.Info("stopped worker", "worker", w.name)
+		case <-ctx.Done():
+			for _, remaining := range workers[:i] {
+				remaining.cancel()
+			}
+			return fmt.Errorf("worker %s did not stop in time: %w", w.name, ctx.Err())
+		}
+	}
+	return nil
+}
@@ -1,73 +1,228 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/lwlach/turvo-integration-backend/internal/auth"
+	"github.com/lwlach/turvo-integration-backend/internal/config"
+	healthhandler "github.com/lwlach/turvo-integration-backend/internal/handler/health"
 	loadhandler "github.com/lwlach/turvo-integration-backend/internal/handler/load"
+	tenanthandler "github.com/lwlach/turvo-integration-backend/internal/handler/tenant"
+	webhookhandler "github.com/lwlach/turvo-integration-backend/internal/handler/webhook"
+	"github.com/lwlach/turvo-integration-backend/internal/logging"
+	"github.com/lwlach/turvo-integration-backend/internal/mapping"
+	"github.com/lwlach/turvo-integration-backend/internal/metrics"
+	"github.com/lwlach/turvo-integration-backend/internal/middleware"
+	"github.com/lwlach/turvo-integration-backend/internal/models"
+	"github.com/lwlach/turvo-integration-backend/internal/notify"
+	healthservice "github.com/lwlach/turvo-integration-backend/internal/service/health"
 	loadservice "github.com/lwlach/turvo-integration-backend/internal/service/load"
+	webhookservice "github.com/lwlach/turvo-integration-backend/internal/service/webhook"
+	"github.com/lwlach/turvo-integration-backend/internal/tenant"
+	"github.com/lwlach/turvo-integration-backend/internal/tracing"
 	"github.com/lwlach/turvo-integration-backend/internal/turvo"
+	"github.com/lwlach/turvo-integration-backend/internal/worker"
 )
 
 func main() {
-	// Initialize Turvo client
-	turvoConfig := turvo.Config{
-		BaseURL:      getEnv("TURVO_BASE_URL", "https://my-sandbox.turvo.com"),
-		APIKey:       getEnv("TURVO_API_KEY", ""), // Legacy: Use ClientName/ClientSecret instead
-		ClientName:   getEnv("TURVO_CLIENT_NAME", ""),
-		ClientSecret: getEnv("TURVO_CLIENT_SECRET", ""),
-		Username:     getEnv("TURVO_USERNAME", ""),
-		Password:     getEnv("TURVO_PASSWORD", ""),
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fidelity":
+			os.Exit(runFidelity(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		}
 	}
 
-	// Validate required authentication credentials
-	if turvoConfig.ClientName == "" || turvoConfig.ClientSecret == "" {
-		log.Fatal("TURVO_CLIENT_NAME and TURVO_CLIENT_SECRET are required for authentication")
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
 	}
-	if turvoConfig.Username == "" || turvoConfig.Password == "" {
-		log.Fatal("TURVO_USERNAME and TURVO_PASSWORD are required for authentication")
+
+	// JSON logs; an invalid log level is reported with the other configuration problems
+	if logErr := logging.Setup(os.Stderr, logging.Config{Level: cfg.Log.Level, Redact: cfg.Log.Redact}); logErr != nil {
+		logging.Setup(os.Stderr, logging.Config{Redact: true})
+	}
+
+	// Every configuration problem, including those found while loading the files it names,
+	// is reported before exiting
+	var problems []error
+	if err != nil {
+		problems = append(problems, err)
+	}
+
+	// Load the Turvo code mapping profiles (built-in defaults if no profile file is configured)
+	profiles := mapping.DefaultSet()
+	if cfg.Tenants.MappingProfile != "" {
+		profiles, err = mapping.LoadFile(cfg.Tenants.MappingProfile)
+		if err != nil {
+			problems = append(problems, err)
+			profiles = mapping.DefaultSet()
+		}
+	}
+
+	// Initialize the tenant registry; every tenant gets its own Turvo client and load service
+	tenants := tenant.NewRegistry(tenant.Config{
+		// Turvo settings shared by all tenants; credentials are per tenant
+		Turvo: turvo.Config{
+			BaseURL:      cfg.Turvo.BaseURL,
+			AuthURL:      cfg.Turvo.AuthURL,
+			APIKey:       cfg.Turvo.APIKey,
+			MaxRetries:   cfg.Turvo.MaxRetries,
+			RetryBackoff: cfg.Turvo.RetryBackoff,
+			RateLimit:    cfg.Turvo.RateLimit,
+			RateBurst:    cfg.Turvo.RateBurst,
+			Mode:         cfg.Turvo.Mode,
+			CassettePath: cfg.Turvo.Cassette,
+		},
+		Loads: loadservice.Config{
+			DetailsCacheTTL:  cfg.Cache.DetailsTTL,
+			DetailsCacheSize: cfg.Cache.DetailsSize,
+			DetailWorkers:    cfg.Turvo.DetailWorkers,
+			SearchScanLimit:  cfg.Turvo.SearchScanLimit,
+		},
+		Profiles:        profiles,
+		StorePath:       cfg.Tenants.Store,
+		DefaultTenantID: cfg.Tenants.DefaultID,
+	})
+
+	// Browser origins allowed to call the API
+	cors, err := middleware.NewCORS(middleware.CORSConfig{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   cfg.CORS.ExposedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
+	})
+	if err != nil {
+		problems = append(problems, err)
+	}
+
+	authConfig, err := newAuthConfig(cfg.Auth, tenants)
+	if err != nil {
+		problems = append(problems, err)
+	}
+
+	// Spans of our handlers, the load service and Turvo calls; dropped unless exported with OTLP
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		problems = append(problems, err)
 	}
 
-	turvoClient := turvo.NewClient(turvoConfig)
+	if len(problems) > 0 {
+		fatal("invalid configuration", "error", errors.Join(problems...))
+	}
+
+	// The Turvo account in the configuration is the default tenant
+	if cfg.HasTurvoCredentials() {
+		name := cfg.Tenants.DefaultName
+		if name == "" {
+			name = cfg.Tenants.DefaultID
+		}
+		_, err := tenants.RegisterStatic(tenant.Spec{
+			ID:   cfg.Tenants.DefaultID,
+			Name: name,
+			Credentials: models.TurvoCredentials{
+				ClientName:   cfg.Turvo.ClientName,
+				ClientSecret: cfg.Turvo.ClientSecret,
+				Username:     cfg.Turvo.Username,
+				Password:     cfg.Turvo.Password,
+			},
+		}, cfg.Tenants.APIKeys)
+		if err != nil {
+			fatal("failed to register tenant", "tenant_id", cfg.Tenants.DefaultID, "error", err)
+		}
+	}
+
+	if err := tenants.LoadStore(); err != nil {
+		fatal("failed to load tenants", "error", err)
+	}
+	if len(tenants.List()) == 0 {
+		slog.Warn("no tenants configured: set TURVO_CLIENT_NAME/TURVO_CLIENT_SECRET/TURVO_USERNAME/TURVO_PASSWORD or register tenants with the admin API")
+	}
 
-	// Initialize service layer
-	loadService := loadservice.NewService(turvoClient)
+	// Notifications about load changes go to notify.webhookURL if set, otherwise to the log
+	var notifier notify.Notifier = notify.NewLogNotifier()
+	if cfg.Notify.WebhookURL != "" {
+		notifier = notify.NewWebhookNotifier(cfg.Notify.WebhookURL)
+	}
+	webhookService := webhookservice.NewService(notifier)
 
 	// Initialize handlers
-	loadHandler := loadhandler.NewHandler(loadService)
+	loadHandler := loadhandler.NewHandler(loadhandler.Config{
+		MaxBodyBytes:        int64(cfg.Server.MaxBodyBytes),
+		RejectUnknownFields: cfg.Server.UnknownFields == "reject",
+		WriteTimeout:        cfg.Server.WriteTimeout,
+	})
+	if cfg.Turvo.WebhookSecret == "" {
+		slog.Warn("TURVO_WEBHOOK_SECRET is not set, Turvo webhook deliveries for tenants without their own secret will be rejected")
+	}
+	webhookHandler := webhookhandler.NewHandler(webhookService, tenants, cfg.Turvo.WebhookSecret)
+	tenantHandler := tenanthandler.NewHandler(tenants)
+	healthHandler := healthhandler.NewHandler(healthservice.NewService(tenants, healthservice.Config{
+		StaleAfter: cfg.Ready.StaleAfter,
+		Timeout:    cfg.Ready.Timeout,
+	}))
+
+	if len(cfg.CORS.AllowedOrigins) == 0 {
+		slog.Warn("CORS_ALLOWED_ORIGINS is not set, cross-origin browser requests are rejected")
+	}
 
 	// Setup chi router
 	r := chi.NewRouter()
 
-	// Middleware
+	// Middleware. The request ID and the span come first so every log line of the request carries them.
+	r.Use(chimiddleware.RequestID)
+	r.Use(middleware.RequestIDHeader)
+	r.Use(chimiddleware.RealIP)
+	r.Use(middleware.Tracing)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Heartbeat("/health"))
-
-	// CORS middleware (basic setup - adjust as needed)
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-
-			next.ServeHTTP(w, r)
-		})
-	})
+	r.Use(chimiddleware.Heartbeat("/health")) // Liveness only, see /ready for Turvo connectivity
+	r.Use(middleware.Metrics)
+	r.Use(cors.Handler)
+
+	// Readiness, not authenticated so load balancers and orchestrators can probe it
+	healthHandler.RegisterRoutes(r)
+
+	// Prometheus metrics, not authenticated either; keep the port off the public network
+	if cfg.Metrics.Enabled {
+		r.Method(http.MethodGet, cfg.Metrics.Path, metrics.Handler())
+	}
 
 	// API routes
 	r.Route("/api/v1", func(r chi.Router) {
-		loadHandler.RegisterRoutes(r)
+		// Webhooks authenticate with the webhook secret instead
+		webhookHandler.RegisterRoutes(r)
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Middleware(authConfig))
+			tenantHandler.RegisterRoutes(r)
+
+			// Routes served for the tenant of the caller's credentials (or X-Tenant-ID)
+			r.Group(func(r chi.Router) {
+				r.Use(tenant.Middleware(tenants))
+				loadHandler.RegisterRoutes(r)
+			})
+		})
 	})
 
 	// Root endpoint
@@ -75,17 +230,124 @@ func main() {
 		w.Write([]byte("Turvo Integration Backend API"))
 	})
 
+	// Background workers, stopped in reverse order after the server has drained
+	var workers worker.Group
+	if cfg.Turvo.TokenRefreshInterval > 0 {
+		workers.Start("Turvo token refresh", func(ctx context.Context) {
+			tenants.RefreshTokens(ctx, cfg.Turvo.TokenRefreshInterval, cfg.Turvo.TokenRefreshMargin)
+		})
+	}
+
 	// Start server
-	port := getEnv("PORT", "8080")
-	log.Printf("Server starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatal(err)
+	server := &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	slog.Info("server starting", "port", cfg.Server.Port)
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	select {
+	case err := <-serverErr:
+		fatal("server failed", "error", err)
+	case <-signals.Done():
+	}
+	// A second signal terminates immediately
+	stopSignals()
+
+	shutdown(server, &workers, tenants, shutdownTracing, cfg.Server.ShutdownTimeout)
+}
+
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// shutdown stops accepting connections and waits for in-flight requests, so a load being
+// created in Turvo is not cut off before we record it. Background workers are stopped after
+// that, since the remaining requests may still need them. Then Turvo traffic recorded in
+// record mode is written to the cassettes, and pending spans are flushed last.
+func shutdown(server *http.Server, workers *worker.Group, tenants *tenant.Registry, shutdownTracing func(context.Context) error, timeout time.Duration) {
+	slog.Info("shutting down, draining in-flight requests", "timeout", timeout.String())
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("requests still running after the shutdown timeout are cut off", "timeout", timeout.String(), "error", err)
+		server.Close()
+	}
+	if err := workers.Stop(ctx); err != nil {
+		slog.Error("failed to stop background workers", "error", err)
+	}
+	if err := tenants.Close(); err != nil {
+		slog.Error("failed to write Turvo cassettes", "error", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush spans", "error", err)
 	}
+	slog.Info("shutdown complete")
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// newAuthConfig sets up authentication of our REST API: tenant API keys, keys from the API
+// key file, the admin API key and, with a JWKS file, JWT bearer tokens
+func newAuthConfig(cfg config.Auth, tenants *tenant.Registry) (auth.Config, error) {
+	if cfg.Disabled {
+		slog.Warn("AUTH_DISABLED=true, the API accepts unauthenticated requests for every tenant. Never use this in production.")
+		return auth.Config{Disabled: true}, nil
+	}
+
+	var errs []error
+
+	var staticKeys []auth.StaticKey
+	if cfg.AdminAPIKey != "" {
+		staticKeys = append(staticKeys, auth.StaticKey{
+			ID:     "admin",
+			Hash:   auth.HashAPIKey(cfg.AdminAPIKey),
+			Scopes: []string{auth.ScopeTenantsAdmin},
+		})
 	}
-	return defaultValue
+	keys, err := auth.NewStaticKeys(staticKeys)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	stores := []auth.KeyStore{tenants, keys}
+	if cfg.APIKeysFile != "" {
+		fileKeys, err := auth.LoadStaticKeys(cfg.APIKeysFile)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			slog.Info("loaded API keys", "count", fileKeys.Len(), "file", cfg.APIKeysFile)
+			stores = append(stores, fileKeys)
+		}
+	}
+	if cfg.AdminAPIKey == "" {
+		slog.Warn("ADMIN_API_KEY is not set, the tenant admin API is only reachable with keys from API_KEYS_FILE or JWTs")
+	}
+
+	authenticators := []auth.Authenticator{auth.NewAPIKeyAuthenticator(stores...)}
+	if cfg.JWKSFile != "" {
+		jwtAuthenticator, err := auth.NewJWTAuthenticator(auth.JWTConfig{
+			JWKSPath:    cfg.JWKSFile,
+			Issuer:      cfg.JWTIssuer,
+			Audience:    cfg.JWTAudience,
+			TenantClaim: cfg.JWTTenantClaim,
+			Leeway:      cfg.JWTLeeway,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to set up JWT authentication: %w", err))
+		} else {
+			authenticators = append(authenticators, jwtAuthenticator)
+		}
+	}
+
+	return auth.Config{Authenticators: authenticators}, errors.Join(errs...)
 }